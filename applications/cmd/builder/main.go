@@ -2,6 +2,7 @@ package main
 
 import (
 	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/enums"
 	"bsquared.network/message-sharing-applications/internal/initiates"
 	"bsquared.network/message-sharing-applications/internal/serves/builder"
 	"bsquared.network/message-sharing-applications/internal/utils/log"
@@ -30,23 +31,20 @@ func main() {
 		logger.Panicf("init db err: %s", err)
 	}
 
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("builder-%s", cfg.Bsquared.Name), cfg.Log.Level)
-		rpc, err := initiates.InitEthereumRpc(cfg.Bsquared.RpcUrl)
-		if err != nil {
-			logger.Panicf("init ethereum rpc err: %s", err)
+	for _, chain := range cfg.Chains {
+		// only evm chains accept message-sharing transactions
+		if chain.ChainType != enums.ChainTypeEVM {
+			continue
 		}
-		builder.NewBuilder(cfg.Bsquared.Builders, cfg.Bsquared, db, rpc, logger).Start()
-	}()
-
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("builder-%s", cfg.Arbitrum.Name), cfg.Log.Level)
-		rpc, err := initiates.InitEthereumRpc(cfg.Arbitrum.RpcUrl)
-		if err != nil {
-			logger.Panicf("init ethereum rpc err: %s", err)
-		}
-		builder.NewBuilder(cfg.Arbitrum.Builders, cfg.Arbitrum, db, rpc, logger).Start()
-	}()
+		go func(chain config.Blockchain) {
+			logger := log.NewLogger(fmt.Sprintf("builder-%s", chain.Name), cfg.Log.Level)
+			rpc, err := initiates.InitEthereumRpc(chain, logger)
+			if err != nil {
+				logger.Panicf("init ethereum rpc err: %s", err)
+			}
+			builder.NewBuilder(chain.Builders, chain, db, rpc, logger).Start()
+		}(chain)
+	}
 	logger.Info("======================================================")
 	select {}
 }
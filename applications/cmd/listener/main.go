@@ -2,20 +2,31 @@ package main
 
 import (
 	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/enums"
 	"bsquared.network/message-sharing-applications/internal/initiates"
 	"bsquared.network/message-sharing-applications/internal/serves/listener/bitcoin"
 	"bsquared.network/message-sharing-applications/internal/serves/listener/ethereum"
 	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"sort"
 )
 
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(),
+		"usage: listener [-f config] [backfill --chain id --from block --to block [--contracts list] [--dry-run]]\n")
+	flag.PrintDefaults()
+}
+
 func main() {
 	decimal.DivisionPrecision = 18
 	var fileName string
 	flag.StringVar(&fileName, "f", "listener", "-f config filename, default: listener")
+	flag.Usage = usage
 	flag.Parse()
 	cfg := config.LoadConfig(fileName)
 	logger := log.NewLogger(fmt.Sprintf("listener-common"), cfg.Log.Level)
@@ -34,33 +45,87 @@ func main() {
 	if err != nil {
 		logger.Panicf("parse bridges err: %s", err)
 	}
+	if flag.Arg(0) == "backfill" {
+		backfill(cfg, bridges, db, flag.Args()[1:])
+		return
+	}
 
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("listener-%s", cfg.Bsquared.Name), cfg.Log.Level)
-		rpc, err := initiates.InitEthereumRpc(cfg.Bsquared.RpcUrl)
-		if err != nil {
-			logger.Panicf("init ethereum rpc err: %s", err)
-		}
-		ethereum.NewListener(bridges, cfg.Bsquared, rpc, db, logger).Start()
-	}()
+	for _, chain := range cfg.Chains {
+		go func(chain config.Blockchain) {
+			logger := log.NewLogger(fmt.Sprintf("listener-%s", chain.Name), cfg.Log.Level)
+			switch chain.ChainType {
+			case enums.ChainTypeEVM:
+				rpc, err := initiates.InitEthereumRpc(chain, logger)
+				if err != nil {
+					logger.Panicf("init ethereum rpc err: %s", err)
+				}
+				ethereum.NewListener(bridges, chain, rpc, db, logger).Start()
+			case enums.ChainTypeUTXO:
+				rpc, err := initiates.InitBitcoinRpc(chain, logger)
+				if err != nil {
+					logger.Panicf("init bitcoin rpc err: %s", err)
+				}
+				bitcoin.NewListener(bridges, chain, cfg.Particle, rpc, db, logger).Start()
+			}
+		}(chain)
+	}
+	logger.Info("======================================================")
+	select {}
+}
 
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("listener-%s", cfg.Arbitrum.Name), cfg.Log.Level)
-		rpc, err := initiates.InitEthereumRpc(cfg.Arbitrum.RpcUrl)
-		if err != nil {
-			logger.Panicf("init ethereum rpc err: %s", err)
-		}
-		ethereum.NewListener(bridges, cfg.Arbitrum, rpc, db, logger).Start()
-	}()
+// backfill creates a bounded sync task re-indexing a block range of an evm
+// chain, with --dry-run it only reports the events found in the range.
+func backfill(cfg config.AppConfig, bridges map[int64]string, db *gorm.DB, args []string) {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	chainId := flags.Int64("chain", 0, "chain id")
+	from := flags.Int64("from", 0, "first block of the range")
+	to := flags.Int64("to", 0, "last block of the range")
+	contracts := flags.String("contracts", "", "comma separated contracts, default: chain listen address")
+	handleNum := flags.Int64("handle-num", ethereum.DefaultBackfillHandleNum, "blocks handled per round")
+	dryRun := flags.Bool("dry-run", false, "report the events found without creating the task")
+	_ = flags.Parse(args)
+
+	logger := log.NewLogger(fmt.Sprintf("backfill-%d", *chainId), cfg.Log.Level)
+	chain, ok := cfg.Chain(*chainId)
+	if !ok {
+		logger.Panicf("chain not found: %d", *chainId)
+	}
+	if chain.ChainType != enums.ChainTypeEVM {
+		logger.Panicf("backfill only supports evm chains, chain: %d", *chainId)
+	}
+	list, err := ethereum.ParseContracts(*contracts)
+	if err != nil {
+		logger.Panicf("parse contracts err: %s", err)
+	}
+	rpc, err := initiates.InitEthereumRpc(chain, logger)
+	if err != nil {
+		logger.Panicf("init ethereum rpc err: %s", err)
+	}
+	listener := ethereum.NewListener(bridges, chain, rpc, db, logger)
 
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("listener-%s", cfg.Bitcoin.Name), cfg.Log.Level)
-		rpc, err := initiates.InitBitcoinRpc(cfg.Bitcoin.RpcUrl, cfg.Bitcoin.BtcUser, cfg.Bitcoin.BtcPass, cfg.Bitcoin.DisableTLS)
+	if *dryRun {
+		report, err := listener.ScanBackfill(context.Background(), *from, *to, list)
 		if err != nil {
-			logger.Panicf("init bitcoin rpc err: %s", err)
+			logger.Panicf("scan backfill err: %s", err)
 		}
-		bitcoin.NewListener(bridges, cfg.Bitcoin, cfg.Particle, rpc, db, logger).Start()
-	}()
-	logger.Info("======================================================")
-	select {}
+		fmt.Printf("chain: %d, blocks: %d-%d\n", *chainId, report.From, report.To)
+		names := make([]string, 0, len(report.Found))
+		for name := range report.Found {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%-24s  found: %-8d  indexed: %d\n", name, report.Found[name], report.Indexed[name])
+		}
+		return
+	}
+	task, created, err := listener.Backfill(*from, *to, list, *handleNum)
+	if err != nil {
+		logger.Panicf("create backfill task err: %s", err)
+	}
+	if created {
+		logger.Infof("backfill task created, id: %d, blocks: %d-%d", task.Id, task.StartBlock, task.EndBlock)
+	} else {
+		logger.Infof("backfill task already pending, id: %d, latest block: %d", task.Id, task.LatestBlock)
+	}
 }
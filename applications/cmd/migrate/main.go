@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/initiates"
+	"bsquared.network/message-sharing-applications/internal/migrations"
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: migrate [-f config] up|down [n]|status\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var fileName string
+	flag.StringVar(&fileName, "f", "migrate", "-f config filename, default: migrate")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+	cfg := config.LoadConfig(fileName)
+	logger := log.NewLogger("migrate", cfg.Log.Level)
+
+	db, err := initiates.InitDB(cfg.Database)
+	if err != nil {
+		logger.Panicf("init db err: %s", err)
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		logger.Panicf("init migrator err: %s", err)
+	}
+
+	switch flag.Arg(0) {
+	case "up":
+		list, err := migrator.Up()
+		for _, one := range list {
+			logger.Infof("migrate up: %d_%s", one.Version, one.Name)
+		}
+		if err != nil {
+			logger.Panicf("migrate up err: %s", err)
+		}
+		logger.Infof("migrate up done, applied: %d", len(list))
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			steps, err = strconv.Atoi(flag.Arg(1))
+			if err != nil || steps < 1 {
+				logger.Panicf("invalid down steps: %s", flag.Arg(1))
+			}
+		}
+		list, err := migrator.Down(steps)
+		for _, one := range list {
+			logger.Infof("migrate down: %d_%s", one.Version, one.Name)
+		}
+		if err != nil {
+			logger.Panicf("migrate down err: %s", err)
+		}
+		logger.Infof("migrate down done, reverted: %d", len(list))
+	case "status":
+		list, err := migrator.Status()
+		if err != nil {
+			logger.Panicf("migrate status err: %s", err)
+		}
+		for _, one := range list {
+			appliedAt := "pending"
+			if one.Applied {
+				appliedAt = one.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%06d  %-32s  %s\n", one.Version, one.Name, appliedAt)
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
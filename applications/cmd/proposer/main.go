@@ -1,11 +1,11 @@
 package main
 
 import (
+	"bsquared.network/message-sharing-applications/internal/adapter"
 	"bsquared.network/message-sharing-applications/internal/config"
 	"bsquared.network/message-sharing-applications/internal/initiates"
 	"bsquared.network/message-sharing-applications/internal/serves/proposer"
 	"bsquared.network/message-sharing-applications/internal/utils/log"
-	"bsquared.network/message-sharing-applications/internal/vo"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -30,43 +30,38 @@ func main() {
 	if err != nil {
 		logger.Panicf("init db err: %s", err)
 	}
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("proposer-%s", cfg.Bitcoin.Name), cfg.Log.Level)
-		pk, host, err := initiates.InitListenHost(cfg.Bitcoin.NodePort, cfg.Bitcoin.NodeKey)
+	// the bridges of the listener, optional
+	bridges := make(map[int64]string)
+	if cfg.Bridges != "" {
+		bridges, err = config.ParseBridges(cfg.Bridges)
 		if err != nil {
-			logger.Panicf("init host err: %s", err)
+			logger.Panicf("parse bridges err: %s", err)
 		}
-		rpc, err := initiates.InitBitcoinRpc(cfg.Bitcoin.RpcUrl, cfg.Bitcoin.BtcUser, cfg.Bitcoin.BtcPass, cfg.Bitcoin.DisableTLS)
-		if err != nil {
-			logger.Panicf("init ethereum rpc err: %s", err)
-		}
-		proposer.NewProposer(pk, host, db, &vo.RpcClient{BtcRpc: rpc}, logger, cfg.Bitcoin, cfg.Particle).Start()
-	}()
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("proposer-%s", cfg.Bsquared.Name), cfg.Log.Level)
-
-		pk, host, err := initiates.InitListenHost(cfg.Bsquared.NodePort, cfg.Bsquared.NodeKey)
-		if err != nil {
-			logger.Panicf("init host err: %s", err)
-		}
-		rpc, err := initiates.InitEthereumRpc(cfg.Bsquared.RpcUrl)
-		if err != nil {
-			logger.Panicf("init ethereum rpc err: %s", err)
-		}
-		proposer.NewProposer(pk, host, db, &vo.RpcClient{EthRpc: rpc}, logger, cfg.Bsquared, cfg.Particle).Start()
-	}()
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("proposer-%s", cfg.Arbitrum.Name), cfg.Log.Level)
-		pk, host, err := initiates.InitListenHost(cfg.Arbitrum.NodePort, cfg.Arbitrum.NodeKey)
-		if err != nil {
-			logger.Panicf("init host err: %s", err)
-		}
-		rpc, err := initiates.InitEthereumRpc(cfg.Arbitrum.RpcUrl)
+	}
+	// the adapters of all chains verify the source of the messages paid out by utxo chains
+	adapters := make(map[int64]adapter.ChainAdapter)
+	for _, chain := range cfg.Chains {
+		logger := log.NewLogger(fmt.Sprintf("proposer-%s", chain.Name), cfg.Log.Level)
+		chainAdapter, err := adapter.NewAdapter(chain, cfg.Particle, bridges, logger)
 		if err != nil {
-			logger.Panicf("init ethereum rpc err: %s", err)
+			logger.Panicf("init chain adapter err: %s", err)
 		}
-		proposer.NewProposer(pk, host, db, &vo.RpcClient{EthRpc: rpc}, logger, cfg.Arbitrum, cfg.Particle).Start()
-	}()
+		adapters[chain.ChainId] = chainAdapter
+	}
+	for _, chain := range cfg.Chains {
+		go func(chain config.Blockchain) {
+			logger := log.NewLogger(fmt.Sprintf("proposer-%s", chain.Name), cfg.Log.Level)
+			pk, host, err := initiates.InitListenHost(chain.NodePort, chain.NodeKey)
+			if err != nil {
+				logger.Panicf("init host err: %s", err)
+			}
+			wallet, err := initiates.InitWallet(chain, logger)
+			if err != nil {
+				logger.Panicf("init withdraw wallet err: %s", err)
+			}
+			proposer.NewProposer(pk, host, db, adapters, wallet, logger, chain).Start()
+		}(chain)
+	}
 	logger.Info("======================================================")
 	select {}
 }
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"bsquared.network/message-sharing-applications/internal/adapter"
 	"bsquared.network/message-sharing-applications/internal/config"
 	"bsquared.network/message-sharing-applications/internal/initiates"
+	"bsquared.network/message-sharing-applications/internal/migrations"
 	"bsquared.network/message-sharing-applications/internal/serves/validator"
 	"bsquared.network/message-sharing-applications/internal/utils/log"
-	"bsquared.network/message-sharing-applications/internal/vo"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -25,42 +27,57 @@ func main() {
 	}
 	logger.Infof("config: %s", value)
 	logger.Info("------------------------------------------------------")
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("validator-%s", cfg.Bitcoin.Name), uint32(cfg.Log.Level))
-		pk, host, err := initiates.InitHost(cfg.Bitcoin.NodeKey)
-		if err != nil {
-			logger.Panicf("init host err: %s", err)
-		}
-		rpc, err := initiates.InitBitcoinRpc(cfg.Bitcoin.RpcUrl, cfg.Bitcoin.BtcUser, cfg.Bitcoin.BtcPass, cfg.Bitcoin.DisableTLS)
-		if err != nil {
-			logger.Panicf("init ethereum rpc err: %s", err)
-		}
-		validator.NewValidator(pk, host, logger, &vo.RpcClient{BtcRpc: rpc}, cfg.Particle, cfg.Bitcoin).Start()
-	}()
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("validator-%s", cfg.Bsquared.Name), uint32(cfg.Log.Level))
-		pk, host, err := initiates.InitHost(cfg.Bsquared.NodeKey)
-		if err != nil {
-			logger.Panicf("init host err: %s", err)
-		}
-		rpc, err := initiates.InitEthereumRpc(cfg.Bsquared.RpcUrl)
-		if err != nil {
-			logger.Panicf("init ethereum rpc err: %s", err)
-		}
-		validator.NewValidator(pk, host, logger, &vo.RpcClient{EthRpc: rpc}, config.Particle{}, cfg.Bsquared).Start()
-	}()
-	go func() {
-		logger := log.NewLogger(fmt.Sprintf("validator-%s", cfg.Arbitrum.Name), uint32(cfg.Log.Level))
-		pk, host, err := initiates.InitHost(cfg.Arbitrum.NodeKey)
+
+	// the bridges of the listener, optional
+	bridges := make(map[int64]string)
+	if cfg.Bridges != "" {
+		bridges, err = config.ParseBridges(cfg.Bridges)
 		if err != nil {
-			logger.Panicf("init host err: %s", err)
+			logger.Panicf("parse bridges err: %s", err)
 		}
-		rpc, err := initiates.InitEthereumRpc(cfg.Arbitrum.RpcUrl)
+	}
+	// the adapters of all chains verify the source of the messages paid out by utxo chains
+	adapters := make(map[int64]adapter.ChainAdapter)
+	for _, chain := range cfg.Chains {
+		logger := log.NewLogger(fmt.Sprintf("validator-%s", chain.Name), uint32(cfg.Log.Level))
+		chainAdapter, err := adapter.NewAdapter(chain, cfg.Particle, bridges, logger)
 		if err != nil {
-			logger.Panicf("init ethereum rpc err: %s", err)
+			logger.Panicf("init chain adapter err: %s", err)
 		}
-		validator.NewValidator(pk, host, logger, &vo.RpcClient{EthRpc: rpc}, config.Particle{}, cfg.Arbitrum).Start()
-	}()
+		adapters[chain.ChainId] = chainAdapter
+	}
+	for _, chain := range cfg.Chains {
+		go func(chain config.Blockchain) {
+			logger := log.NewLogger(fmt.Sprintf("validator-%s", chain.Name), uint32(cfg.Log.Level))
+			pk, host, err := initiates.InitHost(chain.NodeKey)
+			if err != nil {
+				logger.Panicf("init host err: %s", err)
+			}
+			wallet, err := initiates.InitWallet(chain, logger)
+			if err != nil {
+				logger.Panicf("init withdraw wallet err: %s", err)
+			}
+			// the validator keeps the txs it signed in a db of its own
+			var db *gorm.DB
+			if wallet != nil {
+				if chain.SignedDb == "" {
+					logger.Panicf("signed db is empty: %s", chain.Name)
+				}
+				db, err = initiates.InitDB(config.Database{Driver: "sqlite", DbName: chain.SignedDb, LogLevel: 2})
+				if err != nil {
+					logger.Panicf("init signed db err: %s", err)
+				}
+				migrator, err := migrations.NewMigrator(db)
+				if err != nil {
+					logger.Panicf("init migrator err: %s", err)
+				}
+				if _, err = migrator.Up(); err != nil {
+					logger.Panicf("migrate signed db err: %s", err)
+				}
+			}
+			validator.NewValidator(pk, host, db, logger, adapters, wallet, chain).Start()
+		}(chain)
+	}
 	logger.Info("======================================================")
 	select {}
 }
@@ -0,0 +1,46 @@
+package adapter
+
+import (
+	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"bsquared.network/message-sharing-applications/internal/vo"
+	"context"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// Network describes the chain an adapter is bound to.
+type Network struct {
+	ChainType enums.ChainType
+	ChainId   int64
+	Mainnet   bool
+	// Params is only set for utxo chains
+	Params *chaincfg.Params
+}
+
+// ChainAdapter is what the proposer and validator need from a source chain,
+// a new chain family only has to implement it to take part in message sharing.
+type ChainAdapter interface {
+	// Network returns the network params of the chain
+	Network() Network
+	// LatestHeight returns the height of the chain head
+	LatestHeight(ctx context.Context) (int64, error)
+	// FinalizedHeight returns the highest height considered final
+	FinalizedHeight(ctx context.Context) (int64, error)
+	// VerifyMessage checks the message against its source transaction on chain
+	VerifyMessage(ctx context.Context, msg vo.Message) (bool, error)
+}
+
+// NewAdapter returns the adapter of the chain type, the bridges of the listener
+// let utxo chains verify the refunds of deposits without bridge.
+func NewAdapter(conf config.Blockchain, particle config.Particle, bridges map[int64]string, logger *log.Logger) (ChainAdapter, error) {
+	switch conf.ChainType {
+	case enums.ChainTypeEVM:
+		return NewEvmAdapter(conf, logger)
+	case enums.ChainTypeUTXO:
+		return NewBitcoinAdapter(conf, particle, bridges, logger)
+	default:
+		return nil, fmt.Errorf("unsupported chain type: %d", conf.ChainType)
+	}
+}
@@ -0,0 +1,103 @@
+package adapter
+
+import (
+	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/initiates"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"bsquared.network/message-sharing-applications/internal/utils/ord"
+	"bsquared.network/message-sharing-applications/internal/utils/prevout"
+	"bsquared.network/message-sharing-applications/internal/utils/tx"
+	"bsquared.network/message-sharing-applications/internal/vo"
+	"context"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+type BitcoinAdapter struct {
+	conf     config.Blockchain
+	particle config.Particle
+	params   *chaincfg.Params
+	rpc      rpcpool.BtcSource
+	routes   map[string]config.BitcoinRoute
+	bridges  map[int64]string
+	prevouts *prevout.Resolver
+	// ord verifies the token deposits, nil without Tokens
+	ord *ord.Client
+}
+
+func NewBitcoinAdapter(conf config.Blockchain, particle config.Particle, bridges map[int64]string, logger *log.Logger) (*BitcoinAdapter, error) {
+	rpc, err := initiates.InitBitcoinRpc(conf, logger)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := conf.BitcoinRoutes()
+	if err != nil {
+		return nil, err
+	}
+	var indexer *ord.Client
+	if conf.Tokens {
+		indexer = ord.NewClient(conf.OrdUrl)
+	}
+	return &BitcoinAdapter{
+		conf:     conf,
+		particle: particle,
+		params:   conf.BitcoinParams(),
+		rpc:      rpc,
+		routes:   routes,
+		bridges:  bridges,
+		prevouts: prevout.NewResolver(rpc, prevout.DefaultCacheSize),
+		ord:      indexer,
+	}, nil
+}
+
+func (a *BitcoinAdapter) Network() Network {
+	return Network{
+		ChainType: enums.ChainTypeUTXO,
+		ChainId:   a.conf.ChainId,
+		Mainnet:   a.conf.Mainnet,
+		Params:    a.params,
+	}
+}
+
+func (a *BitcoinAdapter) LatestHeight(ctx context.Context) (int64, error) {
+	latest, err := a.rpc.GetBlockCount()
+	if err != nil {
+		return 0, err
+	}
+	return latest, nil
+}
+
+func (a *BitcoinAdapter) FinalizedHeight(ctx context.Context) (int64, error) {
+	latest, err := a.LatestHeight(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return latest - a.conf.SafeBlockNumber, nil
+}
+
+func (a *BitcoinAdapter) VerifyMessage(ctx context.Context, msg vo.Message) (bool, error) {
+	address, err := btcutil.DecodeAddress(msg.FromMessageContract, a.params)
+	if err != nil {
+		return false, nil
+	}
+	route, ok := a.routes[address.EncodeAddress()]
+	if !ok {
+		return false, nil
+	}
+	// a message from the chain to itself refunds a deposit
+	if msg.ToChainId == a.conf.ChainId {
+		return tx.VerifyBtcRefund(a.rpc, a.prevouts, a.params, a.particle, route, a.bridges, a.ord, msg.BlockNumber, msg.TxHash,
+			msg.FromId, msg.Data)
+	}
+	return tx.VerifyBtcTx(a.rpc, a.prevouts, a.params, a.particle, route, a.ord, msg.BlockNumber, msg.TxHash, msg.FromId,
+		msg.ToChainId, msg.ToMessageContract, msg.ToContractAddress, msg.Data)
+}
+
+// HeldDeposit tells whether the outputs of the tx are a deposit held back
+// from the withdrawals, read from the chain rather than from a listener.
+func (a *BitcoinAdapter) HeldDeposit(ctx context.Context, txHash *chainhash.Hash) (bool, error) {
+	return tx.HeldDeposit(a.rpc, a.prevouts, a.params, a.particle, a.routes, a.bridges, a.ord, txHash)
+}
@@ -0,0 +1,54 @@
+package adapter
+
+import (
+	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/initiates"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/block"
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"bsquared.network/message-sharing-applications/internal/utils/tx"
+	"bsquared.network/message-sharing-applications/internal/vo"
+	"context"
+)
+
+type EvmAdapter struct {
+	conf config.Blockchain
+	rpc  *rpcpool.EthClient
+}
+
+func NewEvmAdapter(conf config.Blockchain, logger *log.Logger) (*EvmAdapter, error) {
+	rpc, err := initiates.InitEthereumRpc(conf, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &EvmAdapter{
+		conf: conf,
+		rpc:  rpc,
+	}, nil
+}
+
+func (a *EvmAdapter) Network() Network {
+	return Network{
+		ChainType: enums.ChainTypeEVM,
+		ChainId:   a.conf.ChainId,
+		Mainnet:   a.conf.Mainnet,
+	}
+}
+
+func (a *EvmAdapter) LatestHeight(ctx context.Context) (int64, error) {
+	latest, err := a.rpc.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int64(latest), nil
+}
+
+func (a *EvmAdapter) FinalizedHeight(ctx context.Context) (int64, error) {
+	return block.FinalizedNumber(ctx, a.rpc, a.conf.Finality, a.conf.SafeBlockNumber)
+}
+
+func (a *EvmAdapter) VerifyMessage(ctx context.Context, msg vo.Message) (bool, error) {
+	return tx.VerifyEthTx(a.rpc, msg.TxHash, msg.LogIndex, msg.FromMessageContract, msg.FromChainId,
+		msg.FromId, msg.FromSender, msg.ToChainId, msg.ToContractAddress, msg.Data)
+}
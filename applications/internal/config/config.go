@@ -2,9 +2,13 @@ package config
 
 import (
 	"bsquared.network/message-sharing-applications/internal/enums"
+	"encoding/json"
 	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
+	"reflect"
 	"strconv"
 	"strings"
 )
@@ -12,9 +16,7 @@ import (
 type AppConfig struct {
 	Log      LogConfig
 	Database Database
-	Bsquared Blockchain
-	Bitcoin  Blockchain
-	Arbitrum Blockchain
+	Chains   []Blockchain
 	Particle Particle
 	Bridges  string
 }
@@ -24,11 +26,15 @@ type LogConfig struct {
 }
 
 type Database struct {
+	// Driver is one of mysql, postgres, sqlite, default mysql
+	Driver   string
 	UserName string
 	Password string
 	Host     string
 	Port     int64
+	// DbName is the database file path for sqlite
 	DbName   string
+	SslMode  string
 	LogLevel int64
 }
 
@@ -53,6 +59,51 @@ type Blockchain struct {
 	SignatureWeight   int64
 	Validators        []string
 	Builders          []string
+	// ReorgWindow is the number of recent blocks tracked for reorg detection, default 64
+	ReorgWindow int64
+	// Finality is one of confirmations, safe, finalized for evm chains, default
+	// confirmations which waits SafeBlockNumber blocks
+	Finality enums.Finality
+	// RpcUrls are fail over endpoints of the chain next to RpcUrl
+	RpcUrls []string
+	// Subscribe wakes the listener on the new heads of a ws endpoint and reads
+	// the logs from a log subscription, for evm chains
+	Subscribe bool
+	// Routes map the watched bitcoin addresses to their destination, for utxo chains
+	Routes []BitcoinRoute
+	// Mempool records deposits seen in the mempool as unconfirmed, for utxo chains
+	Mempool bool
+	// BtcSource is one of rpc, esplora for utxo chains, default rpc which
+	// serves the endpoints with bitcoin core, esplora takes rest api base urls
+	BtcSource enums.BtcSource
+	// Tokens detects runes and inscription deposits with the ord server at
+	// OrdUrl, for utxo chains, only the runes ord verified are credited
+	Tokens bool
+	// WithdrawPubKeys are the compressed bitcoin pubkeys of the validator node
+	// keys, for utxo chains, their WithdrawThreshold of n p2wsh multisig is the
+	// listen address the withdrawals are paid from
+	WithdrawPubKeys   []string
+	WithdrawThreshold int
+	// FeeRate is the withdrawal fee rate in sat/vB, for utxo chains
+	FeeRate int64
+	// Refund queues the mined deposits no message can be built for to be paid
+	// back to their sender by the withdrawals, for utxo chains
+	Refund bool
+	// Zmq is the -zmqpubhashblock address of bitcoin core waking the block
+	// indexer, for utxo chains, GetBlockCount is still polled
+	Zmq string
+	// Prefetch is the number of blocks fetched and parsed at once while the
+	// listener catches up, for utxo chains, default 1
+	Prefetch int
+	// OrdUrl is the json api of the ord server indexing the chain, for utxo
+	// chains, required by Tokens
+	OrdUrl string
+	// WithdrawSources are the contracts of the source chains whose call
+	// messages are paid out, for utxo chains, refunds aside no other message is
+	WithdrawSources []WithdrawSource
+	// SignedDb is the sqlite file of a validator keeping the withdrawal txs it
+	// signed, for utxo chains, required by WithdrawPubKeys in the validator
+	SignedDb string
 }
 
 type Particle struct {
@@ -82,13 +133,123 @@ func LoadConfig(input string) AppConfig {
 
 	v.SetEnvPrefix("app")
 
-	if err := v.Unmarshal(&config); err != nil {
+	if err := v.Unmarshal(&config, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		jsonToChainsHookFunc(),
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	))); err != nil {
+		panic(err)
+	}
+
+	if err := checkChains(config.Chains); err != nil {
 		panic(err)
 	}
 
 	return config
 }
 
+// Chain returns the configured chain with the given chain id.
+func (c AppConfig) Chain(chainId int64) (Blockchain, bool) {
+	for _, chain := range c.Chains {
+		if chain.ChainId == chainId {
+			return chain, true
+		}
+	}
+	return Blockchain{}, false
+}
+
+// Endpoints returns the rpc endpoints of the chain, RpcUrl first.
+func (c Blockchain) Endpoints() []string {
+	urls := make([]string, 0, len(c.RpcUrls)+1)
+	seen := make(map[string]bool)
+	for _, url := range append([]string{c.RpcUrl}, c.RpcUrls...) {
+		url = strings.TrimSpace(url)
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// jsonToChainsHookFunc decodes chains given as a json array, e.g. APP_CHAINS from env
+func jsonToChainsHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != reflect.TypeOf([]Blockchain{}) {
+			return data, nil
+		}
+		var chains []map[string]interface{}
+		if err := json.Unmarshal([]byte(data.(string)), &chains); err != nil {
+			return nil, err
+		}
+		return chains, nil
+	}
+}
+
+func checkChains(chains []Blockchain) error {
+	names := make(map[string]bool)
+	chainIds := make(map[int64]bool)
+	for _, chain := range chains {
+		if chain.Name == "" {
+			return errors.New("chain name is empty")
+		}
+		if names[chain.Name] {
+			return fmt.Errorf("duplicate chain name: %s", chain.Name)
+		}
+		if chainIds[chain.ChainId] {
+			return fmt.Errorf("duplicate chain id: %d", chain.ChainId)
+		}
+		if chain.ChainType != enums.ChainTypeEVM && chain.ChainType != enums.ChainTypeUTXO {
+			return fmt.Errorf("invalid chain type: %s#%d", chain.Name, chain.ChainType)
+		}
+		if len(chain.Endpoints()) == 0 {
+			return fmt.Errorf("chain rpc url is empty: %s", chain.Name)
+		}
+		if chain.ChainType == enums.ChainTypeUTXO {
+			if _, err := chain.BitcoinRoutes(); err != nil {
+				return err
+			}
+			if chain.BtcSource != "" &&
+				chain.BtcSource != enums.BtcSourceRpc &&
+				chain.BtcSource != enums.BtcSourceEsplora {
+				return fmt.Errorf("invalid btc source: %s#%s", chain.Name, chain.BtcSource)
+			}
+			if len(chain.WithdrawPubKeys) > 0 &&
+				(chain.WithdrawThreshold <= 0 || chain.WithdrawThreshold > len(chain.WithdrawPubKeys) || chain.FeeRate <= 0) {
+				return fmt.Errorf("invalid withdraw threshold or fee rate: %s", chain.Name)
+			}
+			if chain.Prefetch < 0 {
+				return fmt.Errorf("invalid prefetch: %s#%d", chain.Name, chain.Prefetch)
+			}
+			if chain.Zmq != "" && !strings.HasPrefix(chain.Zmq, "tcp://") {
+				return fmt.Errorf("invalid zmq address: %s#%s", chain.Name, chain.Zmq)
+			}
+			// the refunds spend the withdraw multisig, the deposits of other routes are not in it
+			if chain.Refund && len(chain.Routes) > 0 {
+				return fmt.Errorf("refund takes no routes besides the listen address: %s", chain.Name)
+			}
+			if chain.Tokens && chain.OrdUrl == "" {
+				return fmt.Errorf("ord url is empty: %s", chain.Name)
+			}
+			for _, source := range chain.WithdrawSources {
+				if !common.IsHexAddress(source.MessageBridge) || !common.IsHexAddress(source.Sender) {
+					return fmt.Errorf("invalid withdraw source: %s#%d", chain.Name, source.ChainId)
+				}
+			}
+		}
+		if chain.ChainType == enums.ChainTypeEVM && chain.Finality != "" &&
+			chain.Finality != enums.FinalityConfirmations &&
+			chain.Finality != enums.FinalitySafe &&
+			chain.Finality != enums.FinalityFinalized {
+			return fmt.Errorf("invalid finality: %s#%s", chain.Name, chain.Finality)
+		}
+		names[chain.Name] = true
+		chainIds[chain.ChainId] = true
+	}
+	return nil
+}
+
 func ParseBridges(input string) (map[int64]string, error) {
 	bridges := make(map[int64]string)
 	pairs := strings.Split(input, ",")
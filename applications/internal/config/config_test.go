@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"testing"
+)
+
+func TestCheckChains(t *testing.T) {
+	bitcoin := Blockchain{
+		Name:          "bitcoin",
+		ChainType:     enums.ChainTypeUTXO,
+		RpcUrl:        "127.0.0.1:8332",
+		ListenAddress: "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+	}
+	route := BitcoinRoute{ListenAddress: "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", ToChainId: 1123}
+
+	for name, test := range map[string]struct {
+		change func(chain *Blockchain)
+		valid  bool
+	}{
+		"listen address":   {change: func(chain *Blockchain) {}, valid: true},
+		"routes":           {change: func(chain *Blockchain) { chain.Routes = []BitcoinRoute{route} }, valid: true},
+		"refund":           {change: func(chain *Blockchain) { chain.Refund = true }, valid: true},
+		"refund of routes": {change: func(chain *Blockchain) { chain.Refund = true; chain.Routes = []BitcoinRoute{route} }},
+		"duplicate route": {change: func(chain *Blockchain) {
+			chain.Routes = []BitcoinRoute{{ListenAddress: chain.ListenAddress, ToChainId: 1123}}
+		}},
+		"tokens without ord": {change: func(chain *Blockchain) { chain.Tokens = true }},
+		"withdraw threshold": {change: func(chain *Blockchain) {
+			chain.WithdrawPubKeys = []string{"02", "03"}
+			chain.WithdrawThreshold = 3
+			chain.FeeRate = 10
+		}},
+	} {
+		chain := bitcoin
+		test.change(&chain)
+		err := checkChains([]Blockchain{chain})
+		if (err == nil) != test.valid {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"fmt"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BitcoinRoute sends the deposits to a watched bitcoin address to a business
+// contract on the destination chain.
+type BitcoinRoute struct {
+	ListenAddress string
+	ToChainId     int64
+	// ToMessageBridge defaults to the bridge of ToChainId
+	ToMessageBridge   string
+	ToContractAddress string
+}
+
+// WithdrawSource is a contract of a source chain paid out by a utxo chain, the
+// sender of the calls through MessageBridge.
+type WithdrawSource struct {
+	ChainId       int64
+	MessageBridge string
+	Sender        string
+}
+
+// BitcoinParams returns the network params of a utxo chain, testnet3 unless mainnet.
+func (c Blockchain) BitcoinParams() *chaincfg.Params {
+	if c.Mainnet {
+		return &chaincfg.MainNetParams
+	}
+	return &chaincfg.TestNet3Params
+}
+
+// Withdrawals reports whether a utxo chain pays out the messages targeting it.
+func (c Blockchain) Withdrawals() bool {
+	return c.ChainType == enums.ChainTypeUTXO && len(c.WithdrawPubKeys) > 0
+}
+
+// WithdrawAllowed reports whether a utxo chain pays out a call message of a
+// source chain, a refund of the chain itself or a listed source.
+func (c Blockchain) WithdrawAllowed(fromChainId int64, fromMessageBridge string, fromSender string) bool {
+	if fromChainId == c.ChainId {
+		return true
+	}
+	for _, source := range c.WithdrawSources {
+		if source.ChainId == fromChainId &&
+			common.HexToAddress(source.MessageBridge) == common.HexToAddress(fromMessageBridge) &&
+			common.HexToAddress(source.Sender) == common.HexToAddress(fromSender) {
+			return true
+		}
+	}
+	return false
+}
+
+// BitcoinRoutes returns the routes of a utxo chain keyed by the encoded listen
+// address, ListenAddress with ToChainId and ToContractAddress is a route too.
+func (c Blockchain) BitcoinRoutes() (map[string]BitcoinRoute, error) {
+	routes := make(map[string]BitcoinRoute)
+	list := c.Routes
+	if c.ListenAddress != "" {
+		list = append([]BitcoinRoute{{
+			ListenAddress:     c.ListenAddress,
+			ToChainId:         c.ToChainId,
+			ToContractAddress: c.ToContractAddress,
+		}}, list...)
+	}
+	for _, route := range list {
+		address, err := btcutil.DecodeAddress(route.ListenAddress, c.BitcoinParams())
+		if err != nil {
+			return nil, fmt.Errorf("invalid route listen address: %s#%s, %w", c.Name, route.ListenAddress, err)
+		}
+		if _, ok := routes[address.EncodeAddress()]; ok {
+			return nil, fmt.Errorf("duplicate route listen address: %s#%s", c.Name, route.ListenAddress)
+		}
+		route.ListenAddress = address.EncodeAddress()
+		routes[route.ListenAddress] = route
+	}
+	return routes, nil
+}
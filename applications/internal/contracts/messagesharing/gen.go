@@ -0,0 +1,3 @@
+package messagesharing
+
+//go:generate abigen --abi MessageSharing.abi --pkg messagesharing --type MessageSharing --out message_sharing.go
@@ -0,0 +1,1000 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package messagesharing
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// MessageSharingMetaData contains all meta data concerning the MessageSharing contract.
+var MessageSharingMetaData = &bind.MetaData{
+	ABI: "[{\"type\":\"function\",\"name\":\"validatorRole\",\"stateMutability\":\"pure\",\"inputs\":[{\"name\":\"chain_id\",\"type\":\"uint256\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bytes32\"}]},{\"type\":\"function\",\"name\":\"SendHash\",\"stateMutability\":\"view\",\"inputs\":[{\"name\":\"from_chain_id\",\"type\":\"uint256\"},{\"name\":\"from_id\",\"type\":\"uint256\"},{\"name\":\"from_sender\",\"type\":\"address\"},{\"name\":\"to_chain_id\",\"type\":\"uint256\"},{\"name\":\"to_business_contract\",\"type\":\"address\"},{\"name\":\"to_message\",\"type\":\"bytes\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bytes32\"}]},{\"type\":\"function\",\"name\":\"verify\",\"stateMutability\":\"view\",\"inputs\":[{\"name\":\"from_chain_id\",\"type\":\"uint256\"},{\"name\":\"from_id\",\"type\":\"uint256\"},{\"name\":\"from_sender\",\"type\":\"address\"},{\"name\":\"to_chain_id\",\"type\":\"uint256\"},{\"name\":\"to_business_contract\",\"type\":\"address\"},{\"name\":\"to_message\",\"type\":\"bytes\"},{\"name\":\"signature\",\"type\":\"bytes\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\"}]},{\"type\":\"function\",\"name\":\"setWeight\",\"stateMutability\":\"nonpayable\",\"inputs\":[{\"name\":\"chain_id\",\"type\":\"uint256\"},{\"name\":\"_weight\",\"type\":\"uint256\"}],\"outputs\":[]},{\"type\":\"function\",\"name\":\"call\",\"stateMutability\":\"nonpayable\",\"inputs\":[{\"name\":\"to_chain_id\",\"type\":\"uint256\"},{\"name\":\"to_business_contract\",\"type\":\"address\"},{\"name\":\"to_message\",\"type\":\"bytes\"}],\"outputs\":[{\"name\":\"from_id\",\"type\":\"uint256\"}]},{\"type\":\"function\",\"name\":\"send\",\"stateMutability\":\"nonpayable\",\"inputs\":[{\"name\":\"from_chain_id\",\"type\":\"uint256\"},{\"name\":\"from_id\",\"type\":\"uint256\"},{\"name\":\"from_sender\",\"type\":\"address\"},{\"name\":\"to_business_contract\",\"type\":\"address\"},{\"name\":\"to_message\",\"type\":\"bytes\"},{\"name\":\"signatures\",\"type\":\"bytes[]\"}],\"outputs\":[]},{\"type\":\"function\",\"name\":\"setValidatorRole\",\"stateMutability\":\"nonpayable\",\"inputs\":[{\"name\":\"chain_id\",\"type\":\"uint256\"},{\"name\":\"account\",\"type\":\"address\"},{\"name\":\"valid\",\"type\":\"bool\"}],\"outputs\":[]},{\"type\":\"function\",\"name\":\"sequences\",\"stateMutability\":\"view\",\"inputs\":[{\"name\":\"\",\"type\":\"uint256\"}],\"outputs\":[{\"name\":\"\",\"type\":\"uint256\"}]},{\"type\":\"function\",\"name\":\"ids\",\"stateMutability\":\"view\",\"inputs\":[{\"name\":\"\",\"type\":\"uint256\"},{\"name\":\"\",\"type\":\"uint256\"}],\"outputs\":[{\"name\":\"\",\"type\":\"bool\"}]},{\"type\":\"function\",\"name\":\"weights\",\"stateMutability\":\"view\",\"inputs\":[{\"name\":\"\",\"type\":\"uint256\"}],\"outputs\":[{\"name\":\"\",\"type\":\"uint256\"}]},{\"type\":\"event\",\"name\":\"SetWeight\",\"anonymous\":false,\"inputs\":[{\"name\":\"chain_id\",\"type\":\"uint256\",\"indexed\":false},{\"name\":\"weight\",\"type\":\"uint256\",\"indexed\":false}]},{\"type\":\"event\",\"name\":\"SetValidatorRole\",\"anonymous\":false,\"inputs\":[{\"name\":\"chain_id\",\"type\":\"uint256\",\"indexed\":false},{\"name\":\"account\",\"type\":\"address\",\"indexed\":false},{\"name\":\"valid\",\"type\":\"bool\",\"indexed\":false}]},{\"type\":\"event\",\"name\":\"Send\",\"anonymous\":false,\"inputs\":[{\"name\":\"from_chain_id\",\"type\":\"uint256\",\"indexed\":false},{\"name\":\"from_id\",\"type\":\"uint256\",\"indexed\":false},{\"name\":\"from_sender\",\"type\":\"address\",\"indexed\":false},{\"name\":\"to_chain_id\",\"type\":\"uint256\",\"indexed\":false},{\"name\":\"to_business_contract\",\"type\":\"address\",\"indexed\":false},{\"name\":\"to_message\",\"type\":\"bytes\",\"indexed\":false}]},{\"type\":\"event\",\"name\":\"Call\",\"anonymous\":false,\"inputs\":[{\"name\":\"from_chain_id\",\"type\":\"uint256\",\"indexed\":false},{\"name\":\"from_id\",\"type\":\"uint256\",\"indexed\":false},{\"name\":\"from_sender\",\"type\":\"address\",\"indexed\":false},{\"name\":\"to_chain_id\",\"type\":\"uint256\",\"indexed\":false},{\"name\":\"to_business_contract\",\"type\":\"address\",\"indexed\":false},{\"name\":\"to_message\",\"type\":\"bytes\",\"indexed\":false}]}]",
+}
+
+// MessageSharingABI is the input ABI used to generate the binding from.
+// Deprecated: Use MessageSharingMetaData.ABI instead.
+var MessageSharingABI = MessageSharingMetaData.ABI
+
+// MessageSharing is an auto generated Go binding around an Ethereum contract.
+type MessageSharing struct {
+	MessageSharingCaller     // Read-only binding to the contract
+	MessageSharingTransactor // Write-only binding to the contract
+	MessageSharingFilterer   // Log filterer for contract events
+}
+
+// MessageSharingCaller is an auto generated read-only Go binding around an Ethereum contract.
+type MessageSharingCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// MessageSharingTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type MessageSharingTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// MessageSharingFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type MessageSharingFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// MessageSharingSession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type MessageSharingSession struct {
+	Contract     *MessageSharing   // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// MessageSharingCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type MessageSharingCallerSession struct {
+	Contract *MessageSharingCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts         // Call options to use throughout this session
+}
+
+// MessageSharingTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type MessageSharingTransactorSession struct {
+	Contract     *MessageSharingTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts         // Transaction auth options to use throughout this session
+}
+
+// MessageSharingRaw is an auto generated low-level Go binding around an Ethereum contract.
+type MessageSharingRaw struct {
+	Contract *MessageSharing // Generic contract binding to access the raw methods on
+}
+
+// MessageSharingCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type MessageSharingCallerRaw struct {
+	Contract *MessageSharingCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// MessageSharingTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type MessageSharingTransactorRaw struct {
+	Contract *MessageSharingTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewMessageSharing creates a new instance of MessageSharing, bound to a specific deployed contract.
+func NewMessageSharing(address common.Address, backend bind.ContractBackend) (*MessageSharing, error) {
+	contract, err := bindMessageSharing(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageSharing{MessageSharingCaller: MessageSharingCaller{contract: contract}, MessageSharingTransactor: MessageSharingTransactor{contract: contract}, MessageSharingFilterer: MessageSharingFilterer{contract: contract}}, nil
+}
+
+// NewMessageSharingCaller creates a new read-only instance of MessageSharing, bound to a specific deployed contract.
+func NewMessageSharingCaller(address common.Address, caller bind.ContractCaller) (*MessageSharingCaller, error) {
+	contract, err := bindMessageSharing(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageSharingCaller{contract: contract}, nil
+}
+
+// NewMessageSharingTransactor creates a new write-only instance of MessageSharing, bound to a specific deployed contract.
+func NewMessageSharingTransactor(address common.Address, transactor bind.ContractTransactor) (*MessageSharingTransactor, error) {
+	contract, err := bindMessageSharing(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageSharingTransactor{contract: contract}, nil
+}
+
+// NewMessageSharingFilterer creates a new log filterer instance of MessageSharing, bound to a specific deployed contract.
+func NewMessageSharingFilterer(address common.Address, filterer bind.ContractFilterer) (*MessageSharingFilterer, error) {
+	contract, err := bindMessageSharing(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageSharingFilterer{contract: contract}, nil
+}
+
+// bindMessageSharing binds a generic wrapper to an already deployed contract.
+func bindMessageSharing(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := MessageSharingMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_MessageSharing *MessageSharingRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _MessageSharing.Contract.MessageSharingCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_MessageSharing *MessageSharingRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _MessageSharing.Contract.MessageSharingTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_MessageSharing *MessageSharingRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _MessageSharing.Contract.MessageSharingTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_MessageSharing *MessageSharingCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _MessageSharing.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_MessageSharing *MessageSharingTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _MessageSharing.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_MessageSharing *MessageSharingTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _MessageSharing.Contract.contract.Transact(opts, method, params...)
+}
+
+// SendHash is a free data retrieval call binding the contract method 0x04760f2e.
+//
+// Solidity: function SendHash(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message) view returns(bytes32)
+func (_MessageSharing *MessageSharingCaller) SendHash(opts *bind.CallOpts, from_chain_id *big.Int, from_id *big.Int, from_sender common.Address, to_chain_id *big.Int, to_business_contract common.Address, to_message []byte) ([32]byte, error) {
+	var out []interface{}
+	err := _MessageSharing.contract.Call(opts, &out, "SendHash", from_chain_id, from_id, from_sender, to_chain_id, to_business_contract, to_message)
+
+	if err != nil {
+		return *new([32]byte), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([32]byte)).(*[32]byte)
+
+	return out0, err
+
+}
+
+// SendHash is a free data retrieval call binding the contract method 0x04760f2e.
+//
+// Solidity: function SendHash(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message) view returns(bytes32)
+func (_MessageSharing *MessageSharingSession) SendHash(from_chain_id *big.Int, from_id *big.Int, from_sender common.Address, to_chain_id *big.Int, to_business_contract common.Address, to_message []byte) ([32]byte, error) {
+	return _MessageSharing.Contract.SendHash(&_MessageSharing.CallOpts, from_chain_id, from_id, from_sender, to_chain_id, to_business_contract, to_message)
+}
+
+// SendHash is a free data retrieval call binding the contract method 0x04760f2e.
+//
+// Solidity: function SendHash(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message) view returns(bytes32)
+func (_MessageSharing *MessageSharingCallerSession) SendHash(from_chain_id *big.Int, from_id *big.Int, from_sender common.Address, to_chain_id *big.Int, to_business_contract common.Address, to_message []byte) ([32]byte, error) {
+	return _MessageSharing.Contract.SendHash(&_MessageSharing.CallOpts, from_chain_id, from_id, from_sender, to_chain_id, to_business_contract, to_message)
+}
+
+// Ids is a free data retrieval call binding the contract method 0x9d449d11.
+//
+// Solidity: function ids(uint256 , uint256 ) view returns(bool)
+func (_MessageSharing *MessageSharingCaller) Ids(opts *bind.CallOpts, arg0 *big.Int, arg1 *big.Int) (bool, error) {
+	var out []interface{}
+	err := _MessageSharing.contract.Call(opts, &out, "ids", arg0, arg1)
+
+	if err != nil {
+		return *new(bool), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+
+	return out0, err
+
+}
+
+// Ids is a free data retrieval call binding the contract method 0x9d449d11.
+//
+// Solidity: function ids(uint256 , uint256 ) view returns(bool)
+func (_MessageSharing *MessageSharingSession) Ids(arg0 *big.Int, arg1 *big.Int) (bool, error) {
+	return _MessageSharing.Contract.Ids(&_MessageSharing.CallOpts, arg0, arg1)
+}
+
+// Ids is a free data retrieval call binding the contract method 0x9d449d11.
+//
+// Solidity: function ids(uint256 , uint256 ) view returns(bool)
+func (_MessageSharing *MessageSharingCallerSession) Ids(arg0 *big.Int, arg1 *big.Int) (bool, error) {
+	return _MessageSharing.Contract.Ids(&_MessageSharing.CallOpts, arg0, arg1)
+}
+
+// Sequences is a free data retrieval call binding the contract method 0xc86a64f7.
+//
+// Solidity: function sequences(uint256 ) view returns(uint256)
+func (_MessageSharing *MessageSharingCaller) Sequences(opts *bind.CallOpts, arg0 *big.Int) (*big.Int, error) {
+	var out []interface{}
+	err := _MessageSharing.contract.Call(opts, &out, "sequences", arg0)
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// Sequences is a free data retrieval call binding the contract method 0xc86a64f7.
+//
+// Solidity: function sequences(uint256 ) view returns(uint256)
+func (_MessageSharing *MessageSharingSession) Sequences(arg0 *big.Int) (*big.Int, error) {
+	return _MessageSharing.Contract.Sequences(&_MessageSharing.CallOpts, arg0)
+}
+
+// Sequences is a free data retrieval call binding the contract method 0xc86a64f7.
+//
+// Solidity: function sequences(uint256 ) view returns(uint256)
+func (_MessageSharing *MessageSharingCallerSession) Sequences(arg0 *big.Int) (*big.Int, error) {
+	return _MessageSharing.Contract.Sequences(&_MessageSharing.CallOpts, arg0)
+}
+
+// ValidatorRole is a free data retrieval call binding the contract method 0x593792ec.
+//
+// Solidity: function validatorRole(uint256 chain_id) pure returns(bytes32)
+func (_MessageSharing *MessageSharingCaller) ValidatorRole(opts *bind.CallOpts, chain_id *big.Int) ([32]byte, error) {
+	var out []interface{}
+	err := _MessageSharing.contract.Call(opts, &out, "validatorRole", chain_id)
+
+	if err != nil {
+		return *new([32]byte), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new([32]byte)).(*[32]byte)
+
+	return out0, err
+
+}
+
+// ValidatorRole is a free data retrieval call binding the contract method 0x593792ec.
+//
+// Solidity: function validatorRole(uint256 chain_id) pure returns(bytes32)
+func (_MessageSharing *MessageSharingSession) ValidatorRole(chain_id *big.Int) ([32]byte, error) {
+	return _MessageSharing.Contract.ValidatorRole(&_MessageSharing.CallOpts, chain_id)
+}
+
+// ValidatorRole is a free data retrieval call binding the contract method 0x593792ec.
+//
+// Solidity: function validatorRole(uint256 chain_id) pure returns(bytes32)
+func (_MessageSharing *MessageSharingCallerSession) ValidatorRole(chain_id *big.Int) ([32]byte, error) {
+	return _MessageSharing.Contract.ValidatorRole(&_MessageSharing.CallOpts, chain_id)
+}
+
+// Verify is a free data retrieval call binding the contract method 0xbc5c11aa.
+//
+// Solidity: function verify(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message, bytes signature) view returns(bool)
+func (_MessageSharing *MessageSharingCaller) Verify(opts *bind.CallOpts, from_chain_id *big.Int, from_id *big.Int, from_sender common.Address, to_chain_id *big.Int, to_business_contract common.Address, to_message []byte, signature []byte) (bool, error) {
+	var out []interface{}
+	err := _MessageSharing.contract.Call(opts, &out, "verify", from_chain_id, from_id, from_sender, to_chain_id, to_business_contract, to_message, signature)
+
+	if err != nil {
+		return *new(bool), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(bool)).(*bool)
+
+	return out0, err
+
+}
+
+// Verify is a free data retrieval call binding the contract method 0xbc5c11aa.
+//
+// Solidity: function verify(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message, bytes signature) view returns(bool)
+func (_MessageSharing *MessageSharingSession) Verify(from_chain_id *big.Int, from_id *big.Int, from_sender common.Address, to_chain_id *big.Int, to_business_contract common.Address, to_message []byte, signature []byte) (bool, error) {
+	return _MessageSharing.Contract.Verify(&_MessageSharing.CallOpts, from_chain_id, from_id, from_sender, to_chain_id, to_business_contract, to_message, signature)
+}
+
+// Verify is a free data retrieval call binding the contract method 0xbc5c11aa.
+//
+// Solidity: function verify(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message, bytes signature) view returns(bool)
+func (_MessageSharing *MessageSharingCallerSession) Verify(from_chain_id *big.Int, from_id *big.Int, from_sender common.Address, to_chain_id *big.Int, to_business_contract common.Address, to_message []byte, signature []byte) (bool, error) {
+	return _MessageSharing.Contract.Verify(&_MessageSharing.CallOpts, from_chain_id, from_id, from_sender, to_chain_id, to_business_contract, to_message, signature)
+}
+
+// Weights is a free data retrieval call binding the contract method 0xb5f163ff.
+//
+// Solidity: function weights(uint256 ) view returns(uint256)
+func (_MessageSharing *MessageSharingCaller) Weights(opts *bind.CallOpts, arg0 *big.Int) (*big.Int, error) {
+	var out []interface{}
+	err := _MessageSharing.contract.Call(opts, &out, "weights", arg0)
+
+	if err != nil {
+		return *new(*big.Int), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	return out0, err
+
+}
+
+// Weights is a free data retrieval call binding the contract method 0xb5f163ff.
+//
+// Solidity: function weights(uint256 ) view returns(uint256)
+func (_MessageSharing *MessageSharingSession) Weights(arg0 *big.Int) (*big.Int, error) {
+	return _MessageSharing.Contract.Weights(&_MessageSharing.CallOpts, arg0)
+}
+
+// Weights is a free data retrieval call binding the contract method 0xb5f163ff.
+//
+// Solidity: function weights(uint256 ) view returns(uint256)
+func (_MessageSharing *MessageSharingCallerSession) Weights(arg0 *big.Int) (*big.Int, error) {
+	return _MessageSharing.Contract.Weights(&_MessageSharing.CallOpts, arg0)
+}
+
+// Call is a paid mutator transaction binding the contract method 0x6ba3300c.
+//
+// Solidity: function call(uint256 to_chain_id, address to_business_contract, bytes to_message) returns(uint256 from_id)
+func (_MessageSharing *MessageSharingTransactor) Call(opts *bind.TransactOpts, to_chain_id *big.Int, to_business_contract common.Address, to_message []byte) (*types.Transaction, error) {
+	return _MessageSharing.contract.Transact(opts, "call", to_chain_id, to_business_contract, to_message)
+}
+
+// Call is a paid mutator transaction binding the contract method 0x6ba3300c.
+//
+// Solidity: function call(uint256 to_chain_id, address to_business_contract, bytes to_message) returns(uint256 from_id)
+func (_MessageSharing *MessageSharingSession) Call(to_chain_id *big.Int, to_business_contract common.Address, to_message []byte) (*types.Transaction, error) {
+	return _MessageSharing.Contract.Call(&_MessageSharing.TransactOpts, to_chain_id, to_business_contract, to_message)
+}
+
+// Call is a paid mutator transaction binding the contract method 0x6ba3300c.
+//
+// Solidity: function call(uint256 to_chain_id, address to_business_contract, bytes to_message) returns(uint256 from_id)
+func (_MessageSharing *MessageSharingTransactorSession) Call(to_chain_id *big.Int, to_business_contract common.Address, to_message []byte) (*types.Transaction, error) {
+	return _MessageSharing.Contract.Call(&_MessageSharing.TransactOpts, to_chain_id, to_business_contract, to_message)
+}
+
+// Send is a paid mutator transaction binding the contract method 0x0d682acb.
+//
+// Solidity: function send(uint256 from_chain_id, uint256 from_id, address from_sender, address to_business_contract, bytes to_message, bytes[] signatures) returns()
+func (_MessageSharing *MessageSharingTransactor) Send(opts *bind.TransactOpts, from_chain_id *big.Int, from_id *big.Int, from_sender common.Address, to_business_contract common.Address, to_message []byte, signatures [][]byte) (*types.Transaction, error) {
+	return _MessageSharing.contract.Transact(opts, "send", from_chain_id, from_id, from_sender, to_business_contract, to_message, signatures)
+}
+
+// Send is a paid mutator transaction binding the contract method 0x0d682acb.
+//
+// Solidity: function send(uint256 from_chain_id, uint256 from_id, address from_sender, address to_business_contract, bytes to_message, bytes[] signatures) returns()
+func (_MessageSharing *MessageSharingSession) Send(from_chain_id *big.Int, from_id *big.Int, from_sender common.Address, to_business_contract common.Address, to_message []byte, signatures [][]byte) (*types.Transaction, error) {
+	return _MessageSharing.Contract.Send(&_MessageSharing.TransactOpts, from_chain_id, from_id, from_sender, to_business_contract, to_message, signatures)
+}
+
+// Send is a paid mutator transaction binding the contract method 0x0d682acb.
+//
+// Solidity: function send(uint256 from_chain_id, uint256 from_id, address from_sender, address to_business_contract, bytes to_message, bytes[] signatures) returns()
+func (_MessageSharing *MessageSharingTransactorSession) Send(from_chain_id *big.Int, from_id *big.Int, from_sender common.Address, to_business_contract common.Address, to_message []byte, signatures [][]byte) (*types.Transaction, error) {
+	return _MessageSharing.Contract.Send(&_MessageSharing.TransactOpts, from_chain_id, from_id, from_sender, to_business_contract, to_message, signatures)
+}
+
+// SetValidatorRole is a paid mutator transaction binding the contract method 0xc488e06c.
+//
+// Solidity: function setValidatorRole(uint256 chain_id, address account, bool valid) returns()
+func (_MessageSharing *MessageSharingTransactor) SetValidatorRole(opts *bind.TransactOpts, chain_id *big.Int, account common.Address, valid bool) (*types.Transaction, error) {
+	return _MessageSharing.contract.Transact(opts, "setValidatorRole", chain_id, account, valid)
+}
+
+// SetValidatorRole is a paid mutator transaction binding the contract method 0xc488e06c.
+//
+// Solidity: function setValidatorRole(uint256 chain_id, address account, bool valid) returns()
+func (_MessageSharing *MessageSharingSession) SetValidatorRole(chain_id *big.Int, account common.Address, valid bool) (*types.Transaction, error) {
+	return _MessageSharing.Contract.SetValidatorRole(&_MessageSharing.TransactOpts, chain_id, account, valid)
+}
+
+// SetValidatorRole is a paid mutator transaction binding the contract method 0xc488e06c.
+//
+// Solidity: function setValidatorRole(uint256 chain_id, address account, bool valid) returns()
+func (_MessageSharing *MessageSharingTransactorSession) SetValidatorRole(chain_id *big.Int, account common.Address, valid bool) (*types.Transaction, error) {
+	return _MessageSharing.Contract.SetValidatorRole(&_MessageSharing.TransactOpts, chain_id, account, valid)
+}
+
+// SetWeight is a paid mutator transaction binding the contract method 0x864ad659.
+//
+// Solidity: function setWeight(uint256 chain_id, uint256 _weight) returns()
+func (_MessageSharing *MessageSharingTransactor) SetWeight(opts *bind.TransactOpts, chain_id *big.Int, _weight *big.Int) (*types.Transaction, error) {
+	return _MessageSharing.contract.Transact(opts, "setWeight", chain_id, _weight)
+}
+
+// SetWeight is a paid mutator transaction binding the contract method 0x864ad659.
+//
+// Solidity: function setWeight(uint256 chain_id, uint256 _weight) returns()
+func (_MessageSharing *MessageSharingSession) SetWeight(chain_id *big.Int, _weight *big.Int) (*types.Transaction, error) {
+	return _MessageSharing.Contract.SetWeight(&_MessageSharing.TransactOpts, chain_id, _weight)
+}
+
+// SetWeight is a paid mutator transaction binding the contract method 0x864ad659.
+//
+// Solidity: function setWeight(uint256 chain_id, uint256 _weight) returns()
+func (_MessageSharing *MessageSharingTransactorSession) SetWeight(chain_id *big.Int, _weight *big.Int) (*types.Transaction, error) {
+	return _MessageSharing.Contract.SetWeight(&_MessageSharing.TransactOpts, chain_id, _weight)
+}
+
+// MessageSharingCallIterator is returned from FilterCall and is used to iterate over the raw logs and unpacked data for Call events raised by the MessageSharing contract.
+type MessageSharingCallIterator struct {
+	Event *MessageSharingCall // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *MessageSharingCallIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(MessageSharingCall)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(MessageSharingCall)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *MessageSharingCallIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *MessageSharingCallIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// MessageSharingCall represents a Call event raised by the MessageSharing contract.
+type MessageSharingCall struct {
+	FromChainId        *big.Int
+	FromId             *big.Int
+	FromSender         common.Address
+	ToChainId          *big.Int
+	ToBusinessContract common.Address
+	ToMessage          []byte
+	Raw                types.Log // Blockchain specific contextual infos
+}
+
+// FilterCall is a free log retrieval operation binding the contract event 0x599c34a8d0b3638870afcfe3d7d8125602721889a7535cda986ea656e63fc38c.
+//
+// Solidity: event Call(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message)
+func (_MessageSharing *MessageSharingFilterer) FilterCall(opts *bind.FilterOpts) (*MessageSharingCallIterator, error) {
+
+	logs, sub, err := _MessageSharing.contract.FilterLogs(opts, "Call")
+	if err != nil {
+		return nil, err
+	}
+	return &MessageSharingCallIterator{contract: _MessageSharing.contract, event: "Call", logs: logs, sub: sub}, nil
+}
+
+// WatchCall is a free log subscription operation binding the contract event 0x599c34a8d0b3638870afcfe3d7d8125602721889a7535cda986ea656e63fc38c.
+//
+// Solidity: event Call(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message)
+func (_MessageSharing *MessageSharingFilterer) WatchCall(opts *bind.WatchOpts, sink chan<- *MessageSharingCall) (event.Subscription, error) {
+
+	logs, sub, err := _MessageSharing.contract.WatchLogs(opts, "Call")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(MessageSharingCall)
+				if err := _MessageSharing.contract.UnpackLog(event, "Call", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseCall is a log parse operation binding the contract event 0x599c34a8d0b3638870afcfe3d7d8125602721889a7535cda986ea656e63fc38c.
+//
+// Solidity: event Call(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message)
+func (_MessageSharing *MessageSharingFilterer) ParseCall(log types.Log) (*MessageSharingCall, error) {
+	event := new(MessageSharingCall)
+	if err := _MessageSharing.contract.UnpackLog(event, "Call", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// MessageSharingSendIterator is returned from FilterSend and is used to iterate over the raw logs and unpacked data for Send events raised by the MessageSharing contract.
+type MessageSharingSendIterator struct {
+	Event *MessageSharingSend // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *MessageSharingSendIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(MessageSharingSend)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(MessageSharingSend)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *MessageSharingSendIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *MessageSharingSendIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// MessageSharingSend represents a Send event raised by the MessageSharing contract.
+type MessageSharingSend struct {
+	FromChainId        *big.Int
+	FromId             *big.Int
+	FromSender         common.Address
+	ToChainId          *big.Int
+	ToBusinessContract common.Address
+	ToMessage          []byte
+	Raw                types.Log // Blockchain specific contextual infos
+}
+
+// FilterSend is a free log retrieval operation binding the contract event 0x5849ae3f4bc77f0ebd2d6db4ff282f91f2191d3df4493e63176c2ed22fb81852.
+//
+// Solidity: event Send(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message)
+func (_MessageSharing *MessageSharingFilterer) FilterSend(opts *bind.FilterOpts) (*MessageSharingSendIterator, error) {
+
+	logs, sub, err := _MessageSharing.contract.FilterLogs(opts, "Send")
+	if err != nil {
+		return nil, err
+	}
+	return &MessageSharingSendIterator{contract: _MessageSharing.contract, event: "Send", logs: logs, sub: sub}, nil
+}
+
+// WatchSend is a free log subscription operation binding the contract event 0x5849ae3f4bc77f0ebd2d6db4ff282f91f2191d3df4493e63176c2ed22fb81852.
+//
+// Solidity: event Send(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message)
+func (_MessageSharing *MessageSharingFilterer) WatchSend(opts *bind.WatchOpts, sink chan<- *MessageSharingSend) (event.Subscription, error) {
+
+	logs, sub, err := _MessageSharing.contract.WatchLogs(opts, "Send")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(MessageSharingSend)
+				if err := _MessageSharing.contract.UnpackLog(event, "Send", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseSend is a log parse operation binding the contract event 0x5849ae3f4bc77f0ebd2d6db4ff282f91f2191d3df4493e63176c2ed22fb81852.
+//
+// Solidity: event Send(uint256 from_chain_id, uint256 from_id, address from_sender, uint256 to_chain_id, address to_business_contract, bytes to_message)
+func (_MessageSharing *MessageSharingFilterer) ParseSend(log types.Log) (*MessageSharingSend, error) {
+	event := new(MessageSharingSend)
+	if err := _MessageSharing.contract.UnpackLog(event, "Send", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// MessageSharingSetValidatorRoleIterator is returned from FilterSetValidatorRole and is used to iterate over the raw logs and unpacked data for SetValidatorRole events raised by the MessageSharing contract.
+type MessageSharingSetValidatorRoleIterator struct {
+	Event *MessageSharingSetValidatorRole // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *MessageSharingSetValidatorRoleIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(MessageSharingSetValidatorRole)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(MessageSharingSetValidatorRole)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *MessageSharingSetValidatorRoleIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *MessageSharingSetValidatorRoleIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// MessageSharingSetValidatorRole represents a SetValidatorRole event raised by the MessageSharing contract.
+type MessageSharingSetValidatorRole struct {
+	ChainId *big.Int
+	Account common.Address
+	Valid   bool
+	Raw     types.Log // Blockchain specific contextual infos
+}
+
+// FilterSetValidatorRole is a free log retrieval operation binding the contract event 0xc02c963166d28587fe092cdfd95313fb583c2340dc75a6161f8f533713f55799.
+//
+// Solidity: event SetValidatorRole(uint256 chain_id, address account, bool valid)
+func (_MessageSharing *MessageSharingFilterer) FilterSetValidatorRole(opts *bind.FilterOpts) (*MessageSharingSetValidatorRoleIterator, error) {
+
+	logs, sub, err := _MessageSharing.contract.FilterLogs(opts, "SetValidatorRole")
+	if err != nil {
+		return nil, err
+	}
+	return &MessageSharingSetValidatorRoleIterator{contract: _MessageSharing.contract, event: "SetValidatorRole", logs: logs, sub: sub}, nil
+}
+
+// WatchSetValidatorRole is a free log subscription operation binding the contract event 0xc02c963166d28587fe092cdfd95313fb583c2340dc75a6161f8f533713f55799.
+//
+// Solidity: event SetValidatorRole(uint256 chain_id, address account, bool valid)
+func (_MessageSharing *MessageSharingFilterer) WatchSetValidatorRole(opts *bind.WatchOpts, sink chan<- *MessageSharingSetValidatorRole) (event.Subscription, error) {
+
+	logs, sub, err := _MessageSharing.contract.WatchLogs(opts, "SetValidatorRole")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(MessageSharingSetValidatorRole)
+				if err := _MessageSharing.contract.UnpackLog(event, "SetValidatorRole", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseSetValidatorRole is a log parse operation binding the contract event 0xc02c963166d28587fe092cdfd95313fb583c2340dc75a6161f8f533713f55799.
+//
+// Solidity: event SetValidatorRole(uint256 chain_id, address account, bool valid)
+func (_MessageSharing *MessageSharingFilterer) ParseSetValidatorRole(log types.Log) (*MessageSharingSetValidatorRole, error) {
+	event := new(MessageSharingSetValidatorRole)
+	if err := _MessageSharing.contract.UnpackLog(event, "SetValidatorRole", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
+
+// MessageSharingSetWeightIterator is returned from FilterSetWeight and is used to iterate over the raw logs and unpacked data for SetWeight events raised by the MessageSharing contract.
+type MessageSharingSetWeightIterator struct {
+	Event *MessageSharingSetWeight // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *MessageSharingSetWeightIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(MessageSharingSetWeight)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(MessageSharingSetWeight)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *MessageSharingSetWeightIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *MessageSharingSetWeightIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// MessageSharingSetWeight represents a SetWeight event raised by the MessageSharing contract.
+type MessageSharingSetWeight struct {
+	ChainId *big.Int
+	Weight  *big.Int
+	Raw     types.Log // Blockchain specific contextual infos
+}
+
+// FilterSetWeight is a free log retrieval operation binding the contract event 0x73772429838399ddad5262733697b31811e2c64a54082589dc9801382da40fc2.
+//
+// Solidity: event SetWeight(uint256 chain_id, uint256 weight)
+func (_MessageSharing *MessageSharingFilterer) FilterSetWeight(opts *bind.FilterOpts) (*MessageSharingSetWeightIterator, error) {
+
+	logs, sub, err := _MessageSharing.contract.FilterLogs(opts, "SetWeight")
+	if err != nil {
+		return nil, err
+	}
+	return &MessageSharingSetWeightIterator{contract: _MessageSharing.contract, event: "SetWeight", logs: logs, sub: sub}, nil
+}
+
+// WatchSetWeight is a free log subscription operation binding the contract event 0x73772429838399ddad5262733697b31811e2c64a54082589dc9801382da40fc2.
+//
+// Solidity: event SetWeight(uint256 chain_id, uint256 weight)
+func (_MessageSharing *MessageSharingFilterer) WatchSetWeight(opts *bind.WatchOpts, sink chan<- *MessageSharingSetWeight) (event.Subscription, error) {
+
+	logs, sub, err := _MessageSharing.contract.WatchLogs(opts, "SetWeight")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(MessageSharingSetWeight)
+				if err := _MessageSharing.contract.UnpackLog(event, "SetWeight", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseSetWeight is a log parse operation binding the contract event 0x73772429838399ddad5262733697b31811e2c64a54082589dc9801382da40fc2.
+//
+// Solidity: event SetWeight(uint256 chain_id, uint256 weight)
+func (_MessageSharing *MessageSharingFilterer) ParseSetWeight(log types.Log) (*MessageSharingSetWeight, error) {
+	event := new(MessageSharingSetWeight)
+	if err := _MessageSharing.contract.UnpackLog(event, "SetWeight", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
@@ -0,0 +1,94 @@
+package messagesharing
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+var (
+	parsedABI = mustParseABI()
+	filterer  = mustFilterer()
+
+	CallEventId             = parsedABI.Events["Call"].ID
+	SendEventId             = parsedABI.Events["Send"].ID
+	SetWeightEventId        = parsedABI.Events["SetWeight"].ID
+	SetValidatorRoleEventId = parsedABI.Events["SetValidatorRole"].ID
+)
+
+func mustParseABI() *abi.ABI {
+	parsed, err := MessageSharingMetaData.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func mustFilterer() *MessageSharingFilterer {
+	f, err := NewMessageSharingFilterer(common.Address{}, nil)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// PackSend encodes the calldata of send.
+func PackSend(fromChainId *big.Int, fromId *big.Int, fromSender common.Address, toBusinessContract common.Address,
+	toMessage []byte, signatures [][]byte) ([]byte, error) {
+	data, err := parsedABI.Pack("send", fromChainId, fromId, fromSender, toBusinessContract, toMessage, signatures)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+// ParseCall decodes a Call log, a log of another event or with malformed
+// data returns an error.
+func ParseCall(log types.Log) (*MessageSharingCall, error) {
+	if len(log.Data) == 0 {
+		return nil, errors.New("call log data is empty")
+	}
+	call, err := filterer.ParseCall(log)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return call, nil
+}
+
+// ParseSend decodes a Send log.
+func ParseSend(log types.Log) (*MessageSharingSend, error) {
+	if len(log.Data) == 0 {
+		return nil, errors.New("send log data is empty")
+	}
+	send, err := filterer.ParseSend(log)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return send, nil
+}
+
+// ParseSetWeight decodes a SetWeight log.
+func ParseSetWeight(log types.Log) (*MessageSharingSetWeight, error) {
+	if len(log.Data) == 0 {
+		return nil, errors.New("set weight log data is empty")
+	}
+	setWeight, err := filterer.ParseSetWeight(log)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return setWeight, nil
+}
+
+// ParseSetValidatorRole decodes a SetValidatorRole log.
+func ParseSetValidatorRole(log types.Log) (*MessageSharingSetValidatorRole, error) {
+	if len(log.Data) == 0 {
+		return nil, errors.New("set validator role log data is empty")
+	}
+	setValidatorRole, err := filterer.ParseSetValidatorRole(log)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return setValidatorRole, nil
+}
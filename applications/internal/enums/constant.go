@@ -7,4 +7,8 @@ const (
 	P2PMessageTypeLogin
 	P2PMessageTypeProposal
 	P2PMessageTypeSign
+	// P2PMessageTypeWithdrawProposal asks the validators to sign a withdrawal psbt
+	P2PMessageTypeWithdrawProposal
+	// P2PMessageTypeWithdrawSign returns the psbt signed by a validator
+	P2PMessageTypeWithdrawSign
 )
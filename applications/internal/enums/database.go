@@ -43,6 +43,29 @@ const (
 	DepositStatusPending
 	DepositStatusValid
 	DepositStatusInvalid
+	// DepositStatusReview flags a deposit orphaned by a reorg after its message was delivered
+	DepositStatusReview
+	// DepositStatusUnconfirmed is a deposit seen in the mempool, not indexed in a block yet
+	DepositStatusUnconfirmed
+	// DepositStatusReplaced is an unconfirmed deposit dropped from the mempool, replaced or double spent
+	DepositStatusReplaced
+	// DepositStatusRefunding is an invalid deposit queued to be paid back to its sender
+	DepositStatusRefunding
+	// DepositStatusRefunded is a deposit whose refund tx is confirmed
+	DepositStatusRefunded
+)
+
+type WithdrawStatus int64
+
+const (
+	WithdrawStatusUnknown WithdrawStatus = iota
+	// WithdrawStatusSigning is a withdrawal psbt collecting validator signatures
+	WithdrawStatusSigning
+	// WithdrawStatusSigned is a withdrawal finalized with threshold signatures, not broadcast yet
+	WithdrawStatusSigned
+	WithdrawStatusBroadcast
+	// WithdrawStatusConfirmed is a withdrawal indexed after SafeBlockNumber confirmations
+	WithdrawStatusConfirmed
 )
 
 type SignatureStatus int64
@@ -54,3 +77,18 @@ const (
 	SignatureStatusFailed
 	SignatureStatusInvalid
 )
+
+type Finality string
+
+const (
+	FinalityConfirmations Finality = "confirmations"
+	FinalitySafe          Finality = "safe"
+	FinalityFinalized     Finality = "finalized"
+)
+
+type BtcSource string
+
+const (
+	BtcSourceRpc     BtcSource = "rpc"
+	BtcSourceEsplora BtcSource = "esplora"
+)
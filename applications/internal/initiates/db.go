@@ -3,15 +3,41 @@ package initiates
 import (
 	"bsquared.network/message-sharing-applications/internal/config"
 	"fmt"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+const (
+	DriverMysql    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSqlite   = "sqlite"
+)
+
 func InitDB(database config.Database) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", database.UserName, database.Password, database.Host, database.Port, database.DbName)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+	var dialector gorm.Dialector
+	switch database.Driver {
+	case "", DriverMysql:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", database.UserName, database.Password, database.Host, database.Port, database.DbName)
+		dialector = mysql.Open(dsn)
+	case DriverPostgres:
+		sslMode := database.SslMode
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s", database.Host, database.UserName, database.Password, database.DbName, database.Port, sslMode)
+		dialector = postgres.Open(dsn)
+	case DriverSqlite:
+		dialector = sqlite.Open(fmt.Sprintf("%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", database.DbName))
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", database.Driver)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.LogLevel(database.LogLevel)),
+		// translate driver errors, e.g. duplicate key to gorm.ErrDuplicatedKey
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, err
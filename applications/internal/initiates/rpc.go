@@ -1,28 +1,58 @@
 package initiates
 
 import (
-	"github.com/btcsuite/btcd/rpcclient"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"bsquared.network/message-sharing-applications/internal/utils/withdraw"
+	"time"
 )
 
-func InitEthereumRpc(rpcUrl string) (*ethclient.Client, error) {
-	rpc, err := ethclient.Dial(rpcUrl)
+// default interval the endpoint heads are polled at when the chain has no block interval
+const defaultWatchInterval = 10 * time.Second
+
+func InitEthereumRpc(chain config.Blockchain, logger *log.Logger) (*rpcpool.EthClient, error) {
+	rpc, err := rpcpool.NewEthClient(chain.Name, chain.Endpoints(), logger)
 	if err != nil {
 		return nil, err
 	}
+	rpc.Watch(watchInterval(chain))
 	return rpc, nil
 }
 
-func InitBitcoinRpc(rpcUrl string, btcUser string, btcPass string, disableTLS bool) (*rpcclient.Client, error) {
-	rpc, err := rpcclient.New(&rpcclient.ConnConfig{
-		Host:         rpcUrl,
-		User:         btcUser,
-		Pass:         btcPass,
-		HTTPPostMode: true,       // Bitcoin core only supports HTTP POST mode
-		DisableTLS:   disableTLS, // Bitcoin core does not provide TLS by default
-	}, nil)
+func InitBitcoinRpc(chain config.Blockchain, logger *log.Logger) (rpcpool.BtcSource, error) {
+	var (
+		rpc rpcpool.BtcSource
+		err error
+	)
+	if chain.BtcSource == enums.BtcSourceEsplora {
+		rpc, err = rpcpool.NewEsploraClient(chain.Name, chain.Endpoints(), logger)
+	} else {
+		rpc, err = rpcpool.NewBtcClient(chain.Name, chain.Endpoints(), chain.BtcUser, chain.BtcPass, chain.DisableTLS, logger)
+	}
 	if err != nil {
 		return nil, err
 	}
+	rpc.Watch(watchInterval(chain))
 	return rpc, nil
 }
+
+func watchInterval(chain config.Blockchain) time.Duration {
+	if chain.BlockInterval <= 0 {
+		return defaultWatchInterval
+	}
+	return time.Millisecond * time.Duration(chain.BlockInterval) * 5
+}
+
+// InitWallet returns the withdrawal wallet of a utxo chain paying out messages, nil otherwise.
+func InitWallet(chain config.Blockchain, logger *log.Logger) (*withdraw.Wallet, error) {
+	if !chain.Withdrawals() {
+		return nil, nil
+	}
+	rpc, err := InitBitcoinRpc(chain, logger)
+	if err != nil {
+		return nil, err
+	}
+	return withdraw.NewWallet(chain, rpc)
+}
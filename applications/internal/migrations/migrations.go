@@ -0,0 +1,236 @@
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql
+var files embed.FS
+
+const versionTable = "schema_migrations"
+
+// Migration is one versioned schema change, loaded from sql/{dialect}/{version}_{name}.{up|down}.sql
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration is a row of the migration-version table
+type SchemaMigration struct {
+	Version   int64     `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `json:"name" gorm:"type:varchar(128);not null;default:''"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+func (SchemaMigration) TableName() string {
+	return versionTable
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Load returns the migrations of the dialect ordered by version
+func Load(dialect string) ([]Migration, error) {
+	dir := path.Join("sql", dialect)
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported migration dialect: %s", dialect)
+	}
+	migrations := make(map[int64]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		if strings.HasSuffix(name, ".up.sql") {
+			direction = "up"
+		} else if strings.HasSuffix(name, ".down.sql") {
+			direction = "down"
+		} else {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSuffix(name, "."+direction+".sql"), "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		version, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", name)
+		}
+		content, err := fs.ReadFile(files, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[1]}
+			migrations[version] = migration
+		}
+		if migration.Name != parts[1] {
+			return nil, fmt.Errorf("migration %d has different names: %s, %s", version, migration.Name, parts[1])
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	list := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s misses up or down script", migration.Version, migration.Name)
+		}
+		list = append(list, *migration)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+	return list, nil
+}
+
+// Up applies all pending migrations in version order
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Migration, 0)
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err = m.db.Transaction(func(tx *gorm.DB) error {
+			if err := exec(tx, migration.Up); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return list, errors.Wrapf(err, "migrate up %d_%s", migration.Version, migration.Name)
+		}
+		list = append(list, migration)
+	}
+	return list, nil
+}
+
+// Down reverts the latest steps applied migrations
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Migration, 0)
+	for i := len(m.migrations) - 1; i >= 0 && len(list) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err = m.db.Transaction(func(tx *gorm.DB) error {
+			if err := exec(tx, migration.Down); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return list, errors.Wrapf(err, "migrate down %d_%s", migration.Version, migration.Name)
+		}
+		list = append(list, migration)
+	}
+	return list, nil
+}
+
+// Status lists every known migration and whether it is applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if one, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = one.AppliedAt
+		}
+		list = append(list, status)
+	}
+	return list, nil
+}
+
+func (m *Migrator) applied() (map[int64]SchemaMigration, error) {
+	if err := m.db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+	var list []SchemaMigration
+	if err := m.db.Find(&list).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]SchemaMigration, len(list))
+	for _, one := range list {
+		applied[one.Version] = one
+	}
+	return applied, nil
+}
+
+// exec runs a script statement by statement, drivers do not accept multi statements by default
+func exec(tx *gorm.DB, script string) error {
+	for _, statement := range split(script) {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func split(script string) []string {
+	statements := make([]string, 0)
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		statements = append(statements, strings.TrimSpace(current.String()))
+	}
+	return statements
+}
@@ -0,0 +1,163 @@
+CREATE TABLE `messages` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `type` int NOT NULL COMMENT 'type',
+  `chain_id` bigint NOT NULL COMMENT 'chain id',
+  `from_chain_id` bigint NOT NULL COMMENT 'from_chain_id',
+  `from_sender` varchar(66) NOT NULL COMMENT 'from_sender',
+  `from_message_bridge` varchar(128) NOT NULL DEFAULT '' COMMENT 'from_message_bridge',
+  `from_id` varchar(128) NOT NULL COMMENT 'from_id',
+  `to_chain_id` bigint NOT NULL COMMENT 'to_chain_id',
+  `to_message_bridge` varchar(128) NOT NULL DEFAULT '' COMMENT 'to_message_bridge',
+  `to_contract_address` varchar(66) NOT NULL COMMENT 'to_contract_address',
+  `to_bytes` text NOT NULL COMMENT 'to_bytes',
+  `event_id` bigint NOT NULL COMMENT 'event_id',
+  `block_time` bigint NOT NULL COMMENT 'block_time',
+  `block_number` bigint NOT NULL COMMENT 'block_number',
+  `log_index` bigint NOT NULL COMMENT 'log_index',
+  `tx_hash` varchar(128) NOT NULL COMMENT 'tx_hash',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT 'status',
+  `signatures` json NOT NULL COMMENT 'signatures',
+  `signatures_count` int NOT NULL DEFAULT '0' COMMENT 'signatures count',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_tx_hash_log_index` (`tx_hash`,`log_index`),
+  KEY `idx_from_id` (`type`,`from_chain_id`,`from_id`),
+  KEY `idx_chain_status` (`chain_id`,`type`,`status`),
+  KEY `idx_to_chain_status` (`to_chain_id`,`type`,`status`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `message_signatures` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `message_id` bigint NOT NULL COMMENT 'message id',
+  `signer` varchar(66) NOT NULL COMMENT 'signer address',
+  `signature` varchar(256) NOT NULL COMMENT 'signature',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_message_id_signer` (`message_id`,`signer`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `signatures` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `chain_id` bigint NOT NULL COMMENT 'chain id',
+  `refer_id` varchar(128) NOT NULL COMMENT 'refer id',
+  `nonce` bigint NOT NULL COMMENT 'nonce',
+  `type` bigint NOT NULL COMMENT 'type',
+  `data` text NOT NULL COMMENT 'data',
+  `value` decimal(64,18) NOT NULL DEFAULT '0.000000000000000000' COMMENT 'value',
+  `address` varchar(42) NOT NULL COMMENT 'sender address',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT 'status',
+  `event_id` bigint NOT NULL COMMENT 'event_id',
+  `block_time` bigint NOT NULL COMMENT 'block_time',
+  `block_number` bigint NOT NULL COMMENT 'block_number',
+  `log_index` bigint NOT NULL COMMENT 'log_index',
+  `tx_hash` varchar(66) NOT NULL COMMENT 'tx hash',
+  `signature` text NOT NULL COMMENT 'signature',
+  PRIMARY KEY (`id`),
+  KEY `idx_chain_status` (`chain_id`,`status`),
+  KEY `idx_chain_refer_id` (`chain_id`,`refer_id`),
+  KEY `idx_address_nonce` (`address`,`nonce`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `sync_events` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `sync_block_id` bigint NOT NULL DEFAULT '0' COMMENT 'sync block id',
+  `chain_id` bigint NOT NULL COMMENT 'chain id',
+  `block_time` bigint NOT NULL COMMENT 'block time',
+  `block_number` bigint NOT NULL COMMENT 'block height',
+  `block_hash` varchar(66) NOT NULL COMMENT 'block hash',
+  `block_log_indexed` bigint NOT NULL COMMENT 'block log index',
+  `tx_index` bigint NOT NULL COMMENT 'tx index',
+  `tx_hash` varchar(66) NOT NULL COMMENT 'tx hash',
+  `event_name` varchar(32) NOT NULL COMMENT 'event name',
+  `event_hash` varchar(66) NOT NULL COMMENT 'event hash',
+  `contract_address` varchar(42) NOT NULL COMMENT 'contract address',
+  `data` json NOT NULL COMMENT 'data content',
+  `status` varchar(32) NOT NULL COMMENT 'status',
+  `retry_count` bigint NOT NULL DEFAULT '0' COMMENT 'retry count',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_chain_tx_hash_log_index` (`chain_id`,`tx_hash`,`block_log_indexed`),
+  KEY `idx_chain_status` (`chain_id`,`status`,`event_hash`),
+  KEY `idx_block_number` (`chain_id`,`block_number`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `sync_events_history` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `sync_block_id` bigint NOT NULL DEFAULT '0' COMMENT 'sync block id',
+  `chain_id` bigint NOT NULL COMMENT 'chain id',
+  `block_time` bigint NOT NULL COMMENT 'block time',
+  `block_number` bigint NOT NULL COMMENT 'block height',
+  `block_hash` varchar(66) NOT NULL COMMENT 'block hash',
+  `block_log_indexed` bigint NOT NULL COMMENT 'block log index',
+  `tx_index` bigint NOT NULL COMMENT 'tx index',
+  `tx_hash` varchar(66) NOT NULL COMMENT 'tx hash',
+  `event_name` varchar(32) NOT NULL COMMENT 'event name',
+  `event_hash` varchar(66) NOT NULL COMMENT 'event hash',
+  `contract_address` varchar(42) NOT NULL COMMENT 'contract address',
+  `data` json NOT NULL COMMENT 'data content',
+  `status` varchar(32) NOT NULL COMMENT 'status',
+  `retry_count` bigint NOT NULL DEFAULT '0' COMMENT 'retry count',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `sync_tasks` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `chain_type` tinyint NOT NULL DEFAULT '0' COMMENT 'chain type',
+  `chain_id` bigint NOT NULL COMMENT 'chain id',
+  `latest_block` bigint NOT NULL DEFAULT '0' COMMENT 'handled block height',
+  `latest_tx` bigint NOT NULL DEFAULT '0' COMMENT 'handled tx index',
+  `start_block` bigint NOT NULL DEFAULT '0' COMMENT 'start block',
+  `end_block` bigint NOT NULL DEFAULT '0' COMMENT 'end block, 0 is unbounded',
+  `handle_num` bigint NOT NULL DEFAULT '0' COMMENT 'blocks per round',
+  `contracts` text NOT NULL COMMENT 'contract addresses, split by ,',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT 'status',
+  PRIMARY KEY (`id`),
+  KEY `idx_chain_status` (`chain_type`,`chain_id`,`status`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `deposit_history` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `btc_block_number` bigint NOT NULL DEFAULT '0' COMMENT 'bitcoin block number',
+  `btc_tx_index` bigint NOT NULL DEFAULT '0' COMMENT 'bitcoin tx index',
+  `btc_tx_hash` varchar(64) NOT NULL DEFAULT '' COMMENT 'bitcoin tx hash',
+  `btc_tx_type` smallint NOT NULL DEFAULT '0' COMMENT 'bitcoin tx type',
+  `btc_froms` json DEFAULT NULL COMMENT 'bitcoin transfer, from may be multiple',
+  `btc_from` varchar(64) NOT NULL DEFAULT '' COMMENT 'bitcoin from address',
+  `btc_tos` json DEFAULT NULL COMMENT 'bitcoin transfer, to may be multiple',
+  `btc_to` varchar(64) NOT NULL DEFAULT '' COMMENT 'bitcoin to address',
+  `btc_from_aa_address` varchar(42) NOT NULL DEFAULT '' COMMENT 'from aa address',
+  `btc_from_evm_address` varchar(42) NOT NULL DEFAULT '' COMMENT 'from evm address',
+  `btc_value` bigint NOT NULL DEFAULT '0' COMMENT 'bitcoin transfer value',
+  `b2_tx_from` varchar(42) NOT NULL DEFAULT '' COMMENT 'b2 tx from address',
+  `b2_tx_hash` varchar(66) NOT NULL DEFAULT '' COMMENT 'b2 network tx hash',
+  `b2_tx_nonce` bigint NOT NULL DEFAULT '0' COMMENT 'b2 tx nonce',
+  `b2_tx_status` smallint NOT NULL DEFAULT '1' COMMENT 'b2 tx status',
+  `b2_tx_retry` smallint NOT NULL DEFAULT '0' COMMENT 'b2 tx retry',
+  `b2_eoa_tx_from` varchar(42) NOT NULL DEFAULT '' COMMENT 'b2 eoa tx from address',
+  `b2_eoa_tx_nonce` bigint NOT NULL DEFAULT '0' COMMENT 'b2 eoa tx nonce',
+  `b2_eoa_tx_hash` varchar(66) NOT NULL DEFAULT '' COMMENT 'b2 network eoa tx hash',
+  `b2_eoa_tx_status` smallint NOT NULL DEFAULT '1' COMMENT 'b2 eoa tx status',
+  `btc_block_time` datetime NOT NULL COMMENT 'bitcoin block time',
+  `callback_status` smallint NOT NULL DEFAULT '0' COMMENT 'callback status',
+  `listener_status` smallint NOT NULL DEFAULT '0' COMMENT 'listener status',
+  `b2_tx_check` smallint NOT NULL DEFAULT '1' COMMENT 'b2 tx check',
+  `status` smallint NOT NULL DEFAULT '1' COMMENT 'status',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_btc_tx_hash` (`btc_tx_hash`),
+  KEY `idx_btc_block_number` (`btc_block_number`),
+  KEY `idx_btc_from` (`btc_from`),
+  KEY `idx_btc_to` (`btc_to`),
+  KEY `idx_b2_tx_hash` (`b2_tx_hash`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,11 @@
+CREATE TABLE `sync_blocks` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `chain_id` bigint NOT NULL COMMENT 'chain id',
+  `block_number` bigint NOT NULL COMMENT 'block height',
+  `block_hash` varchar(66) NOT NULL COMMENT 'block hash',
+  `parent_hash` varchar(66) NOT NULL COMMENT 'parent block hash',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_chain_block_number` (`chain_id`,`block_number`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
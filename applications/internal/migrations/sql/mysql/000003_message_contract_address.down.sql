@@ -0,0 +1,3 @@
+ALTER TABLE `messages`
+  DROP KEY `idx_chain_contract`,
+  DROP COLUMN `contract_address`;
@@ -0,0 +1,3 @@
+ALTER TABLE `messages`
+  ADD COLUMN `contract_address` varchar(128) NOT NULL DEFAULT '' COMMENT 'contract address emitting the message' AFTER `chain_id`,
+  ADD KEY `idx_chain_contract` (`chain_id`,`contract_address`);
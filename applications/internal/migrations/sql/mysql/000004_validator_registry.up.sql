@@ -0,0 +1,35 @@
+CREATE TABLE `validator_sets` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `chain_id` bigint NOT NULL COMMENT 'chain id of the contract',
+  `contract_address` varchar(42) NOT NULL COMMENT 'message sharing contract',
+  `from_chain_id` bigint NOT NULL COMMENT 'validated chain id',
+  `account` varchar(42) NOT NULL COMMENT 'validator account',
+  `valid` tinyint(1) NOT NULL DEFAULT '0' COMMENT 'validator role valid',
+  `event_id` bigint NOT NULL COMMENT 'event_id',
+  `block_time` bigint NOT NULL COMMENT 'block_time',
+  `block_number` bigint NOT NULL COMMENT 'block_number',
+  `log_index` bigint NOT NULL COMMENT 'log_index',
+  `tx_hash` varchar(66) NOT NULL COMMENT 'tx_hash',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_contract_account` (`chain_id`,`contract_address`,`from_chain_id`,`account`),
+  KEY `idx_from_chain` (`from_chain_id`,`valid`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `chain_weights` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `chain_id` bigint NOT NULL COMMENT 'chain id of the contract',
+  `contract_address` varchar(42) NOT NULL COMMENT 'message sharing contract',
+  `from_chain_id` bigint NOT NULL COMMENT 'validated chain id',
+  `weight` bigint NOT NULL DEFAULT '0' COMMENT 'signature weight',
+  `event_id` bigint NOT NULL COMMENT 'event_id',
+  `block_time` bigint NOT NULL COMMENT 'block_time',
+  `block_number` bigint NOT NULL COMMENT 'block_number',
+  `log_index` bigint NOT NULL COMMENT 'log_index',
+  `tx_hash` varchar(66) NOT NULL COMMENT 'tx_hash',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_contract_from_chain` (`chain_id`,`contract_address`,`from_chain_id`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE `deposit_history`
+  DROP COLUMN `token_amount`,
+  DROP COLUMN `token_id`;
@@ -0,0 +1,3 @@
+ALTER TABLE `deposit_history`
+  ADD COLUMN `token_id` varchar(80) NOT NULL DEFAULT '' COMMENT 'brc-20 ticker, rune id or name, inscription id' AFTER `btc_tx_type`,
+  ADD COLUMN `token_amount` varchar(80) NOT NULL DEFAULT '' COMMENT 'token amount in base units' AFTER `token_id`;
@@ -0,0 +1,34 @@
+CREATE TABLE `withdraw_history` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `chain_id` bigint NOT NULL COMMENT 'bitcoin chain id',
+  `message_id` bigint NOT NULL COMMENT 'call message id',
+  `from_chain_id` bigint NOT NULL COMMENT 'from chain id',
+  `from_id` varchar(128) NOT NULL COMMENT 'from id',
+  `btc_to` varchar(128) NOT NULL COMMENT 'bitcoin recipient address',
+  `btc_value` bigint NOT NULL COMMENT 'withdrawn amount in satoshis, fee included',
+  `btc_fee` bigint NOT NULL COMMENT 'fee in satoshis',
+  `btc_inputs` text NOT NULL COMMENT 'spent outpoints',
+  `btc_tx_hash` varchar(64) NOT NULL COMMENT 'bitcoin tx hash',
+  `btc_tx` text NOT NULL COMMENT 'signed bitcoin tx',
+  `btc_block_number` bigint NOT NULL DEFAULT '0' COMMENT 'bitcoin block number',
+  `psbt` text NOT NULL COMMENT 'unsigned psbt',
+  `signatures_count` bigint NOT NULL DEFAULT '0' COMMENT 'signatures count',
+  `status` tinyint NOT NULL DEFAULT '0' COMMENT '1 signing, 2 signed, 3 broadcast, 4 confirmed',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_message_id` (`message_id`),
+  KEY `idx_chain_status` (`chain_id`,`status`),
+  KEY `idx_btc_tx_hash` (`btc_tx_hash`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `withdraw_signatures` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `withdraw_id` bigint NOT NULL COMMENT 'withdraw id',
+  `signer` varchar(66) NOT NULL COMMENT 'signer address',
+  `psbt` text NOT NULL COMMENT 'psbt signed by the signer',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_withdraw_id_signer` (`withdraw_id`,`signer`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
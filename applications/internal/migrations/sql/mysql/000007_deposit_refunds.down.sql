@@ -0,0 +1,3 @@
+ALTER TABLE `deposit_history`
+  DROP KEY `idx_refund_tx_hash`,
+  DROP COLUMN `refund_tx_hash`;
@@ -0,0 +1,3 @@
+ALTER TABLE `deposit_history`
+  ADD COLUMN `refund_tx_hash` varchar(64) NOT NULL DEFAULT '' COMMENT 'bitcoin refund tx hash' AFTER `b2_tx_check`,
+  ADD KEY `idx_refund_tx_hash` (`refund_tx_hash`);
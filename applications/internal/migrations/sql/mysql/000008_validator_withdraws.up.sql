@@ -0,0 +1,11 @@
+CREATE TABLE `validator_withdraws` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `chain_id` bigint NOT NULL COMMENT 'bitcoin chain id',
+  `from_chain_id` bigint NOT NULL COMMENT 'from chain id',
+  `from_id` varchar(128) NOT NULL COMMENT 'from id',
+  `btc_tx_hash` varchar(64) NOT NULL COMMENT 'bitcoin tx hash signed by the validator',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_chain_from` (`chain_id`,`from_chain_id`,`from_id`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE `deposit_history`
+  DROP KEY `idx_chain_status`,
+  DROP COLUMN `chain_id`;
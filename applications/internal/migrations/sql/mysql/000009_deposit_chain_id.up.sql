@@ -0,0 +1,3 @@
+ALTER TABLE `deposit_history`
+  ADD COLUMN `chain_id` bigint NOT NULL DEFAULT '0' COMMENT 'bitcoin chain id' AFTER `updated_at`,
+  ADD KEY `idx_chain_status` (`chain_id`,`status`);
@@ -0,0 +1,9 @@
+CREATE TABLE `sync_ranges` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `chain_id` bigint NOT NULL COMMENT 'chain id',
+  `size` bigint NOT NULL COMMENT 'filter logs block range size',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_chain_id` (`chain_id`)
+) ENGINE=InnoDB AUTO_INCREMENT=1000000 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
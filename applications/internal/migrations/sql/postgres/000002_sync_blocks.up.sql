@@ -0,0 +1,10 @@
+CREATE TABLE sync_blocks (
+  id bigint GENERATED BY DEFAULT AS IDENTITY (START WITH 1000000) PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  chain_id bigint NOT NULL,
+  block_number bigint NOT NULL,
+  block_hash varchar(66) NOT NULL,
+  parent_hash varchar(66) NOT NULL,
+  CONSTRAINT uk_sync_blocks_chain_block_number UNIQUE (chain_id, block_number)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_chain_contract;
+ALTER TABLE messages DROP COLUMN contract_address;
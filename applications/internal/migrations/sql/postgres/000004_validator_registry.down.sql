@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS chain_weights;
+DROP TABLE IF EXISTS validator_sets;
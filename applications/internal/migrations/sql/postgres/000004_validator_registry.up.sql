@@ -0,0 +1,33 @@
+CREATE TABLE validator_sets (
+  id bigint GENERATED BY DEFAULT AS IDENTITY (START WITH 1000000) PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  chain_id bigint NOT NULL,
+  contract_address varchar(42) NOT NULL,
+  from_chain_id bigint NOT NULL,
+  account varchar(42) NOT NULL,
+  valid boolean NOT NULL DEFAULT false,
+  event_id bigint NOT NULL,
+  block_time bigint NOT NULL,
+  block_number bigint NOT NULL,
+  log_index bigint NOT NULL,
+  tx_hash varchar(66) NOT NULL,
+  CONSTRAINT uk_validator_sets_contract_account UNIQUE (chain_id, contract_address, from_chain_id, account)
+);
+CREATE INDEX idx_validator_sets_from_chain ON validator_sets (from_chain_id, valid);
+
+CREATE TABLE chain_weights (
+  id bigint GENERATED BY DEFAULT AS IDENTITY (START WITH 1000000) PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  chain_id bigint NOT NULL,
+  contract_address varchar(42) NOT NULL,
+  from_chain_id bigint NOT NULL,
+  weight bigint NOT NULL DEFAULT 0,
+  event_id bigint NOT NULL,
+  block_time bigint NOT NULL,
+  block_number bigint NOT NULL,
+  log_index bigint NOT NULL,
+  tx_hash varchar(66) NOT NULL,
+  CONSTRAINT uk_chain_weights_contract_from_chain UNIQUE (chain_id, contract_address, from_chain_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE deposit_history DROP COLUMN token_amount;
+ALTER TABLE deposit_history DROP COLUMN token_id;
@@ -0,0 +1,2 @@
+DROP INDEX idx_deposit_history_refund_tx_hash;
+ALTER TABLE deposit_history DROP COLUMN refund_tx_hash;
@@ -0,0 +1,2 @@
+ALTER TABLE deposit_history ADD COLUMN refund_tx_hash varchar(64) NOT NULL DEFAULT '';
+CREATE INDEX idx_deposit_history_refund_tx_hash ON deposit_history (refund_tx_hash);
@@ -0,0 +1,10 @@
+CREATE TABLE validator_withdraws (
+  id bigint GENERATED BY DEFAULT AS IDENTITY (START WITH 1000000) PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  chain_id bigint NOT NULL,
+  from_chain_id bigint NOT NULL,
+  from_id varchar(128) NOT NULL,
+  btc_tx_hash varchar(64) NOT NULL,
+  CONSTRAINT uk_validator_withdraws_chain_from UNIQUE (chain_id, from_chain_id, from_id)
+);
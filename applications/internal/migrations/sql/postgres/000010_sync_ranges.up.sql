@@ -0,0 +1,8 @@
+CREATE TABLE sync_ranges (
+  id bigint GENERATED BY DEFAULT AS IDENTITY (START WITH 1000000) PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  chain_id bigint NOT NULL,
+  size bigint NOT NULL,
+  CONSTRAINT uk_sync_ranges_chain_id UNIQUE (chain_id)
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS deposit_history;
+DROP TABLE IF EXISTS sync_tasks;
+DROP TABLE IF EXISTS sync_events_history;
+DROP TABLE IF EXISTS sync_events;
+DROP TABLE IF EXISTS signatures;
+DROP TABLE IF EXISTS message_signatures;
+DROP TABLE IF EXISTS messages;
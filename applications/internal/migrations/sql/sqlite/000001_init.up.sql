@@ -0,0 +1,156 @@
+CREATE TABLE messages (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  type int NOT NULL,
+  chain_id bigint NOT NULL,
+  from_chain_id bigint NOT NULL,
+  from_sender varchar(66) NOT NULL,
+  from_message_bridge varchar(128) NOT NULL DEFAULT '',
+  from_id varchar(128) NOT NULL,
+  to_chain_id bigint NOT NULL,
+  to_message_bridge varchar(128) NOT NULL DEFAULT '',
+  to_contract_address varchar(66) NOT NULL,
+  to_bytes text NOT NULL,
+  event_id bigint NOT NULL,
+  block_time bigint NOT NULL,
+  block_number bigint NOT NULL,
+  log_index bigint NOT NULL,
+  tx_hash varchar(128) NOT NULL,
+  status smallint NOT NULL DEFAULT 0,
+  signatures text NOT NULL,
+  signatures_count int NOT NULL DEFAULT 0,
+  CONSTRAINT uk_messages_tx_hash_log_index UNIQUE (tx_hash, log_index)
+);
+CREATE INDEX idx_messages_from_id ON messages (type, from_chain_id, from_id);
+CREATE INDEX idx_messages_chain_status ON messages (chain_id, type, status);
+CREATE INDEX idx_messages_to_chain_status ON messages (to_chain_id, type, status);
+
+CREATE TABLE message_signatures (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  message_id bigint NOT NULL,
+  signer varchar(66) NOT NULL,
+  signature varchar(256) NOT NULL,
+  CONSTRAINT uk_message_signatures_message_id_signer UNIQUE (message_id, signer)
+);
+
+CREATE TABLE signatures (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  chain_id bigint NOT NULL,
+  refer_id varchar(128) NOT NULL,
+  nonce bigint NOT NULL,
+  type bigint NOT NULL,
+  data text NOT NULL,
+  value decimal(64,18) NOT NULL DEFAULT 0,
+  address varchar(42) NOT NULL,
+  status smallint NOT NULL DEFAULT 0,
+  event_id bigint NOT NULL,
+  block_time bigint NOT NULL,
+  block_number bigint NOT NULL,
+  log_index bigint NOT NULL,
+  tx_hash varchar(66) NOT NULL,
+  signature text NOT NULL
+);
+CREATE INDEX idx_signatures_chain_status ON signatures (chain_id, status);
+CREATE INDEX idx_signatures_chain_refer_id ON signatures (chain_id, refer_id);
+CREATE INDEX idx_signatures_address_nonce ON signatures (address, nonce);
+
+CREATE TABLE sync_events (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  sync_block_id bigint NOT NULL DEFAULT 0,
+  chain_id bigint NOT NULL,
+  block_time bigint NOT NULL,
+  block_number bigint NOT NULL,
+  block_hash varchar(66) NOT NULL,
+  block_log_indexed bigint NOT NULL,
+  tx_index bigint NOT NULL,
+  tx_hash varchar(66) NOT NULL,
+  event_name varchar(32) NOT NULL,
+  event_hash varchar(66) NOT NULL,
+  contract_address varchar(42) NOT NULL,
+  data text NOT NULL,
+  status varchar(32) NOT NULL,
+  retry_count bigint NOT NULL DEFAULT 0,
+  CONSTRAINT uk_sync_events_chain_tx_hash_log_index UNIQUE (chain_id, tx_hash, block_log_indexed)
+);
+CREATE INDEX idx_sync_events_chain_status ON sync_events (chain_id, status, event_hash);
+CREATE INDEX idx_sync_events_block_number ON sync_events (chain_id, block_number);
+
+CREATE TABLE sync_events_history (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  sync_block_id bigint NOT NULL DEFAULT 0,
+  chain_id bigint NOT NULL,
+  block_time bigint NOT NULL,
+  block_number bigint NOT NULL,
+  block_hash varchar(66) NOT NULL,
+  block_log_indexed bigint NOT NULL,
+  tx_index bigint NOT NULL,
+  tx_hash varchar(66) NOT NULL,
+  event_name varchar(32) NOT NULL,
+  event_hash varchar(66) NOT NULL,
+  contract_address varchar(42) NOT NULL,
+  data text NOT NULL,
+  status varchar(32) NOT NULL,
+  retry_count bigint NOT NULL DEFAULT 0
+);
+
+CREATE TABLE sync_tasks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  chain_type smallint NOT NULL DEFAULT 0,
+  chain_id bigint NOT NULL,
+  latest_block bigint NOT NULL DEFAULT 0,
+  latest_tx bigint NOT NULL DEFAULT 0,
+  start_block bigint NOT NULL DEFAULT 0,
+  end_block bigint NOT NULL DEFAULT 0,
+  handle_num bigint NOT NULL DEFAULT 0,
+  contracts text NOT NULL DEFAULT '',
+  status smallint NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_sync_tasks_chain_status ON sync_tasks (chain_type, chain_id, status);
+
+CREATE TABLE deposit_history (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  btc_block_number bigint NOT NULL DEFAULT 0,
+  btc_tx_index bigint NOT NULL DEFAULT 0,
+  btc_tx_hash varchar(64) NOT NULL DEFAULT '',
+  btc_tx_type smallint NOT NULL DEFAULT 0,
+  btc_froms text DEFAULT NULL,
+  btc_from varchar(64) NOT NULL DEFAULT '',
+  btc_tos text DEFAULT NULL,
+  btc_to varchar(64) NOT NULL DEFAULT '',
+  btc_from_aa_address varchar(42) NOT NULL DEFAULT '',
+  btc_from_evm_address varchar(42) NOT NULL DEFAULT '',
+  btc_value bigint NOT NULL DEFAULT 0,
+  b2_tx_from varchar(42) NOT NULL DEFAULT '',
+  b2_tx_hash varchar(66) NOT NULL DEFAULT '',
+  b2_tx_nonce bigint NOT NULL DEFAULT 0,
+  b2_tx_status smallint NOT NULL DEFAULT 1,
+  b2_tx_retry smallint NOT NULL DEFAULT 0,
+  b2_eoa_tx_from varchar(42) NOT NULL DEFAULT '',
+  b2_eoa_tx_nonce bigint NOT NULL DEFAULT 0,
+  b2_eoa_tx_hash varchar(66) NOT NULL DEFAULT '',
+  b2_eoa_tx_status smallint NOT NULL DEFAULT 1,
+  btc_block_time datetime NOT NULL,
+  callback_status smallint NOT NULL DEFAULT 0,
+  listener_status smallint NOT NULL DEFAULT 0,
+  b2_tx_check smallint NOT NULL DEFAULT 1,
+  status smallint NOT NULL DEFAULT 1,
+  CONSTRAINT uk_deposit_history_btc_tx_hash UNIQUE (btc_tx_hash)
+);
+CREATE INDEX idx_deposit_history_btc_block_number ON deposit_history (btc_block_number);
+CREATE INDEX idx_deposit_history_btc_from ON deposit_history (btc_from);
+CREATE INDEX idx_deposit_history_btc_to ON deposit_history (btc_to);
+CREATE INDEX idx_deposit_history_b2_tx_hash ON deposit_history (b2_tx_hash);
+CREATE INDEX idx_deposit_history_status ON deposit_history (status);
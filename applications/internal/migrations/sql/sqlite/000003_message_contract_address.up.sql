@@ -0,0 +1,2 @@
+ALTER TABLE messages ADD COLUMN contract_address varchar(128) NOT NULL DEFAULT '';
+CREATE INDEX idx_messages_chain_contract ON messages (chain_id, contract_address);
@@ -0,0 +1,2 @@
+ALTER TABLE deposit_history ADD COLUMN token_id varchar(80) NOT NULL DEFAULT '';
+ALTER TABLE deposit_history ADD COLUMN token_amount varchar(80) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP TABLE withdraw_signatures;
+DROP TABLE withdraw_history;
@@ -0,0 +1,32 @@
+CREATE TABLE withdraw_history (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  chain_id bigint NOT NULL,
+  message_id bigint NOT NULL,
+  from_chain_id bigint NOT NULL,
+  from_id varchar(128) NOT NULL,
+  btc_to varchar(128) NOT NULL,
+  btc_value bigint NOT NULL,
+  btc_fee bigint NOT NULL,
+  btc_inputs text NOT NULL,
+  btc_tx_hash varchar(64) NOT NULL,
+  btc_tx text NOT NULL,
+  btc_block_number bigint NOT NULL DEFAULT 0,
+  psbt text NOT NULL,
+  signatures_count bigint NOT NULL DEFAULT 0,
+  status smallint NOT NULL DEFAULT 0,
+  CONSTRAINT uk_withdraw_history_message_id UNIQUE (message_id)
+);
+CREATE INDEX idx_withdraw_history_chain_status ON withdraw_history (chain_id, status);
+CREATE INDEX idx_withdraw_history_btc_tx_hash ON withdraw_history (btc_tx_hash);
+
+CREATE TABLE withdraw_signatures (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  withdraw_id bigint NOT NULL,
+  signer varchar(66) NOT NULL,
+  psbt text NOT NULL,
+  CONSTRAINT uk_withdraw_signatures_withdraw_id_signer UNIQUE (withdraw_id, signer)
+);
@@ -0,0 +1 @@
+DROP TABLE validator_withdraws;
@@ -0,0 +1,2 @@
+DROP INDEX idx_deposit_history_chain_status;
+ALTER TABLE deposit_history DROP COLUMN chain_id;
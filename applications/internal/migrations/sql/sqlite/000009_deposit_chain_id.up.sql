@@ -0,0 +1,2 @@
+ALTER TABLE deposit_history ADD COLUMN chain_id bigint NOT NULL DEFAULT 0;
+CREATE INDEX idx_deposit_history_chain_status ON deposit_history (chain_id, status);
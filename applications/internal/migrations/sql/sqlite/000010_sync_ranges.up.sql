@@ -0,0 +1,8 @@
+CREATE TABLE sync_ranges (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  chain_id bigint NOT NULL,
+  size bigint NOT NULL,
+  CONSTRAINT uk_sync_ranges_chain_id UNIQUE (chain_id)
+);
@@ -64,13 +64,16 @@ const (
 
 type Deposit struct {
 	Base
+	ChainId           int64               `json:"chain_id" gorm:"index:idx_chain_status;comment:bitcoin chain id"`
 	BtcBlockNumber    int64               `json:"btc_block_number" gorm:"index;comment:bitcoin block number"`
 	BtcTxIndex        int64               `json:"btc_tx_index" gorm:"comment:bitcoin tx index"`
 	BtcTxHash         string              `json:"btc_tx_hash" gorm:"type:varchar(64);not null;default:'';uniqueIndex;comment:bitcoin tx hash"`
 	BtcTxType         int                 `json:"btc_tx_type" gorm:"type:SMALLINT;default:0;comment:btc tx type"`
-	BtcFroms          string              `json:"btc_froms" gorm:"type:jsonb;comment:bitcoin transfer, from may be multiple"`
+	TokenId           string              `json:"token_id" gorm:"type:varchar(80);not null;default:'';comment:brc-20 ticker, rune id or name, inscription id"`
+	TokenAmount       string              `json:"token_amount" gorm:"type:varchar(80);not null;default:'';comment:token amount in base units"`
+	BtcFroms          string              `json:"btc_froms" gorm:"type:json;comment:bitcoin transfer, from may be multiple"`
 	BtcFrom           string              `json:"btc_from" gorm:"type:varchar(64);not null;default:'';index"`
-	BtcTos            string              `json:"btc_tos" gorm:"type:jsonb;comment:bitcoin transfer, to may be multiple"`
+	BtcTos            string              `json:"btc_tos" gorm:"type:json;comment:bitcoin transfer, to may be multiple"`
 	BtcTo             string              `json:"btc_to" gorm:"type:varchar(64);not null;default:'';index"`
 	BtcFromAAAddress  string              `json:"btc_from_aa_address" gorm:"type:varchar(42);default:'';comment:from aa address"`
 	BtcFromEvmAddress string              `json:"btc_from_evm_address" gorm:"type:varchar(42);default:'';comment:from evm address"`
@@ -88,14 +91,18 @@ type Deposit struct {
 	CallbackStatus    int                 `json:"callback_status" gorm:"type:SMALLINT;default:0"`
 	ListenerStatus    int                 `json:"listener_status" gorm:"type:SMALLINT;default:0"`
 	B2TxCheck         int                 `json:"b2_tx_check" gorm:"type:SMALLINT;default:1"`
-	Status            enums.DepositStatus `json:"status" gorm:"type:SMALLINT;default:1"`
+	RefundTxHash      string              `json:"refund_tx_hash" gorm:"type:varchar(64);not null;default:'';index;comment:bitcoin refund tx hash"`
+	Status            enums.DepositStatus `json:"status" gorm:"type:SMALLINT;default:1;index:idx_chain_status"`
 }
 
 type DepositColumns struct {
+	ChainId           string
 	BtcBlockNumber    string
 	BtcTxIndex        string
 	BtcTxHash         string
 	BtcTxType         string
+	TokenId           string
+	TokenAmount       string
 	BtcFroms          string
 	BtcFrom           string
 	BtcTos            string
@@ -116,6 +123,7 @@ type DepositColumns struct {
 	CallbackStatus    string
 	ListenerStatus    string
 	B2TxCheck         string
+	RefundTxHash      string
 }
 
 func (Deposit) TableName() string {
@@ -124,10 +132,13 @@ func (Deposit) TableName() string {
 
 func (Deposit) Column() DepositColumns {
 	return DepositColumns{
+		ChainId:           "chain_id",
 		BtcBlockNumber:    "btc_block_number",
 		BtcTxIndex:        "btc_tx_index",
 		BtcTxHash:         "btc_tx_hash",
 		BtcTxType:         "btc_tx_type",
+		TokenId:           "token_id",
+		TokenAmount:       "token_amount",
 		BtcFroms:          "btc_froms",
 		BtcFrom:           "btc_from",
 		BtcTos:            "btc_tos",
@@ -148,5 +159,6 @@ func (Deposit) Column() DepositColumns {
 		CallbackStatus:    "callback_status",
 		ListenerStatus:    "listener_status",
 		B2TxCheck:         "b2_tx_check",
+		RefundTxHash:      "refund_tx_hash",
 	}
 }
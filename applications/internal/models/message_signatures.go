@@ -8,5 +8,5 @@ type MessageSignature struct {
 }
 
 func (MessageSignature) TableName() string {
-	return "`message_signatures`"
+	return "message_signatures"
 }
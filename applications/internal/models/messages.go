@@ -5,6 +5,7 @@ import "bsquared.network/message-sharing-applications/internal/enums"
 type Message struct {
 	Base
 	ChainId           int64               `json:"chain_id"`
+	ContractAddress   string              `json:"contract_address"`
 	Type              enums.MessageType   `json:"type"`
 	FromChainId       int64               `json:"from_chain_id"`
 	FromMessageBridge string              `json:"from_message_bridge"`
@@ -21,5 +22,19 @@ type Message struct {
 }
 
 func (Message) TableName() string {
-	return "`messages`"
+	return "messages"
+}
+
+// Undelivered reports whether the message has not been broadcast to or
+// confirmed on the target chain yet.
+func (m Message) Undelivered() bool {
+	switch m.Type {
+	case enums.MessageTypeCall:
+		return m.Status == enums.MessageStatusValidating ||
+			m.Status == enums.MessageStatusPending ||
+			m.Status == enums.MessageStatusInvalid
+	case enums.MessageTypeSend:
+		return m.Status == enums.MessageStatusPending
+	}
+	return false
 }
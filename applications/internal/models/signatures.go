@@ -20,5 +20,5 @@ type Signature struct {
 }
 
 func (Signature) TableName() string {
-	return "`signatures`"
+	return "signatures"
 }
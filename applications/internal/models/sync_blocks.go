@@ -0,0 +1,13 @@
+package models
+
+type SyncBlock struct {
+	Base
+	ChainId     int64  `json:"chain_id"`
+	BlockNumber int64  `json:"block_number"`
+	BlockHash   string `json:"block_hash"`
+	ParentHash  string `json:"parent_hash"`
+}
+
+func (SyncBlock) TableName() string {
+	return "sync_blocks"
+}
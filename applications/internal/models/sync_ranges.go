@@ -0,0 +1,13 @@
+package models
+
+// SyncRange is the FilterLogs block range size the rpc of an evm chain
+// accepts, kept across restarts of the listener.
+type SyncRange struct {
+	Base
+	ChainId int64 `json:"chain_id"`
+	Size    int64 `json:"size"`
+}
+
+func (SyncRange) TableName() string {
+	return "sync_ranges"
+}
@@ -16,5 +16,5 @@ type SyncTask struct {
 }
 
 func (SyncTask) TableName() string {
-	return "`sync_tasks`"
+	return "sync_tasks"
 }
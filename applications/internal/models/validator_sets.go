@@ -0,0 +1,32 @@
+package models
+
+// ValidatorSet is a validator role granted or revoked on a message sharing
+// contract for the messages from FromChainId.
+type ValidatorSet struct {
+	Base
+	ChainId         int64  `json:"chain_id"`
+	ContractAddress string `json:"contract_address"`
+	FromChainId     int64  `json:"from_chain_id"`
+	Account         string `json:"account"`
+	Valid           bool   `json:"valid"`
+	Blockchain
+}
+
+func (ValidatorSet) TableName() string {
+	return "validator_sets"
+}
+
+// ChainWeight is the signature weight a message sharing contract requires
+// for the messages from FromChainId.
+type ChainWeight struct {
+	Base
+	ChainId         int64  `json:"chain_id"`
+	ContractAddress string `json:"contract_address"`
+	FromChainId     int64  `json:"from_chain_id"`
+	Weight          int64  `json:"weight"`
+	Blockchain
+}
+
+func (ChainWeight) TableName() string {
+	return "chain_weights"
+}
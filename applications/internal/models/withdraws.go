@@ -0,0 +1,55 @@
+package models
+
+import "bsquared.network/message-sharing-applications/internal/enums"
+
+// Withdraw is the bitcoin tx paying out a call message targeting a utxo chain,
+// spent from the multisig listen address of the chain.
+type Withdraw struct {
+	Base
+	ChainId     int64  `json:"chain_id"`
+	MessageId   int64  `json:"message_id"`
+	FromChainId int64  `json:"from_chain_id"`
+	FromId      string `json:"from_id"`
+	BtcTo       string `json:"btc_to"`
+	// BtcValue is the amount of the message, the fee is paid out of it
+	BtcValue int64 `json:"btc_value"`
+	BtcFee   int64 `json:"btc_fee"`
+	// BtcInputs are the outpoints spent, reserved until the withdrawal is confirmed
+	BtcInputs       string               `json:"btc_inputs"`
+	BtcTxHash       string               `json:"btc_tx_hash"`
+	BtcTx           string               `json:"btc_tx"`
+	BtcBlockNumber  int64                `json:"btc_block_number"`
+	Psbt            string               `json:"psbt"`
+	SignaturesCount int64                `json:"signatures_count"`
+	Status          enums.WithdrawStatus `json:"status"`
+}
+
+func (Withdraw) TableName() string {
+	return "withdraw_history"
+}
+
+// WithdrawSignature is the withdrawal psbt signed by a validator.
+type WithdrawSignature struct {
+	Base
+	WithdrawId int64  `json:"withdraw_id"`
+	Signer     string `json:"signer"`
+	Psbt       string `json:"psbt"`
+}
+
+func (WithdrawSignature) TableName() string {
+	return "withdraw_signatures"
+}
+
+// ValidatorWithdraw is the withdrawal tx a validator signed for a message, it
+// never signs another tx paying out the message.
+type ValidatorWithdraw struct {
+	Base
+	ChainId     int64  `json:"chain_id"`
+	FromChainId int64  `json:"from_chain_id"`
+	FromId      string `json:"from_id"`
+	BtcTxHash   string `json:"btc_tx_hash"`
+}
+
+func (ValidatorWithdraw) TableName() string {
+	return "validator_withdraws"
+}
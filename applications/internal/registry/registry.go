@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"bsquared.network/message-sharing-applications/internal/models"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// Registry reads the validator sets and signature weights indexed from the
+// message sharing contracts. A contract without indexed records falls back to
+// the static values of the config.
+type Registry struct {
+	db *gorm.DB
+}
+
+func NewRegistry(db *gorm.DB) *Registry {
+	return &Registry{
+		db: db,
+	}
+}
+
+// Weight returns the signature weight the contract on chainId requires for
+// the messages from fromChainId.
+func (r *Registry) Weight(chainId int64, contract string, fromChainId int64, fallback int64) (int64, error) {
+	var weights []models.ChainWeight
+	err := r.db.Where("chain_id=? AND contract_address=? AND from_chain_id=?",
+		chainId, strings.ToLower(contract), fromChainId).Limit(1).Find(&weights).Error
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if len(weights) == 0 {
+		return fallback, nil
+	}
+	return weights[0].Weight, nil
+}
+
+// Validators returns the valid validator accounts of the contract on chainId
+// for the messages from fromChainId.
+func (r *Registry) Validators(chainId int64, contract string, fromChainId int64, fallback []string) ([]string, error) {
+	var sets []models.ValidatorSet
+	err := r.db.Where("chain_id=? AND contract_address=? AND from_chain_id=?",
+		chainId, strings.ToLower(contract), fromChainId).Find(&sets).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(sets) == 0 {
+		return fallback, nil
+	}
+	return validAccounts(sets), nil
+}
+
+// SourceValidators returns the accounts valid on any contract for the
+// messages from fromChainId.
+func (r *Registry) SourceValidators(fromChainId int64, fallback []string) ([]string, error) {
+	var sets []models.ValidatorSet
+	err := r.db.Where("from_chain_id=?", fromChainId).Find(&sets).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(sets) == 0 {
+		return fallback, nil
+	}
+	return validAccounts(sets), nil
+}
+
+// Contains reports whether account is in the validator accounts.
+func Contains(validators []string, account string) bool {
+	for _, validator := range validators {
+		if common.HexToAddress(validator) == common.HexToAddress(account) {
+			return true
+		}
+	}
+	return false
+}
+
+func validAccounts(sets []models.ValidatorSet) []string {
+	accounts := make([]string, 0, len(sets))
+	for _, set := range sets {
+		if set.Valid && !Contains(accounts, set.Account) {
+			accounts = append(accounts, set.Account)
+		}
+	}
+	return accounts
+}
@@ -0,0 +1,262 @@
+package rpcpool
+
+import (
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// blocks a bitcoin endpoint may trail the best head before it is deprioritized
+const btcMaxLag = 1
+
+// prevoutBlock is a block of getblock verbosity 3, the inputs of its txs carry
+// the outputs they spend so no -txindex is needed to resolve them.
+type prevoutBlock struct {
+	Tx []struct {
+		Hex string `json:"hex"`
+		Vin []struct {
+			Txid    string `json:"txid"`
+			Vout    uint32 `json:"vout"`
+			Prevout *struct {
+				ScriptPubKey struct {
+					Hex string `json:"hex"`
+				} `json:"scriptPubKey"`
+			} `json:"prevout"`
+		} `json:"vin"`
+	} `json:"tx"`
+}
+
+// BtcClient is a bitcoin core client over several endpoints of a chain, every
+// call is served by the healthiest endpoint and fails over to the next one
+// on transport errors.
+type BtcClient struct {
+	pool    *pool
+	clients []*rpcclient.Client
+}
+
+func NewBtcClient(name string, urls []string, btcUser string, btcPass string, disableTLS bool, logger *log.Logger) (*BtcClient, error) {
+	pool, err := newPool(name, urls, btcMaxLag, logger)
+	if err != nil {
+		return nil, err
+	}
+	clients := make([]*rpcclient.Client, 0, len(urls))
+	for _, url := range urls {
+		client, err := rpcclient.New(&rpcclient.ConnConfig{
+			Host:         url,
+			User:         btcUser,
+			Pass:         btcPass,
+			HTTPPostMode: true,       // Bitcoin core only supports HTTP POST mode
+			DisableTLS:   disableTLS, // Bitcoin core does not provide TLS by default
+		}, nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		clients = append(clients, client)
+	}
+	return &BtcClient{
+		pool:    pool,
+		clients: clients,
+	}, nil
+}
+
+// Watch polls the head of every endpoint to track how far it trails the chain.
+func (c *BtcClient) Watch(interval time.Duration) {
+	go c.pool.watch(interval, func(i int) (int64, error) {
+		return c.clients[i].GetBlockCount()
+	})
+}
+
+// Stats returns the health of the endpoints.
+func (c *BtcClient) Stats() []Stats {
+	return c.pool.stats()
+}
+
+func btcCall[T any](c *BtcClient, method string, call func(client *rpcclient.Client) (T, error)) (T, error) {
+	var result T
+	err := c.pool.do(method, func(i int) error {
+		var err error
+		result, err = call(c.clients[i])
+		return err
+	}, btcEndpointError)
+	return result, err
+}
+
+// btcEndpointError reports whether err is caused by the endpoint, json-rpc
+// errors are answers of a working node and are returned to the caller.
+func btcEndpointError(err error) bool {
+	var rpcErr *btcjson.RPCError
+	return !errors.As(err, &rpcErr)
+}
+
+func (c *BtcClient) GetBlockCount() (int64, error) {
+	return btcCall(c, "getblockcount", func(client *rpcclient.Client) (int64, error) {
+		return client.GetBlockCount()
+	})
+}
+
+func (c *BtcClient) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	return btcCall(c, "getblockhash", func(client *rpcclient.Client) (*chainhash.Hash, error) {
+		return client.GetBlockHash(height)
+	})
+}
+
+func (c *BtcClient) GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	return btcCall(c, "getblock", func(client *rpcclient.Client) (*wire.MsgBlock, error) {
+		return client.GetBlock(hash)
+	})
+}
+
+func (c *BtcClient) GetRawTransaction(hash *chainhash.Hash) (*btcutil.Tx, error) {
+	tx, err := btcCall(c, "getrawtransaction", func(client *rpcclient.Client) (*btcutil.Tx, error) {
+		return client.GetRawTransaction(hash)
+	})
+	var rpcErr *btcjson.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCNoTxInfo {
+		return nil, errors.Wrap(ErrTxNotFound, rpcErr.Message)
+	}
+	return tx, err
+}
+
+// GetBlockPrevouts returns the block with the prevouts of its inputs, bitcoin
+// core 23.0 or later.
+func (c *BtcClient) GetBlockPrevouts(hash *chainhash.Hash) (*PrevoutBlock, error) {
+	return btcCall(c, "getblock", func(client *rpcclient.Client) (*PrevoutBlock, error) {
+		result, err := client.RawRequest("getblock", []json.RawMessage{
+			json.RawMessage(`"` + hash.String() + `"`),
+			json.RawMessage("3"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		var block prevoutBlock
+		err = json.Unmarshal(result, &block)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		prevouts := &PrevoutBlock{
+			Txs:      make([]*wire.MsgTx, 0, len(block.Tx)),
+			Prevouts: make(map[wire.OutPoint][]byte),
+		}
+		for _, tx := range block.Tx {
+			for _, vin := range tx.Vin {
+				if vin.Prevout == nil {
+					// coinbase
+					continue
+				}
+				txHash, err := chainhash.NewHashFromStr(vin.Txid)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				pkScript, err := hex.DecodeString(vin.Prevout.ScriptPubKey.Hex)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				prevouts.Prevouts[wire.OutPoint{Hash: *txHash, Index: vin.Vout}] = pkScript
+			}
+			raw, err := hex.DecodeString(tx.Hex)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			var msgTx wire.MsgTx
+			err = msgTx.Deserialize(bytes.NewReader(raw))
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			prevouts.Txs = append(prevouts.Txs, &msgTx)
+		}
+		return prevouts, nil
+	})
+}
+
+// GetTxOutScript looks up the utxo set and the mempool, a spent output is unknown.
+func (c *BtcClient) GetTxOutScript(hash *chainhash.Hash, index uint32) ([]byte, error) {
+	txOut, err := btcCall(c, "gettxout", func(client *rpcclient.Client) (*btcjson.GetTxOutResult, error) {
+		return client.GetTxOut(hash, index, true)
+	})
+	if err != nil || txOut == nil {
+		return nil, err
+	}
+	pkScript, err := hex.DecodeString(txOut.ScriptPubKey.Hex)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return pkScript, nil
+}
+
+func (c *BtcClient) GetRawMempool() ([]*chainhash.Hash, error) {
+	return btcCall(c, "getrawmempool", func(client *rpcclient.Client) ([]*chainhash.Hash, error) {
+		return client.GetRawMempool()
+	})
+}
+
+// ListUnspent scans the utxo set for the outputs of the address, no wallet is
+// needed but bitcoin core serves one scan at a time.
+func (c *BtcClient) ListUnspent(address string) ([]Utxo, error) {
+	return btcCall(c, "scantxoutset", func(client *rpcclient.Client) ([]Utxo, error) {
+		descriptors, err := json.Marshal([]string{"addr(" + address + ")"})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		result, err := client.RawRequest("scantxoutset", []json.RawMessage{
+			json.RawMessage(`"start"`),
+			descriptors,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var scan struct {
+			Unspents []struct {
+				Txid   string  `json:"txid"`
+				Vout   uint32  `json:"vout"`
+				Amount float64 `json:"amount"`
+			} `json:"unspents"`
+		}
+		err = json.Unmarshal(result, &scan)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		utxos := make([]Utxo, 0, len(scan.Unspents))
+		for _, unspent := range scan.Unspents {
+			txHash, err := chainhash.NewHashFromStr(unspent.Txid)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			amount, err := btcutil.NewAmount(unspent.Amount)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			utxos = append(utxos, Utxo{
+				OutPoint: wire.OutPoint{Hash: *txHash, Index: unspent.Vout},
+				Value:    int64(amount),
+			})
+		}
+		return utxos, nil
+	})
+}
+
+func (c *BtcClient) SendRawTransaction(tx *wire.MsgTx) (*chainhash.Hash, error) {
+	hash, err := btcCall(c, "sendrawtransaction", func(client *rpcclient.Client) (*chainhash.Hash, error) {
+		return client.SendRawTransaction(tx, false)
+	})
+	var rpcErr *btcjson.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCTxAlreadyInChain {
+		txHash := tx.TxHash()
+		return &txHash, nil
+	}
+	return hash, err
+}
+
+// RawRequest sends a request the typed methods do not cover.
+func (c *BtcClient) RawRequest(method string, params []json.RawMessage) (json.RawMessage, error) {
+	return btcCall(c, method, func(client *rpcclient.Client) (json.RawMessage, error) {
+		return client.RawRequest(method, params)
+	})
+}
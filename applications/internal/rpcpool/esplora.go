@@ -0,0 +1,291 @@
+package rpcpool
+
+import (
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const esploraTimeout = 30 * time.Second
+
+// esploraError is a response of an esplora endpoint other than 200.
+type esploraError struct {
+	status int
+	body   string
+}
+
+func (e *esploraError) Error() string {
+	return fmt.Sprintf("esplora status %d: %s", e.status, e.body)
+}
+
+// EsploraClient is a bitcoin source over several esplora rest api endpoints,
+// e.g. https://blockstream.info/testnet/api, every call is served by the
+// healthiest endpoint and fails over to the next one on transport errors.
+type EsploraClient struct {
+	pool   *pool
+	client *http.Client
+}
+
+func NewEsploraClient(name string, urls []string, logger *log.Logger) (*EsploraClient, error) {
+	pool, err := newPool(name, urls, btcMaxLag, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &EsploraClient{
+		pool:   pool,
+		client: &http.Client{Timeout: esploraTimeout},
+	}, nil
+}
+
+// Watch polls the head of every endpoint to track how far it trails the chain.
+func (c *EsploraClient) Watch(interval time.Duration) {
+	go c.pool.watch(interval, func(i int) (int64, error) {
+		body, err := c.request(i, "/blocks/tip/height")
+		if err != nil {
+			return 0, err
+		}
+		return parseHeight(body)
+	})
+}
+
+// Stats returns the health of the endpoints.
+func (c *EsploraClient) Stats() []Stats {
+	return c.pool.stats()
+}
+
+func (c *EsploraClient) request(i int, path string) ([]byte, error) {
+	return c.do(i, http.MethodGet, path, nil)
+}
+
+func (c *EsploraClient) do(i int, method string, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.pool.endpoints[i].url, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.WithStack(&esploraError{status: resp.StatusCode, body: strings.TrimSpace(string(data))})
+	}
+	return data, nil
+}
+
+// get sends the request to the endpoints in health order, a not found or bad
+// request answer is returned to the caller without trying the next endpoint.
+func (c *EsploraClient) get(path string) ([]byte, error) {
+	var body []byte
+	err := c.pool.do("GET "+path, func(i int) error {
+		var err error
+		body, err = c.request(i, path)
+		return err
+	}, esploraEndpointError)
+	return body, err
+}
+
+func esploraEndpointError(err error) bool {
+	var statusErr *esploraError
+	if errors.As(err, &statusErr) {
+		return statusErr.status != http.StatusNotFound && statusErr.status != http.StatusBadRequest
+	}
+	return true
+}
+
+func esploraNotFound(err error) bool {
+	var statusErr *esploraError
+	return errors.As(err, &statusErr) && statusErr.status == http.StatusNotFound
+}
+
+func parseHeight(body []byte) (int64, error) {
+	height, err := strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return height, nil
+}
+
+func (c *EsploraClient) GetBlockCount() (int64, error) {
+	body, err := c.get("/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	return parseHeight(body)
+}
+
+func (c *EsploraClient) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	body, err := c.get(fmt.Sprintf("/block-height/%d", height))
+	if err != nil {
+		return nil, err
+	}
+	hash, err := chainhash.NewHashFromStr(strings.TrimSpace(string(body)))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return hash, nil
+}
+
+func (c *EsploraClient) GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	body, err := c.get("/block/" + hash.String() + "/raw")
+	if err != nil {
+		return nil, err
+	}
+	var block wire.MsgBlock
+	err = block.Deserialize(bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &block, nil
+}
+
+// GetBlockPrevouts returns the txs of the block only, esplora pages the prevouts
+// of a block 25 txs at a time, they are cheaper looked up per output.
+func (c *EsploraClient) GetBlockPrevouts(hash *chainhash.Hash) (*PrevoutBlock, error) {
+	block, err := c.GetBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	return &PrevoutBlock{
+		Txs:      block.Transactions,
+		Prevouts: make(map[wire.OutPoint][]byte),
+	}, nil
+}
+
+func (c *EsploraClient) GetRawTransaction(hash *chainhash.Hash) (*btcutil.Tx, error) {
+	body, err := c.get("/tx/" + hash.String() + "/raw")
+	if err != nil {
+		if esploraNotFound(err) {
+			return nil, errors.Wrap(ErrTxNotFound, err.Error())
+		}
+		return nil, err
+	}
+	tx, err := btcutil.NewTxFromBytes(body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return tx, nil
+}
+
+// GetTxOutScript looks up the output whether it is spent or not, esplora
+// indexes every tx.
+func (c *EsploraClient) GetTxOutScript(hash *chainhash.Hash, index uint32) ([]byte, error) {
+	body, err := c.get("/tx/" + hash.String())
+	if err != nil {
+		if esploraNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var tx struct {
+		Vout []struct {
+			ScriptPubKey string `json:"scriptpubkey"`
+		} `json:"vout"`
+	}
+	err = json.Unmarshal(body, &tx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if int(index) >= len(tx.Vout) {
+		return nil, nil
+	}
+	pkScript, err := hex.DecodeString(tx.Vout[index].ScriptPubKey)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return pkScript, nil
+}
+
+func (c *EsploraClient) GetRawMempool() ([]*chainhash.Hash, error) {
+	body, err := c.get("/mempool/txids")
+	if err != nil {
+		return nil, err
+	}
+	var txids []string
+	err = json.Unmarshal(body, &txids)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	hashes := make([]*chainhash.Hash, 0, len(txids))
+	for _, txid := range txids {
+		hash, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (c *EsploraClient) ListUnspent(address string) ([]Utxo, error) {
+	body, err := c.get("/address/" + address + "/utxo")
+	if err != nil {
+		return nil, err
+	}
+	var unspents []struct {
+		Txid   string `json:"txid"`
+		Vout   uint32 `json:"vout"`
+		Value  int64  `json:"value"`
+		Status struct {
+			Confirmed bool `json:"confirmed"`
+		} `json:"status"`
+	}
+	err = json.Unmarshal(body, &unspents)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	utxos := make([]Utxo, 0, len(unspents))
+	for _, unspent := range unspents {
+		if !unspent.Status.Confirmed {
+			continue
+		}
+		txHash, err := chainhash.NewHashFromStr(unspent.Txid)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		utxos = append(utxos, Utxo{
+			OutPoint: wire.OutPoint{Hash: *txHash, Index: unspent.Vout},
+			Value:    unspent.Value,
+		})
+	}
+	return utxos, nil
+}
+
+// SendRawTransaction posts the tx to every endpoint in health order until one
+// accepts it, a rejection by a working endpoint is returned to the caller.
+func (c *EsploraClient) SendRawTransaction(tx *wire.MsgTx) (*chainhash.Hash, error) {
+	var buf bytes.Buffer
+	err := tx.Serialize(&buf)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var body []byte
+	err = c.pool.do("POST /tx", func(i int) error {
+		var err error
+		body, err = c.do(i, http.MethodPost, "/tx", []byte(hex.EncodeToString(buf.Bytes())))
+		return err
+	}, esploraEndpointError)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := chainhash.NewHashFromStr(strings.TrimSpace(string(body)))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return hash, nil
+}
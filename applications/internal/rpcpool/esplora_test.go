@@ -0,0 +1,255 @@
+package rpcpool
+
+import (
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// esploraFixture is a block of one tx served by a local esplora stand-in.
+type esploraFixture struct {
+	block *wire.MsgBlock
+	tx    *wire.MsgTx
+	// raw tx posted to /tx
+	posted []byte
+}
+
+func newEsploraFixture() *esploraFixture {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}, Index: 3}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x00, 0x14, 0x01, 0x02}))
+	tx.AddTxOut(wire.NewTxOut(2000, []byte{0x51}))
+	block := wire.NewMsgBlock(wire.NewBlockHeader(1, &chainhash.Hash{2}, &chainhash.Hash{3}, 0, 0))
+	_ = block.AddTransaction(tx)
+	return &esploraFixture{block: block, tx: tx}
+}
+
+func (f *esploraFixture) handler(t *testing.T) http.Handler {
+	blockHash := f.block.BlockHash().String()
+	txid := f.tx.TxHash().String()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blocks/tip/height", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "123\n")
+	})
+	mux.HandleFunc("/block-height/5", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, blockHash)
+	})
+	mux.HandleFunc("/block/"+blockHash+"/raw", func(w http.ResponseWriter, r *http.Request) {
+		_ = f.block.Serialize(w)
+	})
+	mux.HandleFunc("/tx/"+txid+"/raw", func(w http.ResponseWriter, r *http.Request) {
+		_ = f.tx.Serialize(w)
+	})
+	mux.HandleFunc("/tx/"+txid, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"txid":"`+txid+`","vout":[{"scriptpubkey":"00140102","value":1000},{"scriptpubkey":"51","value":2000}]}`)
+	})
+	mux.HandleFunc("/mempool/txids", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `["`+txid+`"]`)
+	})
+	mux.HandleFunc("/address/tb1qtest/utxo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"txid":"`+txid+`","vout":1,"value":2000,"status":{"confirmed":true,"block_height":5}},`+
+			`{"txid":"`+txid+`","vout":0,"value":1000,"status":{"confirmed":false}}]`)
+	})
+	mux.HandleFunc("/address/bad/utxo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"not":"a list"}`)
+	})
+	mux.HandleFunc("/tx", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method", http.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		raw, err := hex.DecodeString(string(body))
+		if err != nil {
+			http.Error(w, "sendrawtransaction RPC error: TX decode failed", http.StatusBadRequest)
+			return
+		}
+		f.posted = raw
+		var tx wire.MsgTx
+		if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+			http.Error(w, "sendrawtransaction RPC error: TX decode failed", http.StatusBadRequest)
+			return
+		}
+		if len(tx.TxIn) == 0 {
+			http.Error(w, "sendrawtransaction RPC error: bad-txns-vin-empty", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, tx.TxHash().String())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Logf("esplora stand-in: not found %s %s", r.Method, r.URL.Path)
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+	})
+	return mux
+}
+
+func newTestEsplora(t *testing.T, urls ...string) *EsploraClient {
+	client, err := NewEsploraClient("esplora-test", urls, log.NewLogger("esplora-test", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestEsploraBlocks(t *testing.T) {
+	fixture := newEsploraFixture()
+	server := httptest.NewServer(fixture.handler(t))
+	defer server.Close()
+	client := newTestEsplora(t, server.URL+"/")
+
+	height, err := client.GetBlockCount()
+	if err != nil || height != 123 {
+		t.Fatalf("block count: %d, err: %v", height, err)
+	}
+	hash, err := client.GetBlockHash(5)
+	if err != nil || *hash != fixture.block.BlockHash() {
+		t.Fatalf("block hash: %v, err: %v", hash, err)
+	}
+	block, err := client.GetBlock(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.BlockHash() != fixture.block.BlockHash() || len(block.Transactions) != 1 ||
+		block.Transactions[0].TxHash() != fixture.tx.TxHash() {
+		t.Fatalf("block mismatch: %s", block.BlockHash())
+	}
+	prevouts, err := client.GetBlockPrevouts(hash)
+	if err != nil || len(prevouts.Txs) != 1 || len(prevouts.Prevouts) != 0 {
+		t.Fatalf("block prevouts: %+v, err: %v", prevouts, err)
+	}
+	_, err = client.GetBlockHash(6)
+	if err == nil {
+		t.Fatal("expected an error for an unknown height")
+	}
+}
+
+func TestEsploraTxs(t *testing.T) {
+	fixture := newEsploraFixture()
+	server := httptest.NewServer(fixture.handler(t))
+	defer server.Close()
+	client := newTestEsplora(t, server.URL)
+	txid := fixture.tx.TxHash()
+
+	tx, err := client.GetRawTransaction(&txid)
+	if err != nil || *tx.Hash() != txid {
+		t.Fatalf("raw tx: %v, err: %v", tx, err)
+	}
+	_, err = client.GetRawTransaction(&chainhash.Hash{9})
+	if !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("expected ErrTxNotFound, got: %v", err)
+	}
+
+	pkScript, err := client.GetTxOutScript(&txid, 1)
+	if err != nil || !bytes.Equal(pkScript, []byte{0x51}) {
+		t.Fatalf("prevout: %x, err: %v", pkScript, err)
+	}
+	pkScript, err = client.GetTxOutScript(&txid, 2)
+	if err != nil || pkScript != nil {
+		t.Fatalf("out of range prevout: %x, err: %v", pkScript, err)
+	}
+	pkScript, err = client.GetTxOutScript(&chainhash.Hash{9}, 0)
+	if err != nil || pkScript != nil {
+		t.Fatalf("unknown prevout: %x, err: %v", pkScript, err)
+	}
+
+	hashes, err := client.GetRawMempool()
+	if err != nil || len(hashes) != 1 || *hashes[0] != txid {
+		t.Fatalf("mempool: %v, err: %v", hashes, err)
+	}
+}
+
+func TestEsploraUnspent(t *testing.T) {
+	fixture := newEsploraFixture()
+	server := httptest.NewServer(fixture.handler(t))
+	defer server.Close()
+	client := newTestEsplora(t, server.URL)
+
+	utxos, err := client.ListUnspent("tb1qtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the unconfirmed output is left out
+	if len(utxos) != 1 || utxos[0].OutPoint.Hash != fixture.tx.TxHash() ||
+		utxos[0].OutPoint.Index != 1 || utxos[0].Value != 2000 {
+		t.Fatalf("utxos: %+v", utxos)
+	}
+	_, err = client.ListUnspent("bad")
+	if err == nil {
+		t.Fatal("expected a decode error")
+	}
+}
+
+func TestEsploraBroadcast(t *testing.T) {
+	fixture := newEsploraFixture()
+	server := httptest.NewServer(fixture.handler(t))
+	defer server.Close()
+	client := newTestEsplora(t, server.URL)
+
+	hash, err := client.SendRawTransaction(fixture.tx)
+	if err != nil || *hash != fixture.tx.TxHash() {
+		t.Fatalf("broadcast: %v, err: %v", hash, err)
+	}
+	var buf bytes.Buffer
+	_ = fixture.tx.Serialize(&buf)
+	if !bytes.Equal(fixture.posted, buf.Bytes()) {
+		t.Fatalf("posted tx: %x", fixture.posted)
+	}
+
+	_, err = client.SendRawTransaction(wire.NewMsgTx(2))
+	var statusErr *esploraError
+	if !errors.As(err, &statusErr) || statusErr.status != http.StatusBadRequest {
+		t.Fatalf("expected a rejection, got: %v", err)
+	}
+}
+
+func TestEsploraFailover(t *testing.T) {
+	fixture := newEsploraFixture()
+	var down int32
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&down, 1)
+		http.Error(w, "upstream error", http.StatusBadGateway)
+	}))
+	defer broken.Close()
+	var served int32
+	handler := fixture.handler(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&served, 1)
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := newTestEsplora(t, broken.URL, server.URL)
+	height, err := client.GetBlockCount()
+	if err != nil || height != 123 {
+		t.Fatalf("block count: %d, err: %v", height, err)
+	}
+	if atomic.LoadInt32(&down) != 1 || atomic.LoadInt32(&served) != 1 {
+		t.Fatalf("expected a fail over, broken: %d, served: %d", down, served)
+	}
+
+	// a not found answer is the caller's, the other endpoints are not asked
+	atomic.StoreInt32(&served, 0)
+	atomic.StoreInt32(&down, 0)
+	client = newTestEsplora(t, server.URL, broken.URL)
+	_, err = client.GetRawTransaction(&chainhash.Hash{9})
+	if !errors.Is(err, ErrTxNotFound) || atomic.LoadInt32(&down) != 0 {
+		t.Fatalf("not found: %v, broken: %d", err, down)
+	}
+
+	// every endpoint down
+	client = newTestEsplora(t, broken.URL)
+	_, err = client.GetBlockCount()
+	var statusErr *esploraError
+	if !errors.As(err, &statusErr) || statusErr.status != http.StatusBadGateway {
+		t.Fatalf("expected the endpoint error, got: %v", err)
+	}
+}
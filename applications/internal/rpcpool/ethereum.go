@@ -0,0 +1,244 @@
+package rpcpool
+
+import (
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// blocks an evm endpoint may trail the best head before it is deprioritized
+	ethMaxLag   = 5
+	dialTimeout = 10 * time.Second
+	pollTimeout = 10 * time.Second
+)
+
+// EthClient is an ethereum client over several endpoints of a chain, every
+// call is served by the healthiest endpoint and fails over to the next one
+// on transport errors.
+type EthClient struct {
+	pool    *pool
+	locks   []sync.Mutex
+	clients []*ethclient.Client
+}
+
+func NewEthClient(name string, urls []string, logger *log.Logger) (*EthClient, error) {
+	pool, err := newPool(name, urls, ethMaxLag, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &EthClient{
+		pool:    pool,
+		locks:   make([]sync.Mutex, len(urls)),
+		clients: make([]*ethclient.Client, len(urls)),
+	}, nil
+}
+
+// Watch polls the head of every endpoint to track how far it trails the chain.
+func (c *EthClient) Watch(interval time.Duration) {
+	go c.pool.watch(interval, func(i int) (int64, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+		defer cancel()
+		client, err := c.client(ctx, i)
+		if err != nil {
+			return 0, err
+		}
+		number, err := client.BlockNumber(ctx)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return int64(number), nil
+	})
+}
+
+// Stats returns the health of the endpoints.
+func (c *EthClient) Stats() []Stats {
+	return c.pool.stats()
+}
+
+// Websocket reports whether an endpoint supports subscriptions.
+func (c *EthClient) Websocket() bool {
+	for _, e := range c.pool.endpoints {
+		if IsWebsocket(e.url) {
+			return true
+		}
+	}
+	return false
+}
+
+// client dials the endpoint on first use, an endpoint that is down at start
+// up is dialed again by the next call.
+func (c *EthClient) client(ctx context.Context, i int) (*ethclient.Client, error) {
+	c.locks[i].Lock()
+	defer c.locks[i].Unlock()
+	if c.clients[i] == nil {
+		ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+		defer cancel()
+		client, err := ethclient.DialContext(ctx, c.pool.endpoints[i].url)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		c.clients[i] = client
+	}
+	return c.clients[i], nil
+}
+
+func ethCall[T any](ctx context.Context, c *EthClient, method string, call func(client *ethclient.Client) (T, error)) (T, error) {
+	var result T
+	err := c.pool.do(method, func(i int) error {
+		client, err := c.client(ctx, i)
+		if err != nil {
+			return err
+		}
+		result, err = call(client)
+		return err
+	}, ethEndpointError(ctx))
+	return result, err
+}
+
+// ethEndpointError reports whether err is caused by the endpoint, json-rpc
+// errors are answers of a working node and are returned to the caller.
+func ethEndpointError(ctx context.Context) func(error) bool {
+	return func(err error) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if errors.Is(err, ethereum.NotFound) {
+			return false
+		}
+		var rpcErr rpc.Error
+		return !errors.As(err, &rpcErr)
+	}
+}
+
+func (c *EthClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return ethCall(ctx, c, "eth_blockNumber", func(client *ethclient.Client) (uint64, error) {
+		return client.BlockNumber(ctx)
+	})
+}
+
+func (c *EthClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return ethCall(ctx, c, "eth_getLogs", func(client *ethclient.Client) ([]types.Log, error) {
+		return client.FilterLogs(ctx, q)
+	})
+}
+
+func (c *EthClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return ethCall(ctx, c, "eth_getTransactionReceipt", func(client *ethclient.Client) (*types.Receipt, error) {
+		return client.TransactionReceipt(ctx, txHash)
+	})
+}
+
+func (c *EthClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return ethCall(ctx, c, "eth_getTransactionCount", func(client *ethclient.Client) (uint64, error) {
+		return client.NonceAt(ctx, account, blockNumber)
+	})
+}
+
+func (c *EthClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return ethCall(ctx, c, "eth_gasPrice", func(client *ethclient.Client) (*big.Int, error) {
+		return client.SuggestGasPrice(ctx)
+	})
+}
+
+func (c *EthClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return ethCall(ctx, c, "eth_estimateGas", func(client *ethclient.Client) (uint64, error) {
+		return client.EstimateGas(ctx, msg)
+	})
+}
+
+// SendTransaction broadcasts tx, resending a signed transaction to another
+// endpoint is safe as nodes dedupe it by hash.
+func (c *EthClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := ethCall(ctx, c, "eth_sendRawTransaction", func(client *ethclient.Client) (struct{}, error) {
+		return struct{}{}, client.SendTransaction(ctx, tx)
+	})
+	return err
+}
+
+func (c *EthClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	_, err := ethCall(ctx, c, method, func(client *ethclient.Client) (struct{}, error) {
+		return struct{}{}, client.Client().CallContext(ctx, result, method, args...)
+	})
+	return err
+}
+
+// BatchCallContext sends the batch to a single endpoint, errors of the
+// elements are left to the caller.
+func (c *EthClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	method := "batch"
+	if len(b) > 0 {
+		method = "batch " + b[0].Method
+	}
+	_, err := ethCall(ctx, c, method, func(client *ethclient.Client) (struct{}, error) {
+		for i := range b {
+			b[i].Error = nil
+		}
+		return struct{}{}, client.Client().BatchCallContext(ctx, b)
+	})
+	return err
+}
+
+// SubscribeNewHead subscribes on the healthiest websocket endpoint.
+func (c *EthClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	sub, _, err := c.subscribe(ctx, "new head", func(client *ethclient.Client) (ethereum.Subscription, error) {
+		return client.SubscribeNewHead(ctx, ch)
+	})
+	return sub, err
+}
+
+// SubscribeFilterLogs subscribes on the healthiest websocket endpoint and
+// returns the head of the endpoint once subscribed, the logs of the blocks
+// above it are all delivered.
+func (c *EthClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, int64, error) {
+	return c.subscribe(ctx, "logs", func(client *ethclient.Client) (ethereum.Subscription, error) {
+		return client.SubscribeFilterLogs(ctx, q, ch)
+	})
+}
+
+func (c *EthClient) subscribe(ctx context.Context, name string, subscribe func(client *ethclient.Client) (ethereum.Subscription, error)) (ethereum.Subscription, int64, error) {
+	var err error = rpc.ErrNotificationsUnsupported
+	for _, i := range c.pool.order() {
+		e := c.pool.endpoints[i]
+		if !IsWebsocket(e.url) {
+			continue
+		}
+		var client *ethclient.Client
+		client, err = c.client(ctx, i)
+		if err != nil {
+			e.fail(err)
+			continue
+		}
+		var sub ethereum.Subscription
+		sub, err = subscribe(client)
+		if err != nil {
+			e.fail(err)
+			c.pool.logger.Warnf("[rpc.%s] subscribe %s failed on %s: %v", c.pool.name, name, e.url, err)
+			continue
+		}
+		var head uint64
+		head, err = client.BlockNumber(ctx)
+		if err != nil {
+			sub.Unsubscribe()
+			e.fail(err)
+			c.pool.logger.Warnf("[rpc.%s] subscribe %s failed on %s: %v", c.pool.name, name, e.url, err)
+			continue
+		}
+		c.pool.logger.Infof("[rpc.%s] subscribe %s on %s", c.pool.name, name, e.url)
+		return sub, int64(head), nil
+	}
+	return nil, 0, errors.WithStack(err)
+}
+
+func IsWebsocket(url string) bool {
+	return strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://")
+}
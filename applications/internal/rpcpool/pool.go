@@ -0,0 +1,216 @@
+package rpcpool
+
+import (
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"github.com/pkg/errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// failures in a row before an endpoint is taken out of rotation
+	maxFailures = 3
+	// time an endpoint stays out of rotation before it is tried again
+	downDuration = 30 * time.Second
+	// weight of the latest call in the latency moving average
+	latencyWeight = 0.2
+)
+
+var ErrNoEndpoint = errors.New("no rpc endpoint configured")
+
+// Stats is a snapshot of the health of an endpoint.
+type Stats struct {
+	Url      string
+	Calls    int64
+	Errors   int64
+	Latency  time.Duration
+	Head     int64
+	Lag      int64
+	Down     bool
+	LastErr  string
+	LastCall time.Time
+}
+
+type endpoint struct {
+	mu        sync.Mutex
+	url       string
+	calls     int64
+	errors    int64
+	failures  int64
+	latency   time.Duration
+	head      int64
+	downUntil time.Time
+	lastErr   error
+	lastCall  time.Time
+}
+
+func (e *endpoint) succeed(latency time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls++
+	e.failures = 0
+	e.downUntil = time.Time{}
+	e.lastCall = time.Now()
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = time.Duration(float64(e.latency)*(1-latencyWeight) + float64(latency)*latencyWeight)
+	}
+}
+
+func (e *endpoint) fail(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls++
+	e.errors++
+	e.failures++
+	e.lastErr = err
+	e.lastCall = time.Now()
+	if e.failures >= maxFailures {
+		e.downUntil = time.Now().Add(downDuration)
+	}
+}
+
+// pool orders the endpoints of a chain by health, the endpoints that are up
+// and follow the best head come first, ties are broken by latency.
+type pool struct {
+	name      string
+	endpoints []*endpoint
+	maxLag    int64
+	logger    *log.Logger
+}
+
+func newPool(name string, urls []string, maxLag int64, logger *log.Logger) (*pool, error) {
+	if len(urls) == 0 {
+		return nil, ErrNoEndpoint
+	}
+	endpoints := make([]*endpoint, 0, len(urls))
+	for _, url := range urls {
+		endpoints = append(endpoints, &endpoint{url: url})
+	}
+	return &pool{
+		name:      name,
+		endpoints: endpoints,
+		maxLag:    maxLag,
+		logger:    logger,
+	}, nil
+}
+
+// do runs call on the endpoints in health order until one serves it. Errors
+// the endpoint is not to blame for, e.g. a reverted call, are returned as is
+// without trying the next endpoint.
+func (p *pool) do(method string, call func(i int) error, endpointError func(error) bool) error {
+	var err error
+	for n, i := range p.order() {
+		e := p.endpoints[i]
+		begin := time.Now()
+		err = call(i)
+		if err == nil || !endpointError(err) {
+			latency := time.Since(begin)
+			e.succeed(latency)
+			p.logger.Debugf("[rpc.%s] %s served by %s in %s", p.name, method, e.url, latency)
+			return err
+		}
+		e.fail(err)
+		if n < len(p.endpoints)-1 {
+			p.logger.Warnf("[rpc.%s] %s failed on %s, fail over: %v", p.name, method, e.url, err)
+		}
+	}
+	return err
+}
+
+func (p *pool) order() []int {
+	type score struct {
+		index   int
+		down    bool
+		lagging bool
+		latency time.Duration
+	}
+	now := time.Now()
+	best := p.bestHead()
+	scores := make([]score, 0, len(p.endpoints))
+	for i, e := range p.endpoints {
+		e.mu.Lock()
+		scores = append(scores, score{
+			index:   i,
+			down:    now.Before(e.downUntil),
+			lagging: e.head > 0 && best-e.head > p.maxLag,
+			latency: e.latency,
+		})
+		e.mu.Unlock()
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].down != scores[j].down {
+			return !scores[i].down
+		}
+		if scores[i].lagging != scores[j].lagging {
+			return !scores[i].lagging
+		}
+		return scores[i].latency < scores[j].latency
+	})
+	order := make([]int, 0, len(scores))
+	for _, s := range scores {
+		order = append(order, s.index)
+	}
+	return order
+}
+
+func (p *pool) bestHead() int64 {
+	best := int64(0)
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		if e.head > best {
+			best = e.head
+		}
+		e.mu.Unlock()
+	}
+	return best
+}
+
+// watch polls the head of every endpoint, a failed poll counts as a failed call.
+func (p *pool) watch(interval time.Duration, head func(i int) (int64, error)) {
+	for {
+		for i, e := range p.endpoints {
+			begin := time.Now()
+			number, err := head(i)
+			if err != nil {
+				e.fail(err)
+				p.logger.Errorf("[rpc.%s] poll head of %s error: %v", p.name, e.url, err)
+				continue
+			}
+			e.succeed(time.Since(begin))
+			e.mu.Lock()
+			e.head = number
+			e.mu.Unlock()
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (p *pool) stats() []Stats {
+	now := time.Now()
+	best := p.bestHead()
+	list := make([]Stats, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		stats := Stats{
+			Url:      e.url,
+			Calls:    e.calls,
+			Errors:   e.errors,
+			Latency:  e.latency,
+			Head:     e.head,
+			Down:     now.Before(e.downUntil),
+			LastCall: e.lastCall,
+		}
+		if e.head > 0 {
+			stats.Lag = best - e.head
+		}
+		if e.lastErr != nil {
+			stats.LastErr = e.lastErr.Error()
+		}
+		e.mu.Unlock()
+		list = append(list, stats)
+	}
+	return list
+}
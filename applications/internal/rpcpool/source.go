@@ -0,0 +1,48 @@
+package rpcpool
+
+import (
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"time"
+)
+
+var ErrTxNotFound = errors.New("transaction not found")
+
+// BtcSource is a source of bitcoin chain data, a bitcoin core node over json-rpc
+// or an esplora rest api.
+type BtcSource interface {
+	// Watch polls the head of every endpoint to track how far it trails the chain.
+	Watch(interval time.Duration)
+	// Stats returns the health of the endpoints.
+	Stats() []Stats
+	GetBlockCount() (int64, error)
+	GetBlockHash(height int64) (*chainhash.Hash, error)
+	GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error)
+	// GetBlockPrevouts returns the txs of the block with the outputs spent by
+	// their inputs, as far as the source returns them.
+	GetBlockPrevouts(hash *chainhash.Hash) (*PrevoutBlock, error)
+	// GetRawTransaction fails with ErrTxNotFound for a tx the source does not know.
+	GetRawTransaction(hash *chainhash.Hash) (*btcutil.Tx, error)
+	// GetTxOutScript returns the pk script of an output, nil when the source
+	// does not know the output.
+	GetTxOutScript(hash *chainhash.Hash, index uint32) ([]byte, error)
+	GetRawMempool() ([]*chainhash.Hash, error)
+	// ListUnspent returns the confirmed unspent outputs of an address.
+	ListUnspent(address string) ([]Utxo, error)
+	// SendRawTransaction broadcasts a signed tx, a tx already known counts as sent.
+	SendRawTransaction(tx *wire.MsgTx) (*chainhash.Hash, error)
+}
+
+// PrevoutBlock is the txs of a block and the pk scripts of the outputs they spend.
+type PrevoutBlock struct {
+	Txs      []*wire.MsgTx
+	Prevouts map[wire.OutPoint][]byte
+}
+
+// Utxo is a confirmed unspent output.
+type Utxo struct {
+	OutPoint wire.OutPoint
+	Value    int64
+}
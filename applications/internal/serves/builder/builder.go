@@ -4,6 +4,8 @@ import (
 	"bsquared.network/message-sharing-applications/internal/config"
 	"bsquared.network/message-sharing-applications/internal/enums"
 	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/registry"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
 	msg "bsquared.network/message-sharing-applications/internal/utils/ethereum/message"
 	"bsquared.network/message-sharing-applications/internal/utils/log"
 	"bytes"
@@ -16,36 +18,45 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	_types "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"math/big"
 	"sync"
 	"time"
 )
 
 type Builder struct {
-	rpc    *ethclient.Client
-	db     *gorm.DB
-	conf   config.Blockchain
-	mu     sync.Mutex
-	keys   map[string]bool
-	logger *log.Logger
+	rpc      *rpcpool.EthClient
+	db       *gorm.DB
+	conf     config.Blockchain
+	mu       sync.Mutex
+	keys     map[string]bool
+	registry *registry.Registry
+	logger   *log.Logger
 }
 
-func NewBuilder(keys []string, conf config.Blockchain, db *gorm.DB, rpc *ethclient.Client, logger *log.Logger) *Builder {
+// messageSource is the chain and contract pair a call message is verified
+// against, every source has its own validator set and signature weight.
+type messageSource struct {
+	FromChainId     int64
+	ToMessageBridge string
+}
+
+func NewBuilder(keys []string, conf config.Blockchain, db *gorm.DB, rpc *rpcpool.EthClient, logger *log.Logger) *Builder {
 	_keys := make(map[string]bool, 0)
 	for _, key := range keys {
 		_keys[key] = true
 	}
 	return &Builder{
-		db:     db,
-		rpc:    rpc,
-		conf:   conf,
-		keys:   _keys,
-		logger: logger,
+		db:       db,
+		rpc:      rpc,
+		conf:     conf,
+		keys:     _keys,
+		registry: registry.NewRegistry(db),
+		logger:   logger,
 	}
 }
 
@@ -64,7 +75,7 @@ func (b *Builder) Start() {
 func (b *Builder) build() {
 	duration := time.Millisecond * time.Duration(b.conf.BlockInterval)
 	for {
-		list, err := b.pendingCallMessage(b.conf.SignatureWeight, 10)
+		list, err := b.pendingCallMessages(10)
 		if err != nil {
 			b.logger.Errorf("et pending call message err: %s", err)
 			time.Sleep(duration)
@@ -126,8 +137,7 @@ func (b *Builder) buildMessage(message models.Message) error {
 	toAddress := common.HexToAddress(message.ToMessageBridge)
 	b.logger.Debugf("toAddress: %v\n", toAddress)
 
-	var signatures []string
-	err = b.db.Model(models.MessageSignature{}).Select([]string{"signature"}).Where("message_id=?", message.Id).Find(&signatures).Error
+	signatures, err := b.validSignatures(message)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -136,7 +146,11 @@ func (b *Builder) buildMessage(message models.Message) error {
 	//	return errors.WithStack(err)
 	//}
 	b.logger.Infof("FromChainId: %d, FromId: %s, FromSender: %s, ToContractAddress: %s, ToBytes: %s", message.FromChainId, common.HexToHash(message.FromId).Big().Text(16), message.FromSender, message.ToContractAddress, message.ToBytes)
-	data := msg.Send(message.FromChainId, common.HexToHash(message.FromId).Big(), message.FromSender, message.ToContractAddress, message.ToBytes, signatures)
+	data, err := msg.Send(message.FromChainId, common.HexToHash(message.FromId).Big(), message.FromSender, message.ToContractAddress, message.ToBytes, signatures)
+	if err != nil {
+		b.logger.Errorf("pack send err: %s\n", err)
+		return errors.WithStack(err)
+	}
 	b.logger.Debugf("data: %x\n", data)
 	gasLimit, err := b.rpc.EstimateGas(context.Background(), ethereum.CallMsg{
 		From:     common.HexToAddress(UserAddress),
@@ -152,7 +166,7 @@ func (b *Builder) buildMessage(message models.Message) error {
 	b.logger.Debugf("gasLimit: %v\n", gasLimit)
 	err = b.db.Transaction(func(tx *gorm.DB) error {
 		// nonce
-		nonce, err := b.GetNonce(UserAddress)
+		nonce, err := b.GetNonce(tx, UserAddress)
 		if err != nil {
 			b.logger.Errorf("get nonce err: %s\n", err)
 			return errors.WithStack(err)
@@ -195,17 +209,63 @@ func (b *Builder) buildMessage(message models.Message) error {
 	return nil
 }
 
-func (b *Builder) pendingCallMessage(weight int64, limit int) ([]models.Message, error) {
-	var list []models.Message
-	err := b.db.Where("`to_chain_id`=? AND `type`=? AND `signatures_count`>=? AND `status`=?",
-		b.conf.ChainId, enums.MessageTypeCall, weight, enums.MessageStatusPending).Limit(limit).Find(&list).Error
+// pendingCallMessages returns the pending messages reaching the weight of
+// their source.
+func (b *Builder) pendingCallMessages(limit int) ([]models.Message, error) {
+	var sources []messageSource
+	err := b.db.Model(models.Message{}).Distinct("from_chain_id", "to_message_bridge").
+		Where("to_chain_id=? AND type=? AND status=?", b.conf.ChainId, enums.MessageTypeCall, enums.MessageStatusPending).
+		Find(&sources).Error
 	if err != nil {
-		b.logger.Errorf("get message err: %s", err)
+		b.logger.Errorf("get message sources err: %s", err)
 		return nil, err
 	}
+	list := make([]models.Message, 0)
+	for _, source := range sources {
+		weight, err := b.registry.Weight(b.conf.ChainId, source.ToMessageBridge, source.FromChainId, b.conf.SignatureWeight)
+		if err != nil {
+			b.logger.Errorf("get weight err: %s", err)
+			return nil, err
+		}
+		var messages []models.Message
+		err = b.db.Where("to_chain_id=? AND type=? AND from_chain_id=? AND to_message_bridge=? AND signatures_count>=? AND status=?",
+			b.conf.ChainId, enums.MessageTypeCall, source.FromChainId, source.ToMessageBridge, weight, enums.MessageStatusPending).
+			Limit(limit - len(list)).Find(&messages).Error
+		if err != nil {
+			b.logger.Errorf("get message err: %s", err)
+			return nil, err
+		}
+		list = append(list, messages...)
+		if len(list) >= limit {
+			break
+		}
+	}
 	return list, nil
 }
 
+// validSignatures returns the message signatures whose signer is in the
+// validator set of the target contract, every signature when no set is known.
+func (b *Builder) validSignatures(message models.Message) ([]string, error) {
+	var messageSignatures []models.MessageSignature
+	err := b.db.Where("message_id=?", message.Id).Find(&messageSignatures).Error
+	if err != nil {
+		return nil, err
+	}
+	validators, err := b.registry.Validators(b.conf.ChainId, message.ToMessageBridge, message.FromChainId, b.conf.Validators)
+	if err != nil {
+		return nil, err
+	}
+	signatures := make([]string, 0, len(messageSignatures))
+	for _, messageSignature := range messageSignatures {
+		if validators != nil && !registry.Contains(validators, messageSignature.Signer) {
+			b.logger.Infof("skip signature of %s, not in validator set", messageSignature.Signer)
+			continue
+		}
+		signatures = append(signatures, messageSignature.Signature)
+	}
+	return signatures, nil
+}
+
 func (b *Builder) SignTx(accountAddress string, accountKey *ecdsa.PrivateKey, nonce uint64, toAddress string, value *big.Int, gasLimit uint64, gasPrice *big.Int, bytecode []byte, chainID int64) ([]byte, error) {
 	_signature, err := b._signTx(accountAddress, accountKey, nonce, toAddress, value, gasLimit, gasPrice, bytecode, chainID)
 	if err != nil {
@@ -236,10 +296,11 @@ func (b *Builder) _signTx(accountAddress string, accountKey *ecdsa.PrivateKey, n
 	return rawTxBytes.Bytes(), nil
 }
 
-func (b *Builder) GetNonce(userAddress string) (uint64, error) {
+func (b *Builder) GetNonce(tx *gorm.DB, userAddress string) (uint64, error) {
 	var signature models.Signature
-	err := b.db.Raw("SELECT * FROM signatures WHERE `status`!=? AND `address`=? ORDER BY nonce DESC FOR UPDATE ",
-		enums.SignatureStatusInvalid, userAddress).First(&signature).Error
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status!=? AND address=?", enums.SignatureStatusInvalid, userAddress).
+		Order("nonce DESC").First(&signature).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return 0, err
 	} else if err == gorm.ErrRecordNotFound {
@@ -334,7 +395,7 @@ func (b *Builder) broadcast() {
 	duration := time.Millisecond * time.Duration(b.conf.BlockInterval)
 	for {
 		var signatures []models.Signature
-		err := b.db.Where("`chain_id`=? AND `status`=?", b.conf.ChainId, enums.SignatureStatusPending).Order("id").Limit(100).Find(&signatures).Error
+		err := b.db.Where("chain_id=? AND status=?", b.conf.ChainId, enums.SignatureStatusPending).Order("id").Limit(100).Find(&signatures).Error
 		if err != nil {
 			time.Sleep(duration)
 			continue
@@ -391,7 +452,7 @@ func (b *Builder) broadcastSignature(signature models.Signature) error {
 func (b *Builder) _broadcast(signature string) error {
 	rawTxBytes, err := hex.DecodeString(signature)
 	if err != nil {
-		b.logger.Errorf("Decode signature err[%d]: %s\n", signature, err)
+		b.logger.Errorf("Decode signature err[%s]: %s\n", signature, err)
 		return err
 	}
 	tx := new(_types.Transaction)
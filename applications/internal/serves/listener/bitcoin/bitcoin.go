@@ -4,27 +4,29 @@ import (
 	"bsquared.network/message-sharing-applications/internal/config"
 	"bsquared.network/message-sharing-applications/internal/enums"
 	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
 	"bsquared.network/message-sharing-applications/internal/types"
 	"bsquared.network/message-sharing-applications/internal/utils/aa"
 	"bsquared.network/message-sharing-applications/internal/utils/ethereum/message"
 	"bsquared.network/message-sharing-applications/internal/utils/log"
-	"bytes"
+	"bsquared.network/message-sharing-applications/internal/utils/ord"
+	"bsquared.network/message-sharing-applications/internal/utils/prevout"
 	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"github.com/btcsuite/btcd/btcutil"
-	"github.com/btcsuite/btcd/chaincfg"
-	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -39,21 +41,40 @@ var (
 
 const (
 	// tx type
-	TxTypeTransfer = "transfer" // btc transfer
-	TxTypeWithdraw = "withdraw" // btc withdraw
+	TxTypeTransfer      = "transfer"       // btc transfer
+	TxTypeWithdraw      = "withdraw"       // btc withdraw
+	TxTypeBrc20Transfer = "brc20_transfer" // brc-20 transfer inscription
+	TxTypeRunesTransfer = "runes_transfer" // runes edicts
+	TxTypeInscription   = "inscription"    // other inscriptions
 )
 
 type BitcoinListener struct {
 	conf        config.Blockchain
 	particle    config.Particle
-	rpc         *rpcclient.Client
+	rpc         rpcpool.BtcSource
 	db          *gorm.DB
 	logger      *log.Logger
 	latestBlock int64
 	bridges     map[int64]string
+	routes      map[string]config.BitcoinRoute
+	prevouts    *prevout.Resolver
+	// ord verifies the token deposits, nil without Tokens
+	ord *ord.Client
+	// closed and replaced when the latest block moves
+	blockMu  sync.Mutex
+	newBlock chan struct{}
 }
 
-func NewListener(bridges map[int64]string, conf config.Blockchain, particle config.Particle, rpc *rpcclient.Client, db *gorm.DB, logger *log.Logger) *BitcoinListener {
+func NewListener(bridges map[int64]string, conf config.Blockchain, particle config.Particle, rpc rpcpool.BtcSource, db *gorm.DB, logger *log.Logger) *BitcoinListener {
+	// the routes are validated when the config is loaded
+	routes, err := conf.BitcoinRoutes()
+	if err != nil {
+		logger.Errorf("parse routes err: %s", err)
+	}
+	var indexer *ord.Client
+	if conf.Tokens {
+		indexer = ord.NewClient(conf.OrdUrl)
+	}
 	return &BitcoinListener{
 		conf:     conf,
 		particle: particle,
@@ -61,6 +82,10 @@ func NewListener(bridges map[int64]string, conf config.Blockchain, particle conf
 		db:       db,
 		logger:   logger,
 		bridges:  bridges,
+		routes:   routes,
+		prevouts: prevout.NewResolver(rpc, prevout.DefaultCacheSize),
+		ord:      indexer,
+		newBlock: make(chan struct{}),
 	}
 }
 
@@ -72,31 +97,75 @@ func (l *BitcoinListener) Start() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go l.syncLatestBlock()
+	if l.conf.Zmq != "" {
+		go l.watchZmq()
+	}
 	go l.syncTask()
 	go l.handDeposit()
+	if l.conf.Mempool {
+		go l.watchMempool()
+	}
+	if l.conf.Refund {
+		go l.handRefund()
+	}
 	<-ctx.Done()
 }
 
 func (l *BitcoinListener) syncLatestBlock() {
 	for {
 		duration := time.Millisecond * time.Duration(l.conf.BlockInterval) * 10
-		latest, err := l.rpc.GetBlockCount()
+		err := l.updateLatestBlock()
 		if err != nil {
 			l.logger.Errorf("sync latest block failed, err: %v", err)
-			time.Sleep(duration)
-			continue
 		}
-		l.latestBlock = int64(latest) - l.conf.SafeBlockNumber
-		l.logger.Infof("sync latest block success, latest block: %d", l.latestBlock)
 		time.Sleep(duration)
 	}
 }
 
+// updateLatestBlock reads the block count, the indexing tasks are woken when it moved.
+func (l *BitcoinListener) updateLatestBlock() error {
+	latest, err := l.rpc.GetBlockCount()
+	if err != nil {
+		return err
+	}
+	latestBlock := int64(latest) - l.conf.SafeBlockNumber
+	l.blockMu.Lock()
+	moved := latestBlock != l.latestBlock
+	l.latestBlock = latestBlock
+	if moved {
+		close(l.newBlock)
+		l.newBlock = make(chan struct{})
+	}
+	l.blockMu.Unlock()
+	l.logger.Infof("sync latest block success, latest block: %d", latestBlock)
+	return nil
+}
+
+// getLatestBlock returns the latest block to index.
+func (l *BitcoinListener) getLatestBlock() int64 {
+	l.blockMu.Lock()
+	defer l.blockMu.Unlock()
+	return l.latestBlock
+}
+
+// waitBlock waits for the latest block to move, at most BlockInterval.
+func (l *BitcoinListener) waitBlock() {
+	l.blockMu.Lock()
+	newBlock := l.newBlock
+	l.blockMu.Unlock()
+	select {
+	case <-newBlock:
+	case <-time.After(time.Millisecond * time.Duration(l.conf.BlockInterval)):
+	}
+}
+
+// syncTask indexes the pending tasks in passes of at most taskPassBlocks blocks,
+// the tasks are listed again before every pass so new tasks are picked up.
 func (l *BitcoinListener) syncTask() {
 	for {
 		duration := time.Millisecond * time.Duration(l.conf.BlockInterval)
 		var tasks []models.SyncTask
-		err := l.db.Where("`chain_type`=? AND `chain_id`=? AND `status`=?", enums.ChainTypeUTXO, l.conf.ChainId, enums.TaskStatusPending).Limit(20).Find(&tasks).Error
+		err := l.db.Where("chain_type=? AND chain_id=? AND status=?", enums.ChainTypeUTXO, l.conf.ChainId, enums.TaskStatusPending).Limit(20).Find(&tasks).Error
 		if err != nil {
 			l.logger.Errorf("get task list err: %s", err)
 			time.Sleep(duration)
@@ -104,111 +173,77 @@ func (l *BitcoinListener) syncTask() {
 		}
 		if len(tasks) == 0 {
 			l.logger.Infof("no task to handle")
-			time.Sleep(duration)
+			l.waitBlock()
 			continue
 		}
+		var moved atomic.Bool
 		wg := sync.WaitGroup{}
 		for _, task := range tasks {
 			wg.Add(1)
-			go func(take models.SyncTask, wg *sync.WaitGroup) {
+			go func(task models.SyncTask, wg *sync.WaitGroup) {
 				defer wg.Done()
-				err := l.handleTask(task)
+				more, err := l.handleTask(task)
 				if err != nil {
-					l.logger.Errorf("handle task err: %s", err)
+					l.logger.Errorf("handle task err: %s, task id: %d", err, task.Id)
+				}
+				if more {
+					moved.Store(true)
 				}
 			}(task, &wg)
 		}
 		wg.Wait()
+		// the tasks caught up or failed wait for the next block
+		if !moved.Load() {
+			l.waitBlock()
+		}
 	}
 }
 
-func (l *BitcoinListener) handleTask(task models.SyncTask) error {
-	l.logger.Infof("start handle task, task id: %d", task.Id)
-	var (
-		currentBlock   int64 // index current block number
-		currentTxIndex int64 // index current block tx index
-	)
-
-	currentBlock = task.LatestBlock
+// handleTask runs one pass of a task, it returns whether blocks are left to
+// index right away.
+func (l *BitcoinListener) handleTask(task models.SyncTask) (bool, error) {
+	currentBlock := task.LatestBlock // index current block number
 	if currentBlock < task.StartBlock {
 		currentBlock = task.StartBlock
 	}
-	currentTxIndex = task.LatestTx
-
-	for {
-		if l.latestBlock <= currentBlock {
-			//time.Sleep(time.Second * time.Duration(l.config.BlockInterval))
-			continue
-		}
-		if currentTxIndex == 0 {
-			currentBlock++
-		} else {
-			currentTxIndex++
+	// a block partly indexed goes on after its latest tx
+	from, txIndex := currentBlock+1, int64(0)
+	if task.LatestTx > 0 {
+		from, txIndex = currentBlock, task.LatestTx+1
+	}
+	if task.EndBlock > 0 && from > task.EndBlock {
+		err := checkpoint(l.db, task, map[string]interface{}{"status": enums.TaskStatusDone})
+		if errors.Is(err, errTaskReset) {
+			// the next pass reads the task again
+			return true, nil
 		}
-
-		for i := currentBlock; i <= l.latestBlock; i++ {
-			l.logger.Infof("start sync task, task id: %d, current block: %d, current tx index: %d", task.Id, currentBlock, currentTxIndex)
-			txResults, blockHeader, err := l.ParseBlock(i, currentTxIndex)
-			if err != nil {
-				if errors.Is(err, ErrTargetConfirmations) {
-					l.logger.Errorf("parse block err: %s", err)
-					//time.Sleep(time.Second * time.Duration(l.config.BlockInterval))
-				} else {
-					l.logger.Errorf("parse block unknown err: %s", err)
-				}
-				if currentTxIndex == 0 {
-					currentBlock = i - 1
-				} else {
-					currentBlock = i
-					currentTxIndex--
-				}
-				break
-			}
-			if len(txResults) > 0 {
-				currentBlock, currentTxIndex, err = l.HandleResults(txResults, task, blockHeader.Timestamp, i)
-				if err != nil {
-					l.logger.Errorf("handle results err: %s", err)
-					//bis.log.Errorw("failed to handle results", "error", err,
-					//	"currentBlock", currentBlock, "currentTxIndex", currentTxIndex, "latestBlock", latestBlock)
-					rollback := true
-					// not duplicated key, rollback index
-					if pgErr, ok := err.(*pgconn.PgError); ok {
-						// 23505 duplicate key value violates unique constraint , continue
-						if pgErr.Code == "23505" {
-							rollback = false
-						}
-					}
-
-					if rollback {
-						if currentTxIndex == 0 {
-							currentBlock = i - 1
-						} else {
-							currentBlock = i
-							currentTxIndex--
-						}
-						break
-					}
-				}
-			}
-			currentBlock = i
-			currentTxIndex = 0
-			task.LatestBlock = currentBlock
-			task.LatestTx = currentTxIndex
-			if err := l.db.Save(&task).Error; err != nil {
-				l.logger.Errorf("save task err: %s", err)
-				//bis.log.Errorw("failed to save bitcoin index block", "error", err, "currentBlock", i,
-				//	"currentTxIndex", currentTxIndex, "latestBlock", latestBlock)
-				// rollback
-				currentBlock = i - 1
-				break
-			}
-			//l.logger.Infof("bitcoin indexer parsed currentBlock, i)
-			//bis.log.Infow("bitcoin indexer parsed", "currentBlock", i,
-			//	"currentTxIndex", currentTxIndex, "latestBlock", latestBlock)
-			//time.Sleep(time.Millisecond * time.Duration(l.config.BlockInterval))
+		if err != nil {
+			return false, err
 		}
+		l.logger.Infof("task done, task id: %d, end block: %d", task.Id, task.EndBlock)
+		return false, nil
 	}
-	return nil
+	to := l.getLatestBlock()
+	if task.EndBlock > 0 && to > task.EndBlock {
+		to = task.EndBlock
+	}
+	if to < from {
+		return false, nil
+	}
+	if to >= from+taskPassBlocks {
+		to = from + taskPassBlocks - 1
+	}
+	l.logger.Infof("handle task, task id: %d, from: %d, to: %d", task.Id, from, to)
+	err := l.indexBlocks(&task, from, to, txIndex)
+	if errors.Is(err, errTaskReset) {
+		// the blocks may be orphaned, they are indexed again from the reset block
+		l.logger.Infof("task reset by a rollback, task id: %d", task.Id)
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "index blocks from: %d", from)
+	}
+	return true, nil
 }
 
 func (l *BitcoinListener) ParseBlock(height int64, txIndex int64) ([]*types.BitcoinTxParseResult, *wire.BlockHeader, error) {
@@ -217,15 +252,29 @@ func (l *BitcoinListener) ParseBlock(height int64, txIndex int64) ([]*types.Bitc
 		return nil, nil, err
 	}
 
+	withdraws, err := l.pendingWithdraws()
+	if err != nil {
+		return nil, nil, err
+	}
+	blockHash := blockResult.BlockHash()
 	blockParsedResult := make([]*types.BitcoinTxParseResult, 0)
 	for k, v := range blockResult.Transactions {
 		if int64(k) < txIndex {
 			continue
 		}
+		// the change of a withdrawal pays the listen address, it is no deposit
+		if withdraws[v.TxHash().String()] {
+			blockParsedResult = append(blockParsedResult, &types.BitcoinTxParseResult{
+				TxID:   v.TxHash().String(),
+				TxType: TxTypeWithdraw,
+				Index:  int64(k),
+			})
+			continue
+		}
 
 		//b.logger.Debugw("parse block", "k", k, "height", height, "txIndex", txIndex, "tx", v.TxHash().String())
 
-		parseTxs, err := l.parseTx(v, k)
+		parseTxs, err := l.parseTx(v, k, &blockHash)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -249,11 +298,15 @@ func (l *BitcoinListener) getBlockByHeight(height int64) (*wire.MsgBlock, error)
 	}
 	return msgBlock, nil
 }
-func (l *BitcoinListener) parseTx(txResult *wire.MsgTx, index int) (*types.BitcoinTxParseResult, error) {
-	listenAddress := false
+
+// parseTx parses a deposit of a tx mined in blockHash, nil for a mempool tx.
+func (l *BitcoinListener) parseTx(txResult *wire.MsgTx, index int, blockHash *chainhash.Hash) (*types.BitcoinTxParseResult, error) {
+	listenAddress := ""
 	var totalValue int64
 	tos := make([]types.BitcoinTo, 0)
-	for _, v := range txResult.TxOut {
+	// outputs paying the listen address
+	listen := make([]bool, len(txResult.TxOut))
+	for k, v := range txResult.TxOut {
 		pkAddress, err := l.parseAddress(v.PkScript)
 		if err != nil {
 			if errors.Is(err, ErrParsePkScript) {
@@ -281,22 +334,16 @@ func (l *BitcoinListener) parseTx(txResult *wire.MsgTx, index int) (*types.Bitco
 			tos = append(tos, parseTo)
 		}
 
-		var chainParams *chaincfg.Params
-		if l.conf.Mainnet {
-			chainParams = &chaincfg.MainNetParams
-		} else {
-			chainParams = &chaincfg.TestNet3Params
-		}
-		_listenAddress, err := btcutil.DecodeAddress(l.conf.ListenAddress, chainParams)
-
-		// if pk address eq dest listened address, after parse from address by vin prev tx
-		if pkAddress == _listenAddress.EncodeAddress() {
-			listenAddress = true
+		// if pk address is a routed listen address, after parse from address by vin prev tx,
+		// the first routed address paid by the tx takes the deposit
+		if _, ok := l.routes[pkAddress]; ok && (listenAddress == "" || listenAddress == pkAddress) {
+			listenAddress = pkAddress
 			totalValue += v.Value
+			listen[k] = true
 		}
 	}
-	if listenAddress {
-		fromAddress, err := l.parseFromAddress(txResult)
+	if listenAddress != "" {
+		fromAddress, err := l.parseFromAddress(txResult, blockHash)
 		if err != nil {
 			return nil, fmt.Errorf("vin parse err:%w", err)
 		}
@@ -308,30 +355,52 @@ func (l *BitcoinListener) parseTx(txResult *wire.MsgTx, index int) (*types.Bitco
 			//	"listenAddress", b.listenAddress.EncodeAddress())
 			return nil, nil
 		}
-
-		var chainParams *chaincfg.Params
-		if l.conf.Mainnet {
-			chainParams = &chaincfg.MainNetParams
-		} else {
-			chainParams = &chaincfg.TestNet3Params
-		}
-		_listenAddress, err := btcutil.DecodeAddress(l.conf.ListenAddress, chainParams)
-		if err != nil {
-			return nil, err
-		}
-		return &types.BitcoinTxParseResult{
+		parseResult := &types.BitcoinTxParseResult{
 			TxID:   txResult.TxHash().String(),
 			TxType: TxTypeTransfer,
 			Index:  int64(index),
 			Value:  totalValue,
 			From:   fromAddress,
-			To:     _listenAddress.EncodeAddress(),
+			To:     listenAddress,
 			Tos:    tos,
-		}, nil
+		}
+		// ord indexes the mined txs only
+		if l.conf.Tokens && blockHash != nil {
+			err = l.parseToken(parseResult, txResult, listen)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return parseResult, nil
 	}
 	return nil, nil
 }
 
+// parseToken records the runes or the inscriptions deposited next to the btc
+// value, a token deposit ord did not verify gets no message.
+func (l *BitcoinListener) parseToken(parseResult *types.BitcoinTxParseResult, txResult *wire.MsgTx, listen []bool) error {
+	token, err := ord.ParseDeposit(l.ord, txResult, listen)
+	if err != nil {
+		return fmt.Errorf("parse token err:%w", err)
+	}
+	if token == nil {
+		return nil
+	}
+	switch token.Type {
+	case types.BitcoinTokenTypeBrc20:
+		parseResult.TxType = TxTypeBrc20Transfer
+	case types.BitcoinTokenTypeRunes:
+		parseResult.TxType = TxTypeRunesTransfer
+	case types.BitcoinTokenTypeInscription:
+		parseResult.TxType = TxTypeInscription
+	}
+	parseResult.TokenType = token.Type
+	parseResult.TokenId = token.Id
+	parseResult.TokenAmount = token.Amount.String()
+	parseResult.TokenVerified = token.Verified
+	return nil
+}
+
 func (l *BitcoinListener) parseAddress(pkScript []byte) (string, error) {
 	pk, err := txscript.ParsePkScript(pkScript)
 	if err != nil {
@@ -347,14 +416,7 @@ func (l *BitcoinListener) parseAddress(pkScript []byte) (string, error) {
 	}
 
 	//  encodes the script into an address for the given chain.
-
-	var chainParams *chaincfg.Params
-	if l.conf.Mainnet {
-		chainParams = &chaincfg.MainNetParams
-	} else {
-		chainParams = &chaincfg.TestNet3Params
-	}
-	pkAddress, err := pk.Address(chainParams)
+	pkAddress, err := pk.Address(l.conf.BitcoinParams())
 	if err != nil {
 		return "", fmt.Errorf("PKScript to address err:%w", err)
 	}
@@ -369,18 +431,12 @@ func (l *BitcoinListener) parseNullData(pkScript []byte) (string, error) {
 	return hex.EncodeToString(pkScript[1:]), nil
 }
 
-func (l *BitcoinListener) parseFromAddress(txResult *wire.MsgTx) (fromAddress []types.BitcoinFrom, err error) {
+func (l *BitcoinListener) parseFromAddress(txResult *wire.MsgTx, blockHash *chainhash.Hash) (fromAddress []types.BitcoinFrom, err error) {
 	for _, vin := range txResult.TxIn {
-		// get prev tx hash
-		prevTxID := vin.PreviousOutPoint.Hash
-		vinResult, err := l.rpc.GetRawTransaction(&prevTxID)
+		vinPKScript, err := l.prevouts.PkScript(vin.PreviousOutPoint, blockHash)
 		if err != nil {
-			return nil, fmt.Errorf("vin get raw transaction err:%w", err)
-		}
-		if len(vinResult.MsgTx().TxOut) == 0 {
-			return nil, fmt.Errorf("vin txOut is null")
+			return nil, fmt.Errorf("vin resolve prevout err:%w", err)
 		}
-		vinPKScript := vinResult.MsgTx().TxOut[vin.PreviousOutPoint.Index].PkScript
 		//  script to address
 		vinPkAddress, err := l.parseAddress(vinPKScript)
 		if err != nil {
@@ -399,38 +455,37 @@ func (l *BitcoinListener) parseFromAddress(txResult *wire.MsgTx) (fromAddress []
 	return fromAddress, nil
 }
 
+// HandleResults saves the deposits and confirms the withdrawals of a block in tx.
 func (l *BitcoinListener) HandleResults(
+	tx *gorm.DB,
 	txResults []*types.BitcoinTxParseResult,
-	syncTask models.SyncTask,
 	btcBlockTime time.Time,
 	currentBlock int64,
-) (int64, int64, error) {
+) error {
 	for _, v := range txResults {
+		if v.TxType == TxTypeWithdraw {
+			err := l.confirmWithdraw(tx, v, currentBlock)
+			if err != nil {
+				return err
+			}
+			continue
+		}
 		// if from is listen address, skip
 		if l.ToInFroms(v.From, v.To) {
-			//bis.log.Infow("current transaction from is listen address", "currentBlock", currentBlock, "currentTxIndex", v.Index, "data", v)
 			continue
 		}
-
-		syncTask.LatestBlock = currentBlock
-		syncTask.LatestTx = v.Index
-		// write db
 		err := l.SaveParsedResult(
+			tx,
 			v,
 			currentBlock,
 			models.DepositB2TxStatusPending,
 			btcBlockTime,
-			syncTask,
 		)
 		if err != nil {
-			//bis.log.Errorw("failed to save bitcoin index tx", "error", err,
-			//	"data", v)
-			return currentBlock, v.Index, err
+			return err
 		}
-		//bis.log.Infow("save bitcoin index tx success", "currentBlock", currentBlock, "currentTxIndex", v.Index, "data", v)
-		time.Sleep(time.Second * 2)
 	}
-	return currentBlock, 0, nil
+	return nil
 }
 
 func (l *BitcoinListener) ToInFroms(a []types.BitcoinFrom, s string) bool {
@@ -442,132 +497,162 @@ func (l *BitcoinListener) ToInFroms(a []types.BitcoinFrom, s string) bool {
 	return false
 }
 
-func (l *BitcoinListener) SaveParsedResult(
+// newDeposit builds the deposit record of a parsed tx, the recipient of the
+// deposit payload takes the deposit instead of the aa address.
+func (l *BitcoinListener) newDeposit(
 	parseResult *types.BitcoinTxParseResult,
 	btcBlockNumber int64,
 	b2TxStatus int,
 	btcBlockTime time.Time,
-	syncTask models.SyncTask,
-) error {
-	// write db
-	err := l.db.Transaction(func(tx *gorm.DB) error {
-		if len(parseResult.From) == 0 {
-			return fmt.Errorf("parse result from empty")
-		}
+) (models.Deposit, error) {
+	if len(parseResult.From) == 0 {
+		return models.Deposit{}, fmt.Errorf("parse result from empty")
+	}
 
-		if len(parseResult.To) == 0 {
-			return fmt.Errorf("parse result to empty")
-		}
+	if len(parseResult.To) == 0 {
+		return models.Deposit{}, fmt.Errorf("parse result to empty")
+	}
 
-		if len(parseResult.Tos) == 0 {
-			return fmt.Errorf("parse result to empty")
-		}
+	if len(parseResult.Tos) == 0 {
+		return models.Deposit{}, fmt.Errorf("parse result to empty")
+	}
 
-		//bis.log.Infow("parseResult:", "result", parseResult)
-		existsEvmAddressData := false // The evm address is processed only if it exists. Otherwise, aa is used
-		parsedEvmAddress := ""        // evm address
-		for _, v := range parseResult.Tos {
-			// only handle first null data
-			if existsEvmAddressData {
-				continue
-			}
-			if v.Type == types.BitcoinToTypeNullData {
-				decodeNullData, err := hex.DecodeString(v.NullData)
-				if err != nil {
-					//bis.log.Errorw("decode null data err", "error", err, "nullData", v.NullData)
-					continue
-				}
-				evmAddress := bytes.TrimSpace(decodeNullData[1:])
-				if common.IsHexAddress(string(evmAddress)) {
-					existsEvmAddressData = true
-					parsedEvmAddress = string(evmAddress)
-					for k := range parseResult.From {
-						parseResult.From[k].Type = types.BitcoinFromTypeEvm
-						parseResult.From[k].EvmAddress = parsedEvmAddress
-					}
-				}
-			}
+	// the recipient of the payload takes the deposit instead of the aa address
+	_, existsEvmAddressData, parsedEvmAddress, err := types.ParseEvmAddressFromNullData(parseResult)
+	if err != nil {
+		return models.Deposit{}, err
+	}
+	froms, err := json.Marshal(parseResult.From)
+	if err != nil {
+		return models.Deposit{}, err
+	}
+	tos, err := json.Marshal(parseResult.Tos)
+	if err != nil {
+		return models.Deposit{}, err
+	}
+	deposit := models.Deposit{
+		ChainId:        l.conf.ChainId,
+		BtcBlockNumber: btcBlockNumber,
+		BtcTxIndex:     parseResult.Index,
+		BtcTxHash:      parseResult.TxID,
+		BtcFrom:        parseResult.From[0].Address,
+		BtcTos:         string(tos),
+		BtcTo:          parseResult.To,
+		BtcValue:       parseResult.Value,
+		BtcTxType:      parseResult.TokenType,
+		TokenId:        parseResult.TokenId,
+		TokenAmount:    parseResult.TokenAmount,
+		BtcFroms:       string(froms),
+		B2TxStatus:     b2TxStatus,
+		BtcBlockTime:   btcBlockTime,
+		B2TxRetry:      0,
+		ListenerStatus: models.ListenerStatusSuccess,
+		CallbackStatus: models.CallbackStatusPending,
+	}
+	if existsEvmAddressData {
+		deposit.BtcFromEvmAddress = parsedEvmAddress
+	}
+	// refunded instead, with Refund
+	if parseResult.TokenType != types.BitcoinTokenTypeBtc && !parseResult.TokenVerified {
+		deposit.Status = enums.DepositStatusInvalid
+	}
+	return deposit, nil
+}
+
+// minedStatus returns the status of a deposit waiting for the listener once
+// its tx is indexed in a block, false if the deposit is not waiting.
+func minedStatus(status enums.DepositStatus) (enums.DepositStatus, bool) {
+	switch status {
+	case enums.DepositStatusUnconfirmed, enums.DepositStatusReplaced, enums.DepositStatusInvalid:
+		return enums.DepositStatusPending, true
+	case enums.DepositStatusReview:
+		// a delivered deposit is valid again
+		return enums.DepositStatusValid, true
+	}
+	return status, false
+}
+
+// SaveParsedResult saves the deposit of a tx indexed in a block in tx.
+func (l *BitcoinListener) SaveParsedResult(
+	tx *gorm.DB,
+	parseResult *types.BitcoinTxParseResult,
+	btcBlockNumber int64,
+	b2TxStatus int,
+	btcBlockTime time.Time,
+) error {
+	record, err := l.newDeposit(parseResult, btcBlockNumber, b2TxStatus, btcBlockTime)
+	if err != nil {
+		return err
+	}
+	// if existed, update deposit record
+	var deposit models.Deposit
+	err = tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&deposit,
+			fmt.Sprintf("%s = ? AND %s = ?", models.Deposit{}.Column().ChainId, models.Deposit{}.Column().BtcTxHash),
+			l.conf.ChainId, parseResult.TxID).Error
+	status, mined := minedStatus(deposit.Status)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
 		}
-		froms, err := json.Marshal(parseResult.From)
+		err = tx.Create(&record).Error
 		if err != nil {
+			//bis.log.Errorw("failed to save tx parsed result", "error", err)
 			return err
 		}
-		tos, err := json.Marshal(parseResult.Tos)
+	} else if deposit.ListenerStatus == models.ListenerStatusPending && mined {
+		// seen in the mempool or orphaned by a reorg, the deposit gets its
+		// message once the tx is indexed after SafeBlockNumber confirmations,
+		// the tokens of a mempool tx are only known once it is mined
+		if record.Status == enums.DepositStatusInvalid {
+			status = enums.DepositStatusInvalid
+		}
+		err = tx.Model(&models.Deposit{}).Where("id = ?", deposit.Id).Updates(map[string]interface{}{
+			models.Deposit{}.Column().BtcBlockNumber: btcBlockNumber,
+			models.Deposit{}.Column().BtcTxIndex:     parseResult.Index,
+			models.Deposit{}.Column().BtcBlockTime:   btcBlockTime,
+			models.Deposit{}.Column().ListenerStatus: models.ListenerStatusSuccess,
+			models.Deposit{}.Column().BtcTxType:      record.BtcTxType,
+			models.Deposit{}.Column().TokenId:        record.TokenId,
+			models.Deposit{}.Column().TokenAmount:    record.TokenAmount,
+			"status":                                 status,
+		}).Error
 		if err != nil {
 			return err
 		}
+		l.logger.Infof("deposit %d mined at block: %d, status: %d", deposit.Id, btcBlockNumber, status)
+	} else if deposit.CallbackStatus == models.CallbackStatusSuccess &&
+		deposit.ListenerStatus == models.ListenerStatusPending {
+		if deposit.BtcValue != parseResult.Value || deposit.BtcFrom != parseResult.From[0].Address {
+			return fmt.Errorf("invalid parameter")
+		}
 		// if existed, update deposit record
-		var deposit models.Deposit
-		err = tx.
-			Set("gorm:query_option", "FOR UPDATE").
-			First(&deposit,
-				fmt.Sprintf("%s = ?", models.Deposit{}.Column().BtcTxHash),
-				parseResult.TxID).Error
+		updateFields := map[string]interface{}{
+			models.Deposit{}.Column().BtcBlockNumber: btcBlockNumber,
+			models.Deposit{}.Column().BtcTxIndex:     parseResult.Index,
+			models.Deposit{}.Column().BtcFroms:       record.BtcFroms,
+			models.Deposit{}.Column().BtcTos:         record.BtcTos,
+			models.Deposit{}.Column().BtcBlockTime:   btcBlockTime,
+			models.Deposit{}.Column().ListenerStatus: models.ListenerStatusSuccess,
+		}
+		if record.BtcFromEvmAddress != "" {
+			updateFields[models.Deposit{}.Column().BtcFromEvmAddress] = record.BtcFromEvmAddress
+		}
+		err = tx.Model(&models.Deposit{}).Where("id = ?", deposit.Id).Updates(updateFields).Error
 		if err != nil {
-			if !errors.Is(err, gorm.ErrRecordNotFound) {
-				return err
-			}
-			deposit := models.Deposit{
-				BtcBlockNumber: btcBlockNumber,
-				BtcTxIndex:     parseResult.Index,
-				BtcTxHash:      parseResult.TxID,
-				BtcFrom:        parseResult.From[0].Address,
-				BtcTos:         string(tos),
-				BtcTo:          parseResult.To,
-				BtcValue:       parseResult.Value,
-				BtcFroms:       string(froms),
-				B2TxStatus:     b2TxStatus,
-				BtcBlockTime:   btcBlockTime,
-				B2TxRetry:      0,
-				ListenerStatus: models.ListenerStatusSuccess,
-				CallbackStatus: models.CallbackStatusPending,
-			}
-			if existsEvmAddressData {
-				deposit.BtcFromEvmAddress = parsedEvmAddress
-			}
-			err = tx.Create(&deposit).Error
-			if err != nil {
-				//bis.log.Errorw("failed to save tx parsed result", "error", err)
-				return err
-			}
-		} else if deposit.CallbackStatus == models.CallbackStatusSuccess &&
-			deposit.ListenerStatus == models.ListenerStatusPending {
-			if deposit.BtcValue != parseResult.Value || deposit.BtcFrom != parseResult.From[0].Address {
-				return fmt.Errorf("invalid parameter")
-			}
-			// if existed, update deposit record
-			updateFields := map[string]interface{}{
-				models.Deposit{}.Column().BtcBlockNumber: btcBlockNumber,
-				models.Deposit{}.Column().BtcTxIndex:     parseResult.Index,
-				models.Deposit{}.Column().BtcFroms:       string(froms),
-				models.Deposit{}.Column().BtcTos:         string(tos),
-				models.Deposit{}.Column().BtcBlockTime:   btcBlockTime,
-				models.Deposit{}.Column().ListenerStatus: models.ListenerStatusSuccess,
-			}
-			if existsEvmAddressData {
-				updateFields[models.Deposit{}.Column().BtcFromEvmAddress] = parsedEvmAddress
-			}
-			err = tx.Model(&models.Deposit{}).Where("id = ?", deposit.Id).Updates(updateFields).Error
-			if err != nil {
-				//bis.log.Errorw("failed to update tx parsed result", "error", err)
-				return err
-			}
-		}
-
-		if err := tx.Save(&syncTask).Error; err != nil {
-			//bis.log.Errorw("failed to save bitcoin tx index", "error", err)
+			//bis.log.Errorw("failed to update tx parsed result", "error", err)
 			return err
 		}
-		return nil
-	})
-	return err
+	}
+	return nil
 }
 
 func (l *BitcoinListener) handDeposit() {
 	duration := time.Millisecond * time.Duration(l.conf.BlockInterval) * 10
 	for {
 		var list []models.Deposit
-		err := l.db.Where("status=?", enums.DepositStatusPending).Find(&list).Error
+		err := l.db.Where("chain_id=? AND status=?", l.conf.ChainId, enums.DepositStatusPending).Find(&list).Error
 		if err != nil {
 			l.logger.Errorf("[Handler.handDeposit] err: %s", err)
 			time.Sleep(duration)
@@ -609,8 +694,30 @@ func (l *BitcoinListener) GetDepositAddress(deposit models.Deposit) (string, err
 }
 
 func (l *BitcoinListener) handleMessage(deposit models.Deposit) error {
-	toChainId := l.conf.ToChainId
-	toContractAddress := l.conf.ToContractAddress
+	route, ok := l.routes[deposit.BtcTo]
+	if !ok {
+		l.logger.Errorf("[Handler.handleMessage] route not found, deposit ID: %d, btc to: %s", deposit.Id, deposit.BtcTo)
+		return l.db.Model(models.Deposit{}).
+			Where("id=?", deposit.Id).
+			Update("status", enums.DepositStatusInvalid).Error
+	}
+	var tos []types.BitcoinTo
+	err := json.Unmarshal([]byte(deposit.BtcTos), &tos)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	payload := types.FindDepositPayload(tos)
+	toChainId, ToMessageBridge, toContractAddress, ok := payload.Destination(route.ToChainId, route.ToMessageBridge, route.ToContractAddress)
+	if !ok {
+		l.logger.Errorf("[Handler.handleMessage] payload targets chain %d without contract, deposit ID: %d", payload.ToChainId, deposit.Id)
+		return l.db.Model(models.Deposit{}).
+			Where("id=?", deposit.Id).
+			Update("status", enums.DepositStatusInvalid).Error
+	}
+	var callDataHash *common.Hash
+	if payload != nil {
+		callDataHash = payload.CallDataHash
+	}
 
 	depositAddress, err := l.GetDepositAddress(deposit)
 	if err != nil && err.Error() != "AAGetBTCAccount not found" {
@@ -623,12 +730,28 @@ func (l *BitcoinListener) handleMessage(deposit models.Deposit) error {
 		return nil
 	}
 
-	data := message.EncodeSendData(deposit.BtcTxHash, deposit.BtcFrom, depositAddress, decimal.New(deposit.BtcValue, 0))
-	var ToMessageBridge string
+	data, err := message.EncodeDepositData(deposit.BtcTxHash, deposit.BtcFrom, depositAddress, decimal.New(deposit.BtcValue, 0), callDataHash)
+	if err != nil {
+		return err
+	}
+	if deposit.BtcTxType != types.BitcoinTokenTypeBtc {
+		tokenAmount, ok := new(big.Int).SetString(deposit.TokenAmount, 10)
+		if !ok {
+			l.logger.Errorf("[Handler.handleMessage] invalid token amount: %s, deposit ID: %d", deposit.TokenAmount, deposit.Id)
+			return l.db.Model(models.Deposit{}).
+				Where("id=?", deposit.Id).
+				Update("status", enums.DepositStatusInvalid).Error
+		}
+		data, err = message.EncodeTokenData(deposit.BtcTxHash, deposit.BtcFrom, depositAddress, decimal.New(deposit.BtcValue, 0),
+			deposit.BtcTxType, deposit.TokenId, tokenAmount, callDataHash)
+		if err != nil {
+			return err
+		}
+	}
 	messageBridge, ok := l.bridges[toChainId]
-	if ok {
+	if ToMessageBridge == "" && ok {
 		ToMessageBridge = messageBridge
-	} else {
+	} else if ToMessageBridge == "" {
 		err = l.db.Model(models.Deposit{}).
 			Where("id=?", deposit.Id).
 			Update("status", enums.DepositStatusInvalid).Error
@@ -636,6 +759,7 @@ func (l *BitcoinListener) handleMessage(deposit models.Deposit) error {
 	}
 	msg := models.Message{
 		ChainId:           l.conf.ChainId,
+		ContractAddress:   deposit.BtcTo,
 		Type:              enums.MessageTypeCall,
 		FromChainId:       l.conf.ChainId,
 		FromSender:        common.HexToAddress("0x0").Hex(),
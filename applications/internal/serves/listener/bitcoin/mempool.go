@@ -0,0 +1,163 @@
+package bitcoin
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"bsquared.network/message-sharing-applications/internal/types"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// watchMempool records the deposits seen in the mempool as unconfirmed. They
+// wait for the block indexer, which promotes them through SaveParsedResult
+// after SafeBlockNumber confirmations, only then a message is created.
+func (l *BitcoinListener) watchMempool() {
+	duration := time.Millisecond * time.Duration(l.conf.BlockInterval)
+	// txs of the mempool already parsed
+	seen := make(map[chainhash.Hash]bool)
+	// outpoints spent by the unconfirmed deposits
+	spends := make(map[wire.OutPoint]chainhash.Hash)
+	for {
+		hashes, err := l.rpc.GetRawMempool()
+		if err != nil {
+			l.logger.Errorf("[Handler.watchMempool] get raw mempool err: %s", err)
+			time.Sleep(duration)
+			continue
+		}
+		mempool := make(map[chainhash.Hash]bool, len(hashes))
+		for _, hash := range hashes {
+			mempool[*hash] = true
+			if seen[*hash] {
+				continue
+			}
+			err = l.observeTx(hash, spends)
+			if err != nil {
+				l.logger.Errorf("[Handler.watchMempool] observe tx err: %s, btc tx hash: %s", err, hash)
+				continue
+			}
+			seen[*hash] = true
+		}
+		for hash := range seen {
+			if !mempool[hash] {
+				delete(seen, hash)
+			}
+		}
+		for outPoint, hash := range spends {
+			if !mempool[hash] {
+				delete(spends, outPoint)
+			}
+		}
+		err = l.checkUnconfirmed(mempool)
+		if err != nil {
+			l.logger.Errorf("[Handler.watchMempool] check unconfirmed err: %s", err)
+		}
+		time.Sleep(duration)
+	}
+}
+
+// observeTx records a mempool tx paying a routed address as an unconfirmed
+// deposit, the unconfirmed deposits spending the same outpoints were replaced
+// by fee or double spent.
+func (l *BitcoinListener) observeTx(hash *chainhash.Hash, spends map[wire.OutPoint]chainhash.Hash) error {
+	tx, err := l.rpc.GetRawTransaction(hash)
+	if err != nil {
+		// left the mempool since it was listed
+		if errors.Is(err, rpcpool.ErrTxNotFound) {
+			return nil
+		}
+		return err
+	}
+	parseResult, err := l.parseTx(tx.MsgTx(), 0, nil)
+	if err != nil {
+		return err
+	}
+	if parseResult == nil || l.ToInFroms(parseResult.From, parseResult.To) {
+		return nil
+	}
+	for _, vin := range tx.MsgTx().TxIn {
+		if other, ok := spends[vin.PreviousOutPoint]; ok && other != *hash {
+			err = l.replaceDeposit(other.String(), hash.String())
+			if err != nil {
+				return err
+			}
+		}
+		spends[vin.PreviousOutPoint] = *hash
+	}
+	return l.saveUnconfirmed(parseResult)
+}
+
+// saveUnconfirmed creates the unconfirmed deposit of a mempool tx, a deposit
+// replaced before and broadcast again is unconfirmed again.
+func (l *BitcoinListener) saveUnconfirmed(parseResult *types.BitcoinTxParseResult) error {
+	deposit, err := l.newDeposit(parseResult, 0, models.DepositB2TxStatusPending, time.Now())
+	if err != nil {
+		return err
+	}
+	deposit.ListenerStatus = models.ListenerStatusPending
+	deposit.Status = enums.DepositStatusUnconfirmed
+	result := l.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deposit)
+	if result.Error != nil {
+		return errors.WithStack(result.Error)
+	}
+	if result.RowsAffected > 0 {
+		l.logger.Infof("unconfirmed deposit %d, btc tx hash: %s, value: %d", deposit.Id, deposit.BtcTxHash, deposit.BtcValue)
+		return nil
+	}
+	err = l.db.Model(models.Deposit{}).
+		Where("chain_id=? AND btc_tx_hash=? AND listener_status=? AND status=?",
+			l.conf.ChainId, deposit.BtcTxHash, models.ListenerStatusPending, enums.DepositStatusReplaced).
+		Update("status", enums.DepositStatusUnconfirmed).Error
+	return errors.WithStack(err)
+}
+
+// replaceDeposit marks an unconfirmed deposit replaced by another mempool tx.
+func (l *BitcoinListener) replaceDeposit(txHash string, by string) error {
+	result := l.db.Model(models.Deposit{}).
+		Where("chain_id=? AND btc_tx_hash=? AND listener_status=? AND status=?",
+			l.conf.ChainId, txHash, models.ListenerStatusPending, enums.DepositStatusUnconfirmed).
+		Update("status", enums.DepositStatusReplaced)
+	if result.Error != nil {
+		return errors.WithStack(result.Error)
+	}
+	if result.RowsAffected > 0 {
+		l.logger.Warnf("unconfirmed deposit replaced, btc tx hash: %s, by: %s", txHash, by)
+	}
+	return nil
+}
+
+// checkUnconfirmed marks the unconfirmed deposits that left the mempool without
+// being mined replaced, a conflicting tx was mined or the tx was evicted. A
+// replaced deposit mined later on is still promoted by SaveParsedResult.
+func (l *BitcoinListener) checkUnconfirmed(mempool map[chainhash.Hash]bool) error {
+	var deposits []models.Deposit
+	err := l.db.Where("chain_id=? AND listener_status=? AND status=?", l.conf.ChainId, models.ListenerStatusPending, enums.DepositStatusUnconfirmed).
+		Find(&deposits).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, deposit := range deposits {
+		hash, err := chainhash.NewHashFromStr(deposit.BtcTxHash)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if mempool[*hash] {
+			continue
+		}
+		// still known to the node, mined and waiting for the indexer
+		_, err = l.rpc.GetRawTransaction(hash)
+		if err != nil {
+			if !errors.Is(err, rpcpool.ErrTxNotFound) {
+				return err
+			}
+			err = l.replaceDeposit(deposit.BtcTxHash, "unknown")
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package bitcoin
+
+import (
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/types"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// taskPassBlocks is the number of blocks a task indexes before the pending
+// tasks are listed again.
+const taskPassBlocks = 100
+
+var errTaskReset = errors.New("task reset by a rollback")
+
+// parsedBlock is a block parsed ahead of its commit.
+type parsedBlock struct {
+	height  int64
+	results []*types.BitcoinTxParseResult
+	header  *wire.BlockHeader
+	err     error
+}
+
+func (l *BitcoinListener) prefetch() int {
+	if l.conf.Prefetch > 0 {
+		return l.conf.Prefetch
+	}
+	return 1
+}
+
+// prefetchBlocks parses the blocks from..to with Prefetch blocks in flight,
+// the blocks come out in height order. Closing done stops the fetching, the
+// blocks in flight are dropped.
+func (l *BitcoinListener) prefetchBlocks(from int64, to int64, txIndex int64, done <-chan struct{}) <-chan chan *parsedBlock {
+	// the consumer waits for one block, the others are buffered
+	pending := make(chan chan *parsedBlock, l.prefetch()-1)
+	go func() {
+		defer close(pending)
+		for i := from; i <= to; i++ {
+			block := make(chan *parsedBlock, 1)
+			select {
+			case pending <- block:
+			case <-done:
+				return
+			}
+			index := int64(0)
+			if i == from {
+				index = txIndex
+			}
+			go func(height int64, index int64) {
+				results, header, err := l.ParseBlock(height, index)
+				block <- &parsedBlock{height: height, results: results, header: header, err: err}
+			}(i, index)
+		}
+	}()
+	return pending
+}
+
+// indexBlocks indexes the blocks from..to of a task, the first one from
+// txIndex. The blocks are fetched and parsed in parallel and committed one by
+// one in height order, it stops at the first error or reorg.
+func (l *BitcoinListener) indexBlocks(task *models.SyncTask, from int64, to int64, txIndex int64) error {
+	done := make(chan struct{})
+	defer close(done)
+	for block := range l.prefetchBlocks(from, to, txIndex, done) {
+		parsed := <-block
+		if parsed.err != nil {
+			if errors.Is(parsed.err, ErrTargetConfirmations) {
+				return errors.Wrapf(parsed.err, "parse block %d", parsed.height)
+			}
+			return errors.Wrapf(parsed.err, "parse block %d unknown err", parsed.height)
+		}
+		fork, err := l.checkReorg(parsed.height, parsed.header)
+		if err != nil {
+			return errors.Wrap(err, "check reorg")
+		}
+		if fork > 0 {
+			l.logger.Infof("reorg detected at block: %d, rollback from block: %d", parsed.height, fork)
+			err = l.rollback(fork)
+			if err != nil {
+				return errors.Wrap(err, "rollback")
+			}
+			task.LatestBlock = fork - 1
+			task.LatestTx = 0
+			return nil
+		}
+		err = l.commitBlock(task, parsed)
+		if err != nil {
+			return errors.Wrapf(err, "commit block %d", parsed.height)
+		}
+	}
+	return nil
+}
+
+// commitBlock saves the results of a block and moves the task checkpoint past
+// it in one db transaction, a failed block is indexed again from the checkpoint.
+// A task moved by a rollback meanwhile fails with errTaskReset, nothing is saved.
+func (l *BitcoinListener) commitBlock(task *models.SyncTask, parsed *parsedBlock) error {
+	l.logger.Infof("commit block, task id: %d, block: %d, results: %d", task.Id, parsed.height, len(parsed.results))
+	next := *task
+	next.LatestBlock = parsed.height
+	next.LatestTx = 0
+	err := l.db.Transaction(func(tx *gorm.DB) error {
+		err := l.HandleResults(tx, parsed.results, parsed.header.Timestamp, parsed.height)
+		if err != nil {
+			return err
+		}
+		err = l.saveBlock(tx, parsed.height, parsed.header)
+		if err != nil {
+			return err
+		}
+		return checkpoint(tx, *task, map[string]interface{}{
+			"latest_block": next.LatestBlock,
+			"latest_tx":    next.LatestTx,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	*task = next
+	return nil
+}
+
+// checkpoint updates the task read at its checkpoint, errTaskReset when a
+// rollback moved the task meanwhile so the stale copy does not overwrite it.
+func checkpoint(tx *gorm.DB, task models.SyncTask, updates map[string]interface{}) error {
+	result := tx.Model(models.SyncTask{}).
+		Where("id=? AND latest_block=? AND latest_tx=?", task.Id, task.LatestBlock, task.LatestTx).
+		Updates(updates)
+	if result.Error != nil {
+		return errors.WithStack(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errTaskReset
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package bitcoin
+
+import (
+	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"fmt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"gorm.io/gorm"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockSource is a bitcoin source serving a chain of blocks, each with one
+// withdrawal tx, the lower blocks are served slower so the prefetched blocks
+// come back out of order.
+type blockSource struct {
+	rpcpool.BtcSource
+	blocks map[chainhash.Hash]*wire.MsgBlock
+	hashes map[int64]chainhash.Hash
+	tip    int64
+
+	mu      sync.Mutex
+	fetched []int64
+}
+
+func newBlockSource(from int64, to int64) *blockSource {
+	s := &blockSource{
+		blocks: make(map[chainhash.Hash]*wire.MsgBlock),
+		hashes: make(map[int64]chainhash.Hash),
+		tip:    to,
+	}
+	prev := chainhash.Hash{}
+	for height := from; height <= to; height++ {
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(height, []byte{0x00, 0x14}))
+		block := wire.NewMsgBlock(wire.NewBlockHeader(2, &prev, &chainhash.Hash{}, 0, uint32(height)))
+		block.AddTransaction(tx)
+		prev = block.BlockHash()
+		s.blocks[prev] = block
+		s.hashes[height] = prev
+	}
+	return s
+}
+
+func (s *blockSource) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	hash, ok := s.hashes[height]
+	if !ok {
+		return nil, fmt.Errorf("block %d not found", height)
+	}
+	return &hash, nil
+}
+
+func (s *blockSource) GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	block := s.blocks[*hash]
+	height := int64(block.Header.Nonce)
+	time.Sleep(time.Duration(s.tip-height) * 5 * time.Millisecond)
+	s.mu.Lock()
+	s.fetched = append(s.fetched, height)
+	s.mu.Unlock()
+	return block, nil
+}
+
+// newPipelineTest returns a listener prefetching 4 blocks of the source, a
+// task at from-1 and a broadcast withdrawal of each block.
+func newPipelineTest(t *testing.T, from int64, to int64) (*BitcoinListener, *gorm.DB, *blockSource, *models.SyncTask) {
+	db := newTestDB(t)
+	source := newBlockSource(from-1, to)
+	l := NewListener(nil, config.Blockchain{ChainId: testChainId, ChainType: enums.ChainTypeUTXO, Prefetch: 4},
+		config.Particle{}, source, db, log.NewLogger("bitcoin-test", 0))
+	for height := from; height <= to; height++ {
+		hash := source.hashes[height]
+		create(t, db, &models.Withdraw{ChainId: testChainId, MessageId: height,
+			BtcTxHash: source.blocks[hash].Transactions[0].TxHash().String(), Status: enums.WithdrawStatusBroadcast})
+	}
+	task := &models.SyncTask{ChainType: enums.ChainTypeUTXO, ChainId: testChainId, LatestBlock: from - 1, Status: enums.TaskStatusPending}
+	create(t, db, task)
+	return l, db, source, task
+}
+
+func TestIndexBlocksOrder(t *testing.T) {
+	const from, to = 100, 109
+	l, db, source, task := newPipelineTest(t, from, to)
+
+	if err := l.indexBlocks(task, from, to, 0); err != nil {
+		t.Fatal(err)
+	}
+	inOrder := true
+	for i := 1; i < len(source.fetched); i++ {
+		inOrder = inOrder && source.fetched[i] > source.fetched[i-1]
+	}
+	if inOrder {
+		t.Fatalf("blocks fetched in order: %v", source.fetched)
+	}
+
+	if task.LatestBlock != to || reload[models.SyncTask](t, db, task.Id).LatestBlock != to {
+		t.Fatalf("task checkpoint: %d", task.LatestBlock)
+	}
+	var blocks []models.SyncBlock
+	if err := db.Where("chain_id=?", testChainId).Order("id").Find(&blocks).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != to-from+1 {
+		t.Fatalf("blocks saved: %d", len(blocks))
+	}
+	for i, block := range blocks {
+		if block.BlockNumber != from+int64(i) || block.BlockHash != source.hashes[block.BlockNumber].String() {
+			t.Fatalf("block %d committed as %d", from+i, block.BlockNumber)
+		}
+	}
+	var withdraws []models.Withdraw
+	if err := db.Order("id").Find(&withdraws).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, withdraw := range withdraws {
+		if withdraw.Status != enums.WithdrawStatusConfirmed || withdraw.BtcBlockNumber != from+int64(i) {
+			t.Fatalf("withdraw of block %d: %d %d", from+i, withdraw.Status, withdraw.BtcBlockNumber)
+		}
+	}
+}
+
+func TestIndexBlocksFailedCommit(t *testing.T) {
+	const from, to, failed = 100, 109, 103
+	l, db, _, task := newPipelineTest(t, from, to)
+	err := db.Exec(fmt.Sprintf("CREATE TRIGGER fail_commit BEFORE INSERT ON sync_blocks WHEN NEW.block_number=%d "+
+		"BEGIN SELECT RAISE(ABORT, 'commit failed'); END", failed)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = l.indexBlocks(task, from, to, 0); err == nil {
+		t.Fatal("indexed past a failed commit")
+	}
+	if task.LatestBlock != failed-1 || reload[models.SyncTask](t, db, task.Id).LatestBlock != failed-1 {
+		t.Fatalf("task checkpoint: %d", task.LatestBlock)
+	}
+	if n := count(t, db, models.SyncBlock{}, "chain_id=? AND block_number>=?", testChainId, failed); n != 0 {
+		t.Fatalf("blocks saved past the failed commit: %d", n)
+	}
+	if n := count(t, db, models.Withdraw{}, "status=?", enums.WithdrawStatusConfirmed); n != failed-from {
+		t.Fatalf("withdraws confirmed: %d", n)
+	}
+
+	// the next pass indexes again from the checkpoint
+	if err = db.Exec("DROP TRIGGER fail_commit").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = l.indexBlocks(task, task.LatestBlock+1, to, 0); err != nil {
+		t.Fatal(err)
+	}
+	if reload[models.SyncTask](t, db, task.Id).LatestBlock != to ||
+		count(t, db, models.Withdraw{}, "status=?", enums.WithdrawStatusConfirmed) != to-from+1 {
+		t.Fatal("blocks not indexed again from the checkpoint")
+	}
+}
@@ -0,0 +1,78 @@
+package bitcoin
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/message"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"gorm.io/gorm"
+	"time"
+)
+
+// handRefund queues the refunds of the mined deposits no message can be built
+// for, the proposer pays them back from the deposit outputs like a withdrawal.
+func (l *BitcoinListener) handRefund() {
+	duration := time.Millisecond * time.Duration(l.conf.BlockInterval) * 10
+	for {
+		var list []models.Deposit
+		err := l.db.Where("chain_id=? AND status=? AND listener_status=?", l.conf.ChainId, enums.DepositStatusInvalid, models.ListenerStatusSuccess).
+			Order("id").Limit(100).Find(&list).Error
+		if err != nil {
+			l.logger.Errorf("[Handler.handRefund] err: %s", err)
+			time.Sleep(duration)
+			continue
+		}
+		for _, deposit := range list {
+			err = l.queueRefund(deposit)
+			if err != nil {
+				l.logger.Errorf("[Handler.handRefund] Deposit ID: %d , err: %s \n", deposit.Id, err)
+			}
+		}
+		time.Sleep(duration)
+	}
+}
+
+// queueRefund creates the message paying the deposit value back to its sender,
+// a call from the chain to itself.
+func (l *BitcoinListener) queueRefund(deposit models.Deposit) error {
+	data, err := message.EncodeWithdrawData(deposit.BtcFrom, deposit.BtcValue)
+	if err != nil {
+		return err
+	}
+	msg := models.Message{
+		ChainId:           l.conf.ChainId,
+		ContractAddress:   deposit.BtcTo,
+		Type:              enums.MessageTypeCall,
+		FromChainId:       l.conf.ChainId,
+		FromSender:        common.HexToAddress("0x0").Hex(),
+		FromMessageBridge: deposit.BtcTo,
+		FromId:            common.HexToHash(deposit.BtcTxHash).Hex(),
+		ToChainId:         l.conf.ChainId,
+		ToMessageBridge:   deposit.BtcTo,
+		ToContractAddress: common.HexToAddress("0x0").Hex(),
+		ToBytes:           hexutil.Encode(data),
+		Signatures:        "{}",
+		Status:            enums.MessageStatusValidating,
+		Blockchain: models.Blockchain{
+			EventId:     deposit.Id,
+			BlockTime:   deposit.BtcBlockTime.Unix(),
+			BlockNumber: deposit.BtcBlockNumber,
+			LogIndex:    deposit.BtcTxIndex,
+			TxHash:      common.HexToHash(deposit.BtcTxHash).Hex(),
+		},
+	}
+	return l.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(models.Deposit{}).Where("id=? AND status=?", deposit.Id, enums.DepositStatusInvalid).
+			Update("status", enums.DepositStatusRefunding)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		l.logger.Infof("[Handler.handRefund] refund queued, deposit ID: %d, btc from: %s, value: %d",
+			deposit.Id, deposit.BtcFrom, deposit.BtcValue)
+		return tx.Create(&msg).Error
+	})
+}
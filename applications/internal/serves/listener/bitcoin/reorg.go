@@ -0,0 +1,168 @@
+package bitcoin
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const defaultReorgWindow = 64
+
+func (l *BitcoinListener) reorgWindow() int64 {
+	if l.conf.ReorgWindow > 0 {
+		return l.conf.ReorgWindow
+	}
+	return defaultReorgWindow
+}
+
+// checkReorg compares the parent of the block at height with the tracked
+// hash and walks back to the first orphaned height, 0 if nothing was orphaned.
+func (l *BitcoinListener) checkReorg(height int64, header *wire.BlockHeader) (int64, error) {
+	var parents []models.SyncBlock
+	err := l.db.Where("chain_id=? AND block_number=?", l.conf.ChainId, height-1).Limit(1).Find(&parents).Error
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if len(parents) == 0 || parents[0].BlockHash == header.PrevBlock.String() {
+		return 0, nil
+	}
+	var blocks []models.SyncBlock
+	err = l.db.Where("chain_id=? AND block_number<?", l.conf.ChainId, height).
+		Order("block_number DESC").Limit(int(l.reorgWindow())).Find(&blocks).Error
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	fork := int64(0)
+	for _, block := range blocks {
+		hash, err := l.rpc.GetBlockHash(block.BlockNumber)
+		if err != nil {
+			return 0, err
+		}
+		if hash.String() == block.BlockHash {
+			return fork, nil
+		}
+		fork = block.BlockNumber
+	}
+	if fork > 0 {
+		l.logger.Errorf("reorg deeper than tracked window, rollback from block: %d", fork)
+	}
+	return fork, nil
+}
+
+// saveBlock tracks the hash of an indexed height inside the reorg window.
+func (l *BitcoinListener) saveBlock(tx *gorm.DB, height int64, header *wire.BlockHeader) error {
+	window := l.getLatestBlock() - l.reorgWindow()
+	if height <= window {
+		return nil
+	}
+	err := tx.Where("chain_id=? AND block_number=?", l.conf.ChainId, height).Delete(&models.SyncBlock{}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.Create(&models.SyncBlock{
+		ChainId:     l.conf.ChainId,
+		BlockNumber: height,
+		BlockHash:   header.BlockHash().String(),
+		ParentHash:  header.PrevBlock.String(),
+	}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.Where("chain_id=? AND block_number<=?", l.conf.ChainId, window).
+		Delete(&models.SyncBlock{}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// rollback marks the deposits of the orphaned blocks invalid and drops their
+// undelivered messages, a deposit whose message was already delivered is
+// flagged for review. Confirmed withdrawals go back to broadcast. The tasks are
+// reset to re-index from the fork, a deposit mined again in the new chain is
+// restored by SaveParsedResult.
+func (l *BitcoinListener) rollback(fork int64) error {
+	return l.db.Transaction(func(tx *gorm.DB) error {
+		var deposits []models.Deposit
+		err := tx.Where("chain_id=? AND btc_block_number>=? AND listener_status=?", l.conf.ChainId, fork, models.ListenerStatusSuccess).
+			Find(&deposits).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, deposit := range deposits {
+			var messages []models.Message
+			err = tx.Where("chain_id=? AND type=? AND event_id=?", l.conf.ChainId, enums.MessageTypeCall, deposit.Id).
+				Find(&messages).Error
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			// the refund of the deposit is delivered once its tx is signed
+			withdraws, err := signedWithdraws(tx, messages)
+			if err != nil {
+				return err
+			}
+			status := enums.DepositStatusInvalid
+			messageIds := make([]int64, 0, len(messages))
+			for _, message := range messages {
+				if !message.Undelivered() || withdraws[message.Id] {
+					status = enums.DepositStatusReview
+					continue
+				}
+				messageIds = append(messageIds, message.Id)
+			}
+			if status == enums.DepositStatusReview {
+				l.logger.Errorf("deposit %d delivered before reorg, flagged for review, btc tx hash: %s",
+					deposit.Id, deposit.BtcTxHash)
+			} else if len(messageIds) > 0 {
+				err = tx.Where("message_id in ?", messageIds).Delete(&models.MessageSignature{}).Error
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				err = deleteWithdraws(tx, messageIds)
+				if err != nil {
+					return err
+				}
+				err = tx.Where("id in ?", messageIds).Delete(&models.Message{}).Error
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			updates := map[string]interface{}{
+				models.Deposit{}.Column().ListenerStatus: models.ListenerStatusPending,
+				"status":                                 status,
+			}
+			if status == enums.DepositStatusInvalid {
+				updates[models.Deposit{}.Column().RefundTxHash] = ""
+			}
+			err = tx.Model(models.Deposit{}).Where("id=?", deposit.Id).Updates(updates).Error
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		l.logger.Infof("rollback from block: %d, deposits: %d", fork, len(deposits))
+		err = l.rollbackWithdraws(tx, fork)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("chain_id=? AND block_number>=?", l.conf.ChainId, fork).Delete(&models.SyncBlock{}).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		// a bounded task done past the fork indexes the new chain again
+		err = tx.Model(models.SyncTask{}).
+			Where("chain_type=? AND chain_id=? AND latest_block>=? AND status IN ?", enums.ChainTypeUTXO, l.conf.ChainId, fork,
+				[]enums.TaskStatus{enums.TaskStatusPending, enums.TaskStatusDone}).
+			Updates(map[string]interface{}{
+				"latest_block": fork - 1,
+				"latest_tx":    0,
+				"status":       enums.TaskStatusPending,
+			}).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,202 @@
+package bitcoin
+
+import (
+	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/initiates"
+	"bsquared.network/message-sharing-applications/internal/migrations"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"fmt"
+	"gorm.io/gorm"
+	"testing"
+)
+
+const testChainId = 1
+
+// newTestDB returns a migrated sqlite database.
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := initiates.InitDB(config.Database{Driver: "sqlite", DbName: t.TempDir() + "/listener.db", LogLevel: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func create(t *testing.T, db *gorm.DB, value interface{}) {
+	if err := db.Create(value).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func reload[T any](t *testing.T, db *gorm.DB, id int64) T {
+	var value T
+	if err := db.Where("id=?", id).First(&value).Error; err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}, query string, args ...interface{}) int64 {
+	var n int64
+	if err := db.Model(model).Where(query, args...).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func newDepositAt(t *testing.T, db *gorm.DB, chainId int64, block int64, status enums.DepositStatus) *models.Deposit {
+	deposit := &models.Deposit{
+		ChainId:        chainId,
+		BtcBlockNumber: block,
+		BtcTxHash:      fmt.Sprintf("%064x", count(t, db, models.Deposit{}, "1=1")+1),
+		BtcFroms:       "[]",
+		BtcTos:         "[]",
+		ListenerStatus: models.ListenerStatusSuccess,
+		Status:         status,
+	}
+	create(t, db, deposit)
+	return deposit
+}
+
+func newCallMessage(t *testing.T, db *gorm.DB, deposit *models.Deposit, status enums.MessageStatus) *models.Message {
+	message := &models.Message{
+		ChainId:    testChainId,
+		Type:       enums.MessageTypeCall,
+		Status:     status,
+		Blockchain: models.Blockchain{EventId: deposit.Id, TxHash: deposit.BtcTxHash},
+	}
+	create(t, db, message)
+	return message
+}
+
+func TestRollback(t *testing.T) {
+	db := newTestDB(t)
+	l := NewListener(nil, config.Blockchain{ChainId: testChainId, ChainType: enums.ChainTypeUTXO}, config.Particle{}, nil, db,
+		log.NewLogger("bitcoin-test", 0))
+	const fork = 100
+
+	kept := newDepositAt(t, db, testChainId, fork-1, enums.DepositStatusValid)
+	keptMessage := newCallMessage(t, db, kept, enums.MessageStatusPending)
+	// an orphaned deposit whose message and withdrawal are still collecting signatures
+	orphaned := newDepositAt(t, db, testChainId, fork, enums.DepositStatusRefunding)
+	orphanedMessage := newCallMessage(t, db, orphaned, enums.MessageStatusValidating)
+	create(t, db, &models.MessageSignature{MessageId: orphanedMessage.Id, Signer: "a"})
+	signing := &models.Withdraw{ChainId: testChainId, MessageId: orphanedMessage.Id, Status: enums.WithdrawStatusSigning}
+	create(t, db, signing)
+	create(t, db, &models.WithdrawSignature{WithdrawId: signing.Id, Signer: "a"})
+	db.Model(models.Deposit{}).Where("id=?", orphaned.Id).Update("refund_tx_hash", "aa")
+	// an orphaned deposit whose message was delivered
+	delivered := newDepositAt(t, db, testChainId, fork+1, enums.DepositStatusValid)
+	deliveredMessage := newCallMessage(t, db, delivered, enums.MessageStatusValid)
+	// the deposit of another bitcoin chain at the same height
+	other := newDepositAt(t, db, 2, fork+1, enums.DepositStatusValid)
+
+	// a refund confirmed in an orphaned block and one confirmed before the fork
+	refunded := newDepositAt(t, db, testChainId, 50, enums.DepositStatusRefunded)
+	refundMessage := newCallMessage(t, db, refunded, enums.MessageStatusValid)
+	confirmed := &models.Withdraw{ChainId: testChainId, MessageId: refundMessage.Id, BtcTxHash: "bb",
+		BtcBlockNumber: fork + 2, Status: enums.WithdrawStatusConfirmed}
+	create(t, db, confirmed)
+	db.Model(models.Deposit{}).Where("id=?", refunded.Id).Update("refund_tx_hash", "bb")
+	earlier := &models.Withdraw{ChainId: testChainId, MessageId: keptMessage.Id, BtcTxHash: "cc",
+		BtcBlockNumber: fork - 1, Status: enums.WithdrawStatusConfirmed}
+	create(t, db, earlier)
+
+	for height := int64(fork - 1); height <= fork+2; height++ {
+		create(t, db, &models.SyncBlock{ChainId: testChainId, BlockNumber: height, BlockHash: fmt.Sprint(height)})
+	}
+	running := &models.SyncTask{ChainType: enums.ChainTypeUTXO, ChainId: testChainId, LatestBlock: fork + 5, LatestTx: 3,
+		Status: enums.TaskStatusPending}
+	done := &models.SyncTask{ChainType: enums.ChainTypeUTXO, ChainId: testChainId, LatestBlock: fork + 2, EndBlock: fork + 2,
+		Status: enums.TaskStatusDone}
+	behind := &models.SyncTask{ChainType: enums.ChainTypeUTXO, ChainId: testChainId, LatestBlock: 50, Status: enums.TaskStatusPending}
+	evm := &models.SyncTask{ChainType: enums.ChainTypeEVM, ChainId: testChainId, LatestBlock: fork + 5, Status: enums.TaskStatusPending}
+	for _, task := range []*models.SyncTask{running, done, behind, evm} {
+		create(t, db, task)
+	}
+
+	if err := l.rollback(fork); err != nil {
+		t.Fatal(err)
+	}
+
+	deposit := reload[models.Deposit](t, db, kept.Id)
+	if deposit.Status != enums.DepositStatusValid || deposit.ListenerStatus != models.ListenerStatusSuccess {
+		t.Fatalf("deposit before the fork: %d %d", deposit.Status, deposit.ListenerStatus)
+	}
+	deposit = reload[models.Deposit](t, db, orphaned.Id)
+	if deposit.Status != enums.DepositStatusInvalid || deposit.ListenerStatus != models.ListenerStatusPending || deposit.RefundTxHash != "" {
+		t.Fatalf("orphaned deposit: %d %d %q", deposit.Status, deposit.ListenerStatus, deposit.RefundTxHash)
+	}
+	if n := count(t, db, models.Message{}, "id=?", orphanedMessage.Id); n != 0 {
+		t.Fatal("undelivered message kept")
+	}
+	if n := count(t, db, models.MessageSignature{}, "message_id=?", orphanedMessage.Id); n != 0 {
+		t.Fatal("message signatures kept")
+	}
+	if n := count(t, db, models.Withdraw{}, "id=?", signing.Id) + count(t, db, models.WithdrawSignature{}, "withdraw_id=?", signing.Id); n != 0 {
+		t.Fatal("signing withdrawal kept")
+	}
+	deposit = reload[models.Deposit](t, db, delivered.Id)
+	if deposit.Status != enums.DepositStatusReview || deposit.ListenerStatus != models.ListenerStatusPending {
+		t.Fatalf("delivered deposit: %d %d", deposit.Status, deposit.ListenerStatus)
+	}
+	if n := count(t, db, models.Message{}, "id=?", deliveredMessage.Id); n != 1 {
+		t.Fatal("delivered message dropped")
+	}
+	deposit = reload[models.Deposit](t, db, other.Id)
+	if deposit.Status != enums.DepositStatusValid || deposit.ListenerStatus != models.ListenerStatusSuccess {
+		t.Fatalf("deposit of another chain: %d %d", deposit.Status, deposit.ListenerStatus)
+	}
+
+	withdraw := reload[models.Withdraw](t, db, confirmed.Id)
+	if withdraw.Status != enums.WithdrawStatusBroadcast || withdraw.BtcBlockNumber != 0 {
+		t.Fatalf("orphaned withdrawal: %d %d", withdraw.Status, withdraw.BtcBlockNumber)
+	}
+	message := reload[models.Message](t, db, refundMessage.Id)
+	if message.Status != enums.MessageStatusBroadcast {
+		t.Fatalf("orphaned withdrawal message: %d", message.Status)
+	}
+	deposit = reload[models.Deposit](t, db, refunded.Id)
+	if deposit.Status != enums.DepositStatusRefunding {
+		t.Fatalf("refunded deposit: %d", deposit.Status)
+	}
+	withdraw = reload[models.Withdraw](t, db, earlier.Id)
+	if withdraw.Status != enums.WithdrawStatusConfirmed || withdraw.BtcBlockNumber != fork-1 {
+		t.Fatalf("withdrawal before the fork: %d %d", withdraw.Status, withdraw.BtcBlockNumber)
+	}
+	message = reload[models.Message](t, db, keptMessage.Id)
+	if message.Status != enums.MessageStatusPending {
+		t.Fatalf("message before the fork: %d", message.Status)
+	}
+
+	if n := count(t, db, models.SyncBlock{}, "chain_id=? AND block_number>=?", testChainId, fork); n != 0 {
+		t.Fatalf("orphaned blocks kept: %d", n)
+	}
+	if n := count(t, db, models.SyncBlock{}, "chain_id=?", testChainId); n != 1 {
+		t.Fatalf("blocks before the fork: %d", n)
+	}
+	for _, test := range []struct {
+		name   string
+		task   *models.SyncTask
+		block  int64
+		status enums.TaskStatus
+	}{
+		{"running", running, fork - 1, enums.TaskStatusPending},
+		{"done", done, fork - 1, enums.TaskStatusPending},
+		{"behind", behind, 50, enums.TaskStatusPending},
+		{"evm", evm, fork + 5, enums.TaskStatusPending},
+	} {
+		task := reload[models.SyncTask](t, db, test.task.Id)
+		if task.LatestBlock != test.block || task.LatestTx != 0 || task.Status != test.status {
+			t.Errorf("%s task: %d %d %d", test.name, task.LatestBlock, task.LatestTx, task.Status)
+		}
+	}
+}
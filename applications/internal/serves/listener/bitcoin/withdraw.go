@@ -0,0 +1,132 @@
+package bitcoin
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/types"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// pendingWithdraws returns the tx hashes of the withdrawals sent by the
+// proposer and not indexed yet.
+func (l *BitcoinListener) pendingWithdraws() (map[string]bool, error) {
+	var hashes []string
+	err := l.db.Model(models.Withdraw{}).
+		Where("chain_id=? AND status IN ?", l.conf.ChainId,
+			[]enums.WithdrawStatus{enums.WithdrawStatusSigned, enums.WithdrawStatusBroadcast}).
+		Pluck("btc_tx_hash", &hashes).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	withdraws := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		withdraws[hash] = true
+	}
+	return withdraws, nil
+}
+
+// confirmWithdraw marks the withdrawal indexed after SafeBlockNumber
+// confirmations and its message delivered.
+func (l *BitcoinListener) confirmWithdraw(tx *gorm.DB, parseResult *types.BitcoinTxParseResult, btcBlockNumber int64) error {
+	var withdraw models.Withdraw
+	err := tx.Where("chain_id=? AND btc_tx_hash=?", l.conf.ChainId, parseResult.TxID).First(&withdraw).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.Model(models.Withdraw{}).Where("id=?", withdraw.Id).Updates(map[string]interface{}{
+		"btc_block_number": btcBlockNumber,
+		"status":           enums.WithdrawStatusConfirmed,
+	}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.Model(models.Message{}).Where("id=?", withdraw.MessageId).
+		Update("status", enums.MessageStatusValid).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.Model(models.Deposit{}).
+		Where("chain_id=? AND "+models.Deposit{}.Column().RefundTxHash+"=? AND status=?",
+			l.conf.ChainId, parseResult.TxID, enums.DepositStatusRefunding).
+		Update("status", enums.DepositStatusRefunded).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	l.logger.Infof("withdraw %d confirmed at block: %d, btc tx hash: %s", withdraw.Id, btcBlockNumber, parseResult.TxID)
+	return nil
+}
+
+// rollbackWithdraws returns the withdrawals of the orphaned blocks to broadcast,
+// they are confirmed again once mined in the new chain.
+func (l *BitcoinListener) rollbackWithdraws(tx *gorm.DB, fork int64) error {
+	var withdraws []models.Withdraw
+	err := tx.Where("chain_id=? AND status=? AND btc_block_number>=?", l.conf.ChainId, enums.WithdrawStatusConfirmed, fork).
+		Find(&withdraws).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(withdraws) == 0 {
+		return nil
+	}
+	messageIds := make([]int64, 0, len(withdraws))
+	txHashes := make([]string, 0, len(withdraws))
+	for _, withdraw := range withdraws {
+		messageIds = append(messageIds, withdraw.MessageId)
+		txHashes = append(txHashes, withdraw.BtcTxHash)
+	}
+	err = tx.Model(models.Withdraw{}).
+		Where("chain_id=? AND status=? AND btc_block_number>=?", l.conf.ChainId, enums.WithdrawStatusConfirmed, fork).
+		Updates(map[string]interface{}{
+			"btc_block_number": 0,
+			"status":           enums.WithdrawStatusBroadcast,
+		}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.Model(models.Message{}).Where("id IN ?", messageIds).
+		Update("status", enums.MessageStatusBroadcast).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.Model(models.Deposit{}).
+		Where("chain_id=? AND "+models.Deposit{}.Column().RefundTxHash+" IN ? AND status=?",
+			l.conf.ChainId, txHashes, enums.DepositStatusRefunded).
+		Update("status", enums.DepositStatusRefunding).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	l.logger.Infof("rollback from block: %d, withdraws: %d", fork, len(messageIds))
+	return nil
+}
+
+// signedWithdraws returns the messages paid out by a withdrawal signed with
+// threshold signatures, the tx may be broadcast so the message is delivered.
+func signedWithdraws(tx *gorm.DB, messages []models.Message) (map[int64]bool, error) {
+	ids := make([]int64, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.Id)
+	}
+	var messageIds []int64
+	err := tx.Model(models.Withdraw{}).Where("message_id in ? AND status!=?", ids, enums.WithdrawStatusSigning).
+		Pluck("message_id", &messageIds).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	withdraws := make(map[int64]bool, len(messageIds))
+	for _, id := range messageIds {
+		withdraws[id] = true
+	}
+	return withdraws, nil
+}
+
+// deleteWithdraws drops the withdrawals of the messages still collecting
+// signatures, releasing their inputs.
+func deleteWithdraws(tx *gorm.DB, messageIds []int64) error {
+	err := tx.Where("withdraw_id in (?)", tx.Model(models.Withdraw{}).Select("id").Where("message_id in ?", messageIds)).
+		Delete(&models.WithdrawSignature{}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(tx.Where("message_id in ?", messageIds).Delete(&models.Withdraw{}).Error)
+}
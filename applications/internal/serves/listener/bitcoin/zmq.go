@@ -0,0 +1,36 @@
+package bitcoin
+
+import (
+	"bsquared.network/message-sharing-applications/internal/utils/zmq"
+	"time"
+)
+
+// watchZmq updates the latest block on the hashblock notifications of bitcoin
+// core, the block itself is read by the indexer, syncLatestBlock keeps polling in case a notification is missed or the
+// subscription is down.
+func (l *BitcoinListener) watchZmq() {
+	duration := time.Millisecond * time.Duration(l.conf.BlockInterval)
+	for {
+		sub, err := zmq.Dial(l.conf.Zmq, zmq.TopicHashBlock)
+		if err != nil {
+			l.logger.Errorf("[Handler.watchZmq] dial %s err: %s", l.conf.Zmq, err)
+			time.Sleep(duration)
+			continue
+		}
+		l.logger.Infof("[Handler.watchZmq] subscribed to %s", l.conf.Zmq)
+		for {
+			msg, err := sub.Receive()
+			if err != nil {
+				l.logger.Errorf("[Handler.watchZmq] receive err: %s", err)
+				break
+			}
+			l.logger.Infof("[Handler.watchZmq] %s notification, sequence: %d", msg.Topic, msg.Sequence)
+			err = l.updateLatestBlock()
+			if err != nil {
+				l.logger.Errorf("[Handler.watchZmq] sync latest block err: %s", err)
+			}
+		}
+		_ = sub.Close()
+		time.Sleep(duration)
+	}
+}
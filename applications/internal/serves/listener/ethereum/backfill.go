@@ -0,0 +1,159 @@
+package ethereum
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/block"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event/message"
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+const DefaultBackfillHandleNum = 1000
+
+// BackfillReport counts the events of a backfill range by event name, Indexed
+// holds the ones already stored by a previous sync.
+type BackfillReport struct {
+	From    int64
+	To      int64
+	Found   map[string]int64
+	Indexed map[string]int64
+}
+
+// Backfill creates a bounded task indexing [from, to] for the contracts, the
+// listen address when none is given. A pending task for the same range is
+// returned instead of creating a second one.
+func (l *EthereumListener) Backfill(from int64, to int64, contracts []common.Address, handleNum int64) (models.SyncTask, bool, error) {
+	err := checkBackfillRange(from, to)
+	if err != nil {
+		return models.SyncTask{}, false, err
+	}
+	if handleNum <= 0 {
+		handleNum = DefaultBackfillHandleNum
+	}
+	task := models.SyncTask{
+		ChainType:   enums.ChainTypeEVM,
+		ChainId:     l.conf.ChainId,
+		LatestBlock: from,
+		StartBlock:  from,
+		EndBlock:    to,
+		HandleNum:   handleNum,
+		Contracts:   joinContracts(contracts),
+		Status:      enums.TaskStatusPending,
+	}
+	var tasks []models.SyncTask
+	err = l.db.Where("chain_type=? AND chain_id=? AND start_block=? AND end_block=? AND contracts=? AND status=?",
+		task.ChainType, task.ChainId, task.StartBlock, task.EndBlock, task.Contracts, enums.TaskStatusPending).
+		Limit(1).Find(&tasks).Error
+	if err != nil {
+		return models.SyncTask{}, false, errors.WithStack(err)
+	}
+	if len(tasks) > 0 {
+		return tasks[0], false, nil
+	}
+	err = l.db.Create(&task).Error
+	if err != nil {
+		return models.SyncTask{}, false, errors.WithStack(err)
+	}
+	return task, true, nil
+}
+
+// ScanBackfill reports the events a backfill of [from, to] would index without
+// writing anything, the range is capped at the finalized block.
+func (l *EthereumListener) ScanBackfill(ctx context.Context, from int64, to int64, contracts []common.Address) (*BackfillReport, error) {
+	err := checkBackfillRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := block.FinalizedNumber(ctx, l.rpc, l.conf.Finality, l.conf.SafeBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if to > latest {
+		to = latest
+	}
+	if len(contracts) == 0 {
+		contracts = append(contracts, common.HexToAddress(l.conf.ListenAddress))
+	}
+	report := &BackfillReport{
+		From:    from,
+		To:      to,
+		Found:   make(map[string]int64),
+		Indexed: make(map[string]int64),
+	}
+	for start := from; start <= to; {
+		end := start + DefaultBackfillHandleNum - 1
+		if end > to {
+			end = to
+		}
+		logs, end, err := l.filterLogs(ctx, start, end, contracts)
+		if err != nil {
+			return nil, err
+		}
+		for _, vlog := range logs {
+			name := logEventName(vlog)
+			report.Found[name]++
+			var count int64
+			err = l.db.Model(models.SyncEvent{}).Where("chain_id=? AND tx_hash=? AND block_log_indexed=?",
+				l.conf.ChainId, vlog.TxHash.Hex(), vlog.Index).Count(&count).Error
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if count > 0 {
+				report.Indexed[name]++
+			}
+		}
+		l.logger.Infof("scan backfill start: %d, end: %d, logs: %d", start, end, len(logs))
+		start = end + 1
+	}
+	return report, nil
+}
+
+// ParseContracts parses a comma separated contract list.
+func ParseContracts(value string) ([]common.Address, error) {
+	contracts := make([]common.Address, 0)
+	for _, contract := range strings.Split(value, ",") {
+		contract = strings.TrimSpace(contract)
+		if contract == "" {
+			continue
+		}
+		if !common.IsHexAddress(contract) {
+			return nil, errors.Errorf("invalid contract address: %s", contract)
+		}
+		contracts = append(contracts, common.HexToAddress(contract))
+	}
+	return contracts, nil
+}
+
+func checkBackfillRange(from int64, to int64) error {
+	if from < 0 || to < from {
+		return errors.Errorf("invalid backfill range, from: %d, to: %d", from, to)
+	}
+	return nil
+}
+
+func joinContracts(contracts []common.Address) string {
+	values := make([]string, 0, len(contracts))
+	for _, contract := range contracts {
+		values = append(values, strings.ToLower(contract.Hex()))
+	}
+	return strings.Join(values, ",")
+}
+
+func logEventName(vlog types.Log) string {
+	switch event.TopicToHash(vlog, 0) {
+	case common.BytesToHash(message.MessageCallHash):
+		return message.MessageCallName
+	case common.BytesToHash(message.MessageSendHash):
+		return message.MessageSendName
+	case common.BytesToHash(message.SetWeightHash):
+		return message.SetWeightName
+	case common.BytesToHash(message.SetValidatorRoleHash):
+		return message.SetValidatorRoleName
+	}
+	return "unknown"
+}
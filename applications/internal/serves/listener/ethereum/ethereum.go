@@ -4,40 +4,55 @@ import (
 	"bsquared.network/message-sharing-applications/internal/config"
 	"bsquared.network/message-sharing-applications/internal/enums"
 	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/block"
 	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event"
 	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event/message"
 	"bsquared.network/message-sharing-applications/internal/utils/log"
 	"context"
 	"fmt"
-	"github.com/ethereum/go-ethereum"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
-	"math/big"
 	"strings"
 	"sync"
 	"time"
 )
 
+const blockTimeCacheSize = 4096
+
+var errTaskReset = errors.New("task reset by a rollback")
+
 type EthereumListener struct {
 	conf        config.Blockchain
-	rpc         *ethclient.Client
+	rpc         *rpcpool.EthClient
 	db          *gorm.DB
 	logger      *log.Logger
+	blockMu     sync.Mutex
 	latestBlock int64
 	bridges     map[int64]string
+	headNotify  chan struct{}
+	eventNotify chan struct{}
+	blockTimes  *lru.Cache[common.Hash, int64]
+	blockRange  *blockRange
+	logBuffer   *logBuffer
 }
 
-func NewListener(bridges map[int64]string, conf config.Blockchain, rpc *ethclient.Client, db *gorm.DB, logger *log.Logger) *EthereumListener {
+func NewListener(bridges map[int64]string, conf config.Blockchain, rpc *rpcpool.EthClient, db *gorm.DB, logger *log.Logger) *EthereumListener {
 	return &EthereumListener{
-		conf:    conf,
-		rpc:     rpc,
-		db:      db,
-		logger:  logger,
-		bridges: bridges,
+		conf:        conf,
+		rpc:         rpc,
+		db:          db,
+		logger:      logger,
+		bridges:     bridges,
+		headNotify:  make(chan struct{}, 1),
+		eventNotify: make(chan struct{}, 1),
+		blockTimes:  lru.NewCache[common.Hash, int64](blockTimeCacheSize),
+		blockRange:  &blockRange{},
+		logBuffer:   newLogBuffer(),
 	}
 }
 
@@ -48,9 +63,21 @@ func (l *EthereumListener) Start() {
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	err := l.loadBlockRange()
+	if err != nil {
+		l.logger.Errorf("load filter logs range size err: %s", err)
+	}
 	go l.syncLastBlock()
+	if l.conf.Subscribe {
+		if l.rpc.Websocket() {
+			go l.subscribe()
+		} else {
+			l.logger.Errorf("subscribe needs a ws endpoint, fallback to polling")
+		}
+	}
 	go l.syncTask()
 	go l.handEvent()
+	go l.handleRegistry()
 	go l.confirm()
 	<-ctx.Done()
 }
@@ -58,23 +85,38 @@ func (l *EthereumListener) Start() {
 func (l *EthereumListener) syncLastBlock() {
 	for {
 		duration := time.Millisecond * time.Duration(l.conf.BlockInterval)
-		latest, err := l.rpc.BlockNumber(context.Background())
+		latest, err := block.FinalizedNumber(context.Background(), l.rpc, l.conf.Finality, l.conf.SafeBlockNumber)
 		if err != nil {
 			l.logger.Errorf("sync latest block error: %s", err.Error())
 			time.Sleep(duration)
 			continue
 		}
-		l.latestBlock = int64(latest)
-		l.logger.Infof("sync latest block: %d", l.latestBlock)
+		l.setLatestBlock(latest, false)
+		l.logger.Infof("sync latest block: %d, finality: %s", latest, l.conf.Finality)
 		time.Sleep(duration)
 	}
 }
 
+func (l *EthereumListener) getLatestBlock() int64 {
+	l.blockMu.Lock()
+	defer l.blockMu.Unlock()
+	return l.latestBlock
+}
+
+// setLatestBlock stores the latest block, forward only moves it ahead.
+func (l *EthereumListener) setLatestBlock(latest int64, forward bool) {
+	l.blockMu.Lock()
+	defer l.blockMu.Unlock()
+	if !forward || latest > l.latestBlock {
+		l.latestBlock = latest
+	}
+}
+
 func (l *EthereumListener) syncTask() {
 	for {
 		duration := time.Millisecond * time.Duration(l.conf.BlockInterval) * 5
 		var tasks []models.SyncTask
-		err := l.db.Where("`chain_type`=? AND chain_id=? AND status=?", enums.ChainTypeEVM, l.conf.ChainId, enums.TaskStatusPending).Limit(20).Find(&tasks).Error
+		err := l.db.Where("chain_type=? AND chain_id=? AND status=?", enums.ChainTypeEVM, l.conf.ChainId, enums.TaskStatusPending).Limit(20).Find(&tasks).Error
 		if err != nil {
 			l.logger.Errorf("task list error: %s\n", err)
 			time.Sleep(duration)
@@ -82,7 +124,7 @@ func (l *EthereumListener) syncTask() {
 		}
 		if len(tasks) == 0 {
 			l.logger.Info("task list is empty")
-			time.Sleep(duration)
+			wait(l.headNotify, duration)
 			continue
 		}
 		wg := sync.WaitGroup{}
@@ -98,7 +140,7 @@ func (l *EthereumListener) syncTask() {
 
 		}
 		wg.Wait()
-		time.Sleep(duration)
+		wait(l.headNotify, duration)
 	}
 }
 
@@ -109,10 +151,23 @@ func (l *EthereumListener) handleTask(task models.SyncTask) error {
 	}
 
 	if task.EndBlock > 0 && start > task.EndBlock {
-		task.Status = enums.TaskStatusDone
-		l.db.Save(&task)
+		err := checkpoint(l.db, task, map[string]interface{}{"status": enums.TaskStatusDone})
+		if err != nil && !errors.Is(err, errTaskReset) {
+			return err
+		}
 		return nil
 	}
+
+	fork, err := l.checkReorg(context.Background(), start)
+	if err != nil {
+		l.logger.Errorf("[Handler.SyncTask]  check reorg error: %v", err)
+		return errors.WithStack(err)
+	}
+	if fork > 0 {
+		l.logger.Infof("[Handler.SyncTask]  reorg detected, rollback from block: %d", fork)
+		return l.rollback(fork)
+	}
+
 	end := start
 	if task.HandleNum > 0 {
 		end = start + task.HandleNum - 1
@@ -120,64 +175,50 @@ func (l *EthereumListener) handleTask(task models.SyncTask) error {
 	if task.EndBlock > 0 && end > task.EndBlock {
 		end = task.EndBlock
 	}
-	if end > l.latestBlock {
-		end = l.latestBlock
+	if latest := l.getLatestBlock(); end > latest {
+		end = latest
 	}
 	if start > end {
 		return nil
 	}
 
-	//Contracts := strings.Split(task.Contracts, ",")
-	//if len(Contracts) == 0 {
-	//	//Contracts = l.GetContracts()
-	//	//log.Infof("[Handler.SyncTask]  Contracts invalid")
-	//	////task.UpdateTime = time.Now()
-	//	//task.Status = models.SyncTaskInvalid
-	//	//ctx.Db.Save(&task)
-	//	//return nil
-	//}
-
-	logs, err := l.rpc.FilterLogs(context.Background(), ethereum.FilterQuery{
-		FromBlock: big.NewInt(start),
-		ToBlock:   big.NewInt(end),
-		Topics: [][]common.Hash{
-			{
-				common.BytesToHash(message.MessageCallHash),
-				common.BytesToHash(message.MessageSendHash),
-			},
-		},
-		Addresses: []common.Address{
-			common.HexToAddress(l.conf.ListenAddress),
-		},
-	})
-	l.logger.Infof(" start: %d, end: %d\n", start, end)
+	logs, blocks, end, err := l.fetchLogs(context.Background(), start, end, l.taskContracts(task))
 	if err != nil {
-		l.logger.Errorf("[Handler.SyncTask]  FilterLogs error: %v", err)
+		l.logger.Errorf("[Handler.SyncTask]  fetch logs error: %v", err)
 		return errors.WithStack(err)
 	}
-
+	l.logger.Infof(" start: %d, end: %d\n", start, end)
 	events, err := l.LogsToEvents(logs)
 	if err != nil {
 		l.logger.Errorf("[Handler.SyncTask]  LogsToEvents error: %v", err)
 		return errors.WithStack(err)
 	}
+	err = checkBlockHashes(blocks, events)
+	if err != nil {
+		l.logger.Errorf("[Handler.SyncTask]  check block hashes error: %v", err)
+		return errors.WithStack(err)
+	}
+	// events indexed before, e.g. by a backfill over a synced range, keep
+	// their status so the messages are not handled twice
 	BatchCreateEvents := make([]*models.SyncEvent, 0)
-	BatchUpdateEventIds := make([]int64, 0)
 	for _, event := range events {
 		var one models.SyncEvent
-		err = l.db.Select("id").Where("block_number=? AND block_log_indexed=? AND tx_hash=? AND event_hash=?",
-			event.BlockNumber, event.BlockLogIndexed, event.TxHash, event.EventHash).First(&one).Error
+		err = l.db.Select("id").Where("chain_id=? AND block_number=? AND block_log_indexed=? AND tx_hash=? AND event_hash=?",
+			event.ChainId, event.BlockNumber, event.BlockLogIndexed, event.TxHash, event.EventHash).First(&one).Error
 		if err != nil && err != gorm.ErrRecordNotFound {
 			l.logger.Errorf("[Handler.SyncTask]  Get event err: %s\n", err)
 			return errors.WithStack(err)
 		} else if err == gorm.ErrRecordNotFound {
 			BatchCreateEvents = append(BatchCreateEvents, event)
-		} else {
-			BatchUpdateEventIds = append(BatchUpdateEventIds, one.Id)
 		}
 	}
 
 	err = l.db.Transaction(func(tx *gorm.DB) error {
+		err = l.saveBlocks(tx, blocks, BatchCreateEvents)
+		if err != nil {
+			l.logger.Errorf("[Handler.SyncEvent]Save SyncBlock err: %s\n", err)
+			return errors.WithStack(err)
+		}
 		if len(BatchCreateEvents) > 0 {
 			err = tx.CreateInBatches(&BatchCreateEvents, 100).Error
 			if err != nil {
@@ -185,35 +226,93 @@ func (l *EthereumListener) handleTask(task models.SyncTask) error {
 				return errors.WithStack(err)
 			}
 		}
-		if len(BatchUpdateEventIds) > 0 {
-			err = tx.Model(models.SyncEvent{}).
-				Where("id in ?", BatchUpdateEventIds).
-				Update("status", models.EventPending).Error
-			if err != nil {
-				l.logger.Errorf("[Handler.SyncEvent]Update SyncEvent err: %s\n,", err)
-				return errors.WithStack(err)
-			}
-		}
-		task.LatestBlock = end + 1
-		err = tx.Save(&task).Error
-		if err != nil {
-			l.logger.Errorf("[Handler.SyncEvent]Update SyncTask err: %s\n,", err)
-			return errors.WithStack(err)
-		}
-		return nil
+		return checkpoint(tx, task, map[string]interface{}{"latest_block": end + 1})
 	})
+	if errors.Is(err, errTaskReset) {
+		// the range may be orphaned, it is indexed again from the reset block
+		l.logger.Infof("[Handler.SyncEvent] task %d reset by a rollback, restart", task.Id)
+		return nil
+	}
 	if err != nil {
 		l.logger.Errorf("[Handler.SyncEvent]Update SyncTask err: %s\n,", err)
 		return errors.WithStack(err)
 	}
+	if len(BatchCreateEvents) > 0 {
+		notify(l.eventNotify)
+	}
+	return nil
+}
+
+// fetchLogs returns the logs of the contracts in [start, end] with the tracked
+// blocks, from the log subscription when it covers the range, else from
+// FilterLogs. It returns the end actually queried.
+func (l *EthereumListener) fetchLogs(ctx context.Context, start int64, end int64, contracts []common.Address) ([]types.Log, []models.SyncBlock, int64, error) {
+	var blocks []models.SyncBlock
+	tracked := false
+	if l.logBuffer.covers(start) {
+		var headers []*block.Header
+		var err error
+		blocks, headers, err = l.trackBlocks(ctx, start, end)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		logs, ok := l.logBuffer.take(start, end, headers, contracts)
+		if ok {
+			return logs, blocks, end, nil
+		}
+		tracked = true
+	}
+	logs, end, err := l.filterLogs(ctx, start, end, contracts)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if !tracked {
+		blocks, _, err = l.trackBlocks(ctx, start, end)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	// the blocks past a split range are tracked with the next range
+	for len(blocks) > 0 && blocks[len(blocks)-1].BlockNumber > end {
+		blocks = blocks[:len(blocks)-1]
+	}
+	return logs, blocks, end, nil
+}
+
+// checkpoint updates the task read at its latest block, errTaskReset when a
+// rollback moved the task meanwhile so the stale copy does not overwrite it.
+func checkpoint(tx *gorm.DB, task models.SyncTask, updates map[string]interface{}) error {
+	result := tx.Model(models.SyncTask{}).Where("id=? AND latest_block=?", task.Id, task.LatestBlock).Updates(updates)
+	if result.Error != nil {
+		return errors.WithStack(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errTaskReset
+	}
 	return nil
 }
 
+// taskContracts returns the contracts indexed by the task, the listen address
+// when the task has none.
+func (l *EthereumListener) taskContracts(task models.SyncTask) []common.Address {
+	contracts := make([]common.Address, 0)
+	for _, contract := range strings.Split(task.Contracts, ",") {
+		contract = strings.TrimSpace(contract)
+		if common.IsHexAddress(contract) {
+			contracts = append(contracts, common.HexToAddress(contract))
+		}
+	}
+	if len(contracts) == 0 {
+		contracts = append(contracts, common.HexToAddress(l.conf.ListenAddress))
+	}
+	return contracts
+}
+
 func (l *EthereumListener) handEvent() {
 	duration := time.Millisecond * time.Duration(l.conf.BlockInterval) * 5
 	for {
 		var events []models.SyncEvent
-		err := l.db.Model(models.SyncEvent{}).Where("`chain_id`=? AND `event_hash` in ? AND `status`=?",
+		err := l.db.Model(models.SyncEvent{}).Where("chain_id=? AND event_hash in ? AND status=?",
 			l.conf.ChainId, []string{
 				common.BytesToHash(message.MessageCallHash).Hex(),
 				common.BytesToHash(message.MessageSendHash).Hex(),
@@ -226,7 +325,7 @@ func (l *EthereumListener) handEvent() {
 		}
 		if len(events) == 0 {
 			l.logger.Errorf("[Handler.SyncEvent] no event\n")
-			time.Sleep(duration)
+			wait(l.eventNotify, duration)
 			continue
 		}
 		valids := make([]int64, 0)
@@ -234,18 +333,18 @@ func (l *EthereumListener) handEvent() {
 		messages := make([]models.Message, 0)
 		handles := make(map[string]bool)
 
-		var Type enums.MessageType
-		var FromMessageBridge string
-		var FromChainId int64
-		var FromSender string
-		var FromId string
-		var ToChainId int64
-		var ToMessageBridge string
-		var ToContractAddress string
-		var ToBytes string
-		var status enums.MessageStatus
-
 		for _, event := range events {
+			var Type enums.MessageType
+			var FromMessageBridge string
+			var FromChainId int64
+			var FromSender string
+			var FromId string
+			var ToChainId int64
+			var ToMessageBridge string
+			var ToContractAddress string
+			var ToBytes string
+			var status enums.MessageStatus
+
 			key := fmt.Sprintf("%s#%d", event.TxHash, event.BlockLogIndexed)
 			if handles[key] {
 				invalids = append(invalids, event.Id)
@@ -308,7 +407,7 @@ func (l *EthereumListener) handEvent() {
 			}
 
 			var message models.Message
-			err = l.db.Where("`tx_hash`=? AND `log_index`=?", event.TxHash, event.BlockLogIndexed).First(&message).Error
+			err = l.db.Where("tx_hash=? AND log_index=?", event.TxHash, event.BlockLogIndexed).First(&message).Error
 			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 				l.logger.Errorf("query message err: %v, data: %v\n", err, event)
 				time.Sleep(duration)
@@ -317,6 +416,7 @@ func (l *EthereumListener) handEvent() {
 				handles[key] = true
 				message = models.Message{
 					ChainId:           event.ChainId,
+					ContractAddress:   event.ContractAddress,
 					Type:              Type,
 					FromChainId:       FromChainId,
 					FromSender:        FromSender,
@@ -376,7 +476,10 @@ func (l *EthereumListener) handEvent() {
 
 func (l *EthereumListener) LogsToEvents(logs []types.Log) ([]*models.SyncEvent, error) {
 	var events []*models.SyncEvent
-	blockTimes := make(map[int64]int64)
+	blockTimes, err := l.fetchBlockTimes(logs)
+	if err != nil {
+		return nil, err
+	}
 	for _, vlog := range logs {
 		eventHash := event.TopicToHash(vlog, 0)
 		contractAddress := vlog.Address
@@ -401,25 +504,26 @@ func (l *EthereumListener) LogsToEvents(logs []types.Log) ([]*models.SyncEvent,
 				return nil, err
 			}
 			data = _data
+		} else if eventHash == common.BytesToHash(message.SetWeightHash) {
+			eventName = message.SetWeightName
+			e := &message.SetWeight{}
+			_data, err := e.Data(vlog)
+			if err != nil {
+				l.logger.Errorf("parse set weight err: %v, data: %v\n", err, vlog)
+				return nil, err
+			}
+			data = _data
+		} else if eventHash == common.BytesToHash(message.SetValidatorRoleHash) {
+			eventName = message.SetValidatorRoleName
+			e := &message.SetValidatorRole{}
+			_data, err := e.Data(vlog)
+			if err != nil {
+				l.logger.Errorf("parse set validator role err: %v, data: %v\n", err, vlog)
+				return nil, err
+			}
+			data = _data
 		}
-		blockTime := blockTimes[int64(vlog.BlockNumber)]
-		if blockTime == 0 {
-			//	blockJson, err := rpc2.HttpPostJson("", l.Blockchain.RpcUrl, "{\"jsonrpc\":\"2.0\",\"method\":\"eth_getBlockByNumber\",\"params\":[\""+fmt.Sprintf("0x%X", vlog.BlockNumber)+"\", true],\"id\":1}")
-			//	if err != nil {
-			//		log.Errorf("[Handler.SyncBlock] Syncing block by number error: %s\n", errors.WithStack(err))
-			//		time.Sleep(3 * time.Second)
-			//		continue
-			//	}
-			//	block := rpc2.ParseJsonBlock(string(blockJson))
-			//	//
-			//block, err := l.rpc.BlockByNumber(context.Background(), big.NewInt(int64(vlog.BlockNumber)))
-			//if err != nil {
-			//	l.logger.Errorf("Syncing block by number error: %s\n", errors.WithStack(err))
-			//	return nil, errors.WithStack(err)
-			//}
-			//blockTime = int64(block.Time())
-			//blockTimes[int64(vlog.BlockNumber)] = blockTime
-		}
+		blockTime := blockTimes[vlog.BlockHash]
 		events = append(events, &models.SyncEvent{
 			ChainId:         l.conf.ChainId,
 			BlockTime:       blockTime,
@@ -438,6 +542,36 @@ func (l *EthereumListener) LogsToEvents(logs []types.Log) ([]*models.SyncEvent,
 	return events, nil
 }
 
+// fetchBlockTimes returns the timestamps of the log blocks, the headers missing
+// from the cache are fetched once per block in batches.
+func (l *EthereumListener) fetchBlockTimes(logs []types.Log) (map[common.Hash]int64, error) {
+	blockTimes := make(map[common.Hash]int64)
+	hashes := make([]common.Hash, 0)
+	for _, vlog := range logs {
+		if _, ok := blockTimes[vlog.BlockHash]; ok {
+			continue
+		}
+		blockTime, ok := l.blockTimes.Get(vlog.BlockHash)
+		if !ok {
+			hashes = append(hashes, vlog.BlockHash)
+		}
+		blockTimes[vlog.BlockHash] = blockTime
+	}
+	if len(hashes) == 0 {
+		return blockTimes, nil
+	}
+	headers, err := block.HeadersByHash(context.Background(), l.rpc, hashes)
+	if err != nil {
+		l.logger.Errorf("fetch block headers error: %v", err)
+		return nil, err
+	}
+	for _, header := range headers {
+		blockTimes[header.Hash] = int64(header.Time)
+		l.blockTimes.Add(header.Hash, int64(header.Time))
+	}
+	return blockTimes, nil
+}
+
 func (l *EthereumListener) confirm() {
 	duration := time.Millisecond * time.Duration(l.conf.BlockInterval) * 5
 	for {
@@ -467,8 +601,12 @@ func (l *EthereumListener) confirm() {
 }
 
 func (l *EthereumListener) confirmMessage(message models.Message) error {
-	var callMessage models.Message
-	err := l.db.Where("type=? AND from_chain_id=? AND from_id=?", enums.MessageTypeCall, message.FromChainId, message.FromId).First(&callMessage).Error
+	var callMessages []models.Message
+	err := l.db.Where("type=? AND from_chain_id=? AND from_id=?", enums.MessageTypeCall, message.FromChainId, message.FromId).Find(&callMessages).Error
+	if err != nil {
+		return err
+	}
+	callMessage, err := matchCallMessage(callMessages, message)
 	if err != nil {
 		return err
 	}
@@ -479,8 +617,12 @@ func (l *EthereumListener) confirmMessage(message models.Message) error {
 		if err != nil {
 			return err
 		}
+		// the send event does not name its source deployment, the call does
 		err = tx.Model(models.Message{}).Where("id=? AND status=?", message.Id, message.Status).
-			Update("status", enums.MessageStatusValid).Error
+			Updates(map[string]interface{}{
+				"status":              enums.MessageStatusValid,
+				"from_message_bridge": callMessage.FromMessageBridge,
+			}).Error
 		if err != nil {
 			return err
 		}
@@ -504,9 +646,35 @@ func (l *EthereumListener) confirmMessage(message models.Message) error {
 	return nil
 }
 
+// matchCallMessage picks the call the send message executed. From ids are only
+// unique per deployment and the send event does not name the source
+// deployment, so the call of the same from id carrying the sender, target and
+// payload of the send wins, a call not confirmed yet first.
+func matchCallMessage(callMessages []models.Message, message models.Message) (models.Message, error) {
+	matches := make([]models.Message, 0, len(callMessages))
+	for _, callMessage := range callMessages {
+		if callMessage.ToChainId == message.ToChainId &&
+			strings.EqualFold(callMessage.FromSender, message.FromSender) &&
+			strings.EqualFold(callMessage.ToContractAddress, message.ToContractAddress) &&
+			strings.EqualFold(callMessage.ToBytes, message.ToBytes) {
+			matches = append(matches, callMessage)
+		}
+	}
+	if len(matches) == 0 {
+		return models.Message{}, fmt.Errorf("call message not matched, from chain id: %d, from id: %s",
+			message.FromChainId, message.FromId)
+	}
+	for _, callMessage := range matches {
+		if callMessage.Status != enums.MessageStatusValid {
+			return callMessage, nil
+		}
+	}
+	return matches[0], nil
+}
+
 func (l *EthereumListener) pendingSendMessage(limit int) ([]models.Message, error) {
 	var list []models.Message
-	err := l.db.Where("`to_chain_id`=? AND `type`=? AND status=?", l.conf.ChainId, enums.MessageTypeSend, enums.MessageStatusPending).Limit(limit).Find(&list).Error
+	err := l.db.Where("to_chain_id=? AND type=? AND status=?", l.conf.ChainId, enums.MessageTypeSend, enums.MessageStatusPending).Limit(limit).Find(&list).Error
 	if err != nil {
 		return nil, err
 	}
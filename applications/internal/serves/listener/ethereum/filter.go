@@ -0,0 +1,195 @@
+package ethereum
+
+import (
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event/message"
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// rangeGrowAfter is the number of successful full range queries before the range doubles.
+	rangeGrowAfter = 5
+	// rateRetries is the number of retries of a rate limited query, the wait doubling from rateBackoff.
+	rateRetries = 5
+)
+
+var rateBackoff = time.Second
+
+// logTopics are the message and registry events indexed by the listener.
+var logTopics = []common.Hash{
+	common.BytesToHash(message.MessageCallHash),
+	common.BytesToHash(message.MessageSendHash),
+	common.BytesToHash(message.SetWeightHash),
+	common.BytesToHash(message.SetValidatorRoleHash),
+}
+
+// rangeErrors are the provider errors asking for a smaller block range.
+var rangeErrors = []string{
+	"more than 10000 results",
+	"query returned more than",
+	"block range too large",
+	"block range is too large",
+	"range too large",
+	"exceed maximum block range",
+	"exceeds the range",
+	"response size exceeded",
+	"log response size exceeded",
+	"too many blocks",
+}
+
+// rateErrors are the provider errors asking to slow down, the range is not the cause.
+var rateErrors = []string{
+	"rate limit",
+	"too many requests",
+	"request count limit",
+	"compute units",
+}
+
+func isRangeError(err error) bool {
+	return containsError(err, rangeErrors) && !isRateError(err)
+}
+
+func isRateError(err error) bool {
+	return containsError(err, rateErrors)
+}
+
+func containsError(err error, patterns []string) bool {
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range patterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockRange remembers the block range size the rpc of the chain accepts,
+// 0 means no limit was hit yet. The size is kept in sync_ranges so a restart
+// does not hit the limit again.
+type blockRange struct {
+	mu        sync.Mutex
+	size      int64
+	successes int64
+}
+
+// limit returns the range size to query, at most max.
+func (r *blockRange) limit(max int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size < max {
+		return r.size
+	}
+	return max
+}
+
+// set restores a size kept before.
+func (r *blockRange) set(size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.size = size
+	r.successes = 0
+}
+
+// shrink halves the range size that failed and returns the new size.
+func (r *blockRange) shrink(size int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.size = size / 2
+	if r.size < 1 {
+		r.size = 1
+	}
+	r.successes = 0
+	return r.size
+}
+
+// succeed counts a query of the full range size, the size doubles after
+// rangeGrowAfter of them. It returns the size and whether it grew.
+func (r *blockRange) succeed(size int64) (int64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size == 0 || size < r.size {
+		return r.size, false
+	}
+	r.successes++
+	if r.successes >= rangeGrowAfter {
+		r.size = r.size * 2
+		r.successes = 0
+		return r.size, true
+	}
+	return r.size, false
+}
+
+// loadBlockRange restores the range size kept for the chain.
+func (l *EthereumListener) loadBlockRange() error {
+	var ranges []models.SyncRange
+	err := l.db.Where("chain_id=?", l.conf.ChainId).Limit(1).Find(&ranges).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ranges) > 0 && ranges[0].Size > 0 {
+		l.blockRange.set(ranges[0].Size)
+		l.logger.Infof("filter logs range size: %d", ranges[0].Size)
+	}
+	return nil
+}
+
+// saveBlockRange keeps the range size of the chain, a failure only costs the
+// size after a restart.
+func (l *EthereumListener) saveBlockRange(size int64) {
+	err := l.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size", "updated_at"}),
+	}).Create(&models.SyncRange{ChainId: l.conf.ChainId, Size: size}).Error
+	if err != nil {
+		l.logger.Errorf("save filter logs range size err: %s", err)
+	}
+}
+
+// filterLogs queries the message and registry logs from start up to end, the range is split
+// while the rpc rejects it as too large and the query waits while the rpc is rate limited.
+// It returns the end actually queried.
+func (l *EthereumListener) filterLogs(ctx context.Context, start int64, end int64, contracts []common.Address) ([]types.Log, int64, error) {
+	backoff := rateBackoff
+	retries := 0
+	for {
+		to := start + l.blockRange.limit(end-start+1) - 1
+		logs, err := l.rpc.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: big.NewInt(start),
+			ToBlock:   big.NewInt(to),
+			Topics:    [][]common.Hash{logTopics},
+			Addresses: contracts,
+		})
+		if err == nil {
+			if size, grown := l.blockRange.succeed(to - start + 1); grown {
+				l.saveBlockRange(size)
+			}
+			return logs, to, nil
+		}
+		if isRateError(err) && retries < rateRetries {
+			retries++
+			l.logger.Infof("filter logs rate limited, start: %d, end: %d, retry in: %s", start, to, backoff)
+			select {
+			case <-ctx.Done():
+				return nil, 0, errors.WithStack(ctx.Err())
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			continue
+		}
+		if !isRangeError(err) || to == start {
+			return nil, 0, errors.WithStack(err)
+		}
+		size := l.blockRange.shrink(to - start + 1)
+		l.saveBlockRange(size)
+		l.logger.Infof("filter logs range too large, start: %d, end: %d, retry size: %d", start, to, size)
+	}
+}
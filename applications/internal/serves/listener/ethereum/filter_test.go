@@ -0,0 +1,253 @@
+package ethereum
+
+import (
+	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/initiates"
+	"bsquared.network/message-sharing-applications/internal/migrations"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"bsquared.network/message-sharing-applications/internal/utils/log"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// getLogsServer is a json-rpc stand-in answering eth_getLogs with the error
+// respond returns for the queried range, or no logs.
+type getLogsServer struct {
+	mu      sync.Mutex
+	ranges  [][2]int64
+	respond func(from int64, to int64) string
+}
+
+func (s *getLogsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Id     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params []struct {
+			FromBlock hexutil.Big `json:"fromBlock"`
+			ToBlock   hexutil.Big `json:"toBlock"`
+		} `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_getLogs" || len(req.Params) != 1 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	from, to := req.Params[0].FromBlock.ToInt().Int64(), req.Params[0].ToBlock.ToInt().Int64()
+	s.mu.Lock()
+	s.ranges = append(s.ranges, [2]int64{from, to})
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if msg := s.respond(from, to); msg != "" {
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32005,"message":%q}}`, req.Id, msg)
+		return
+	}
+	fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":[]}`, req.Id)
+}
+
+func (s *getLogsServer) queried() [][2]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][2]int64(nil), s.ranges...)
+}
+
+func newTestListener(t *testing.T, server *getLogsServer) *EthereumListener {
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	logger := log.NewLogger("filter-test", 0)
+	client, err := rpcpool.NewEthClient("filter-test", []string{httpServer.URL}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := initiates.InitDB(config.Database{Driver: "sqlite", DbName: t.TempDir() + "/listener.db", LogLevel: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	return NewListener(nil, config.Blockchain{ChainId: 7}, client, db, logger)
+}
+
+func savedRange(t *testing.T, l *EthereumListener) int64 {
+	var ranges []models.SyncRange
+	if err := l.db.Where("chain_id=?", l.conf.ChainId).Find(&ranges).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) > 1 {
+		t.Fatalf("ranges of chain: %d", len(ranges))
+	}
+	if len(ranges) == 0 {
+		return 0
+	}
+	return ranges[0].Size
+}
+
+func TestBlockRange(t *testing.T) {
+	r := &blockRange{}
+	if limit := r.limit(100); limit != 100 {
+		t.Fatalf("unlimited range: %d", limit)
+	}
+	if size, grown := r.succeed(100); size != 0 || grown {
+		t.Fatalf("unlimited range grew: %d %t", size, grown)
+	}
+	if size := r.shrink(100); size != 50 {
+		t.Fatalf("shrunk size: %d", size)
+	}
+	if limit := r.limit(100); limit != 50 {
+		t.Fatalf("shrunk limit: %d", limit)
+	}
+	if limit := r.limit(20); limit != 20 {
+		t.Fatalf("limit under the size: %d", limit)
+	}
+	// a range cut short by the end does not count
+	for i := 0; i < rangeGrowAfter; i++ {
+		if _, grown := r.succeed(20); grown {
+			t.Fatal("short range grew the size")
+		}
+	}
+	for i := 1; i < rangeGrowAfter; i++ {
+		if _, grown := r.succeed(50); grown {
+			t.Fatalf("grew after %d successes", i)
+		}
+	}
+	if size, grown := r.succeed(50); size != 100 || !grown {
+		t.Fatalf("regrown size: %d %t", size, grown)
+	}
+	// a shrink restarts the count
+	for i := 1; i < rangeGrowAfter; i++ {
+		r.succeed(100)
+	}
+	r.shrink(100)
+	if size, grown := r.succeed(50); size != 50 || grown {
+		t.Fatalf("count kept over a shrink: %d %t", size, grown)
+	}
+	if size := r.shrink(1); size != 1 {
+		t.Fatalf("size under one: %d", size)
+	}
+}
+
+func TestFilterLogsShrink(t *testing.T) {
+	server := &getLogsServer{respond: func(from int64, to int64) string {
+		if to-from+1 > 25 {
+			return "block range is too large"
+		}
+		return ""
+	}}
+	l := newTestListener(t, server)
+	_, end, err := l.filterLogs(context.Background(), 1, 100, []common.Address{{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end != 25 {
+		t.Fatalf("queried end: %d", end)
+	}
+	expected := [][2]int64{{1, 100}, {1, 50}, {1, 25}}
+	if ranges := server.queried(); fmt.Sprint(ranges) != fmt.Sprint(expected) {
+		t.Fatalf("queried ranges: %v", ranges)
+	}
+	if size := savedRange(t, l); size != 25 {
+		t.Fatalf("saved size: %d", size)
+	}
+
+	// the size grows back after rangeGrowAfter full range queries, the first one above
+	for i := 1; i < rangeGrowAfter; i++ {
+		if _, _, err = l.filterLogs(context.Background(), 1, 100, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if size := savedRange(t, l); size != 50 {
+		t.Fatalf("regrown saved size: %d", size)
+	}
+
+	// a restarted listener of the chain starts at the saved size
+	restarted := NewListener(nil, l.conf, l.rpc, l.db, l.logger)
+	if err = restarted.loadBlockRange(); err != nil {
+		t.Fatal(err)
+	}
+	if limit := restarted.blockRange.limit(100); limit != 50 {
+		t.Fatalf("restored limit: %d", limit)
+	}
+	other := NewListener(nil, config.Blockchain{ChainId: 8}, l.rpc, l.db, l.logger)
+	if err = other.loadBlockRange(); err != nil {
+		t.Fatal(err)
+	}
+	if limit := other.blockRange.limit(100); limit != 100 {
+		t.Fatalf("limit of another chain: %d", limit)
+	}
+}
+
+func TestFilterLogsSingleBlock(t *testing.T) {
+	server := &getLogsServer{respond: func(from int64, to int64) string {
+		return "query returned more than 10000 results"
+	}}
+	l := newTestListener(t, server)
+	_, _, err := l.filterLogs(context.Background(), 1, 4, nil)
+	if err == nil {
+		t.Fatal("expected the error of a single block range")
+	}
+	if ranges := server.queried(); len(ranges) != 3 || ranges[2] != [2]int64{1, 1} {
+		t.Fatalf("queried ranges: %v", ranges)
+	}
+}
+
+func TestFilterLogsRateLimit(t *testing.T) {
+	backoff := rateBackoff
+	rateBackoff = 10 * time.Millisecond
+	defer func() { rateBackoff = backoff }()
+
+	calls := 0
+	server := &getLogsServer{respond: func(from int64, to int64) string {
+		calls++
+		if calls <= 2 {
+			return "rate limit exceeded, too many requests"
+		}
+		return ""
+	}}
+	l := newTestListener(t, server)
+	start := time.Now()
+	_, end, err := l.filterLogs(context.Background(), 1, 100, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the waits double, 10ms then 20ms
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("retried after: %s", elapsed)
+	}
+	// a rate limit does not shrink the range
+	if end != 100 || savedRange(t, l) != 0 {
+		t.Fatalf("queried end: %d, saved size: %d", end, savedRange(t, l))
+	}
+	if ranges := server.queried(); len(ranges) != 3 {
+		t.Fatalf("queried ranges: %v", ranges)
+	}
+
+	// the retries give up
+	server.respond = func(from int64, to int64) string {
+		return "rate limit exceeded"
+	}
+	if _, _, err = l.filterLogs(context.Background(), 1, 100, nil); err == nil {
+		t.Fatal("expected the rate limit error")
+	}
+	if ranges := server.queried(); len(ranges) != 3+rateRetries+1 {
+		t.Fatalf("queried %d ranges", len(ranges))
+	}
+
+	// a cancelled wait returns
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err = l.filterLogs(ctx, 1, 100, nil); err == nil {
+		t.Fatal("expected the context error")
+	}
+}
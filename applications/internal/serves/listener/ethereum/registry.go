@@ -0,0 +1,154 @@
+package ethereum
+
+import (
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event/message"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// registryEventHashes are the events maintaining the validator registry.
+var registryEventHashes = []string{
+	common.BytesToHash(message.SetWeightHash).Hex(),
+	common.BytesToHash(message.SetValidatorRoleHash).Hex(),
+}
+
+var errInvalidRegistryEvent = errors.New("invalid registry event")
+
+// handleRegistry applies the SetWeight and SetValidatorRole events to the
+// chain_weights and validator_sets tables in chain order.
+func (l *EthereumListener) handleRegistry() {
+	duration := time.Millisecond * time.Duration(l.conf.BlockInterval) * 5
+	for {
+		var events []models.SyncEvent
+		err := l.db.Where("chain_id=? AND event_hash in ? AND status=?", l.conf.ChainId, registryEventHashes, models.EventPending).
+			Order("block_number, block_log_indexed").Limit(500).Find(&events).Error
+		if err != nil {
+			l.logger.Errorf("[Handler.Registry] err: %s\n", err)
+			time.Sleep(duration)
+			continue
+		}
+		if len(events) == 0 {
+			time.Sleep(duration)
+			continue
+		}
+		err = l.db.Transaction(func(tx *gorm.DB) error {
+			for _, event := range events {
+				status := models.EventValid
+				err := l.applyRegistryEvent(tx, event)
+				if errors.Is(err, errInvalidRegistryEvent) {
+					l.logger.Errorf("[Handler.Registry] apply event err: %v, data: %v\n", err, event)
+					status = models.EventInvalid
+				} else if err != nil {
+					return err
+				}
+				err = tx.Model(models.SyncEvent{}).Where("id=?", event.Id).Update("status", status).Error
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			l.logger.Errorf("[Handler.Registry] update registry err: %v\n", err)
+			time.Sleep(duration)
+		}
+	}
+}
+
+func (l *EthereumListener) applyRegistryEvent(tx *gorm.DB, event models.SyncEvent) error {
+	blockchain := models.Blockchain{
+		EventId:     event.Id,
+		BlockTime:   event.BlockTime,
+		BlockNumber: event.BlockNumber,
+		LogIndex:    event.BlockLogIndexed,
+		TxHash:      event.TxHash,
+	}
+	switch event.EventName {
+	case message.SetWeightName:
+		var setWeight message.SetWeight
+		err := (&setWeight).ToObj(event.Data)
+		if err != nil {
+			return errors.Wrap(errInvalidRegistryEvent, err.Error())
+		}
+		var weights []models.ChainWeight
+		err = tx.Where("chain_id=? AND contract_address=? AND from_chain_id=?",
+			event.ChainId, event.ContractAddress, setWeight.ChainId).Limit(1).Find(&weights).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		weight := models.ChainWeight{
+			ChainId:         event.ChainId,
+			ContractAddress: event.ContractAddress,
+			FromChainId:     setWeight.ChainId,
+		}
+		if len(weights) > 0 {
+			if !newer(event, weights[0].Blockchain) {
+				return nil
+			}
+			weight = weights[0]
+		}
+		weight.Weight = setWeight.Weight
+		weight.Blockchain = blockchain
+		return errors.WithStack(tx.Save(&weight).Error)
+	case message.SetValidatorRoleName:
+		var setValidatorRole message.SetValidatorRole
+		err := (&setValidatorRole).ToObj(event.Data)
+		if err != nil {
+			return errors.Wrap(errInvalidRegistryEvent, err.Error())
+		}
+		var sets []models.ValidatorSet
+		err = tx.Where("chain_id=? AND contract_address=? AND from_chain_id=? AND account=?",
+			event.ChainId, event.ContractAddress, setValidatorRole.ChainId, setValidatorRole.Account).Limit(1).Find(&sets).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		set := models.ValidatorSet{
+			ChainId:         event.ChainId,
+			ContractAddress: event.ContractAddress,
+			FromChainId:     setValidatorRole.ChainId,
+			Account:         setValidatorRole.Account,
+		}
+		if len(sets) > 0 {
+			if !newer(event, sets[0].Blockchain) {
+				return nil
+			}
+			set = sets[0]
+		}
+		set.Valid = setValidatorRole.Valid
+		set.Blockchain = blockchain
+		return errors.WithStack(tx.Save(&set).Error)
+	default:
+		return errors.Wrap(errInvalidRegistryEvent, event.EventName)
+	}
+}
+
+// newer reports whether the event comes after the one recorded.
+func newer(event models.SyncEvent, recorded models.Blockchain) bool {
+	if event.BlockNumber != recorded.BlockNumber {
+		return event.BlockNumber > recorded.BlockNumber
+	}
+	return event.BlockLogIndexed > recorded.LogIndex
+}
+
+// rollbackRegistry drops the registry records from the orphaned blocks and
+// replays the remaining registry events to restore the previous values.
+func (l *EthereumListener) rollbackRegistry(tx *gorm.DB, fork int64) error {
+	err := tx.Where("chain_id=? AND block_number>=?", l.conf.ChainId, fork).Delete(&models.ChainWeight{}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.Where("chain_id=? AND block_number>=?", l.conf.ChainId, fork).Delete(&models.ValidatorSet{}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.Model(models.SyncEvent{}).
+		Where("chain_id=? AND event_hash in ? AND status=?", l.conf.ChainId, registryEventHashes, models.EventValid).
+		Update("status", models.EventPending).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
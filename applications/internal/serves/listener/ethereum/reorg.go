@@ -0,0 +1,259 @@
+package ethereum
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/block"
+	"context"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const defaultReorgWindow = 64
+
+func (l *EthereumListener) reorgWindow() int64 {
+	if l.conf.ReorgWindow > 0 {
+		return l.conf.ReorgWindow
+	}
+	return defaultReorgWindow
+}
+
+// checkReorg compares the tracked blocks below start with the canonical chain
+// and returns the first orphaned block number, 0 if nothing was orphaned. The
+// newest tracked block is checked first, the window is fetched in batches only
+// when it was orphaned.
+func (l *EthereumListener) checkReorg(ctx context.Context, start int64) (int64, error) {
+	var blocks []models.SyncBlock
+	err := l.db.Where("chain_id=? AND block_number<?", l.conf.ChainId, start).
+		Order("block_number DESC").Limit(int(l.reorgWindow())).Find(&blocks).Error
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if len(blocks) == 0 {
+		return 0, nil
+	}
+	numbers := make([]int64, 0, len(blocks))
+	for _, syncBlock := range blocks {
+		numbers = append(numbers, syncBlock.BlockNumber)
+	}
+	headers, err := block.HeadersByNumber(ctx, l.rpc, numbers[:1])
+	if err != nil {
+		return 0, err
+	}
+	if headers[0].Hash.Hex() == blocks[0].BlockHash {
+		return 0, nil
+	}
+	rest, err := block.HeadersByNumber(ctx, l.rpc, numbers[1:])
+	if err != nil {
+		return 0, err
+	}
+	headers = append(headers, rest...)
+	fork := int64(0)
+	for i, syncBlock := range blocks {
+		if headers[i].Hash.Hex() == syncBlock.BlockHash {
+			return fork, nil
+		}
+		fork = syncBlock.BlockNumber
+	}
+	l.logger.Errorf("reorg deeper than tracked window, rollback from block: %d", fork)
+	return fork, nil
+}
+
+// trackBlocks fetches the blocks in [start, end] that are inside the reorg
+// window and checks they link to each other and to the tracked parent, it
+// returns their headers too.
+func (l *EthereumListener) trackBlocks(ctx context.Context, start int64, end int64) ([]models.SyncBlock, []*block.Header, error) {
+	from := l.getLatestBlock() - l.reorgWindow() + 1
+	if from < start {
+		from = start
+	}
+	if from > end {
+		return nil, nil, nil
+	}
+	var parent models.SyncBlock
+	err := l.db.Where("chain_id=? AND block_number=?", l.conf.ChainId, from-1).First(&parent).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, errors.WithStack(err)
+	}
+	parentHash := parent.BlockHash
+	numbers := make([]int64, 0, end-from+1)
+	for number := from; number <= end; number++ {
+		numbers = append(numbers, number)
+	}
+	headers, err := block.HeadersByNumber(ctx, l.rpc, numbers)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocks := make([]models.SyncBlock, 0, len(headers))
+	for _, header := range headers {
+		if parentHash != "" && header.ParentHash.Hex() != parentHash {
+			return nil, nil, errors.Errorf("block %d parent hash mismatch, chain reorganised during sync", header.Number)
+		}
+		parentHash = header.Hash.Hex()
+		l.blockTimes.Add(header.Hash, int64(header.Time))
+		blocks = append(blocks, models.SyncBlock{
+			ChainId:     l.conf.ChainId,
+			BlockNumber: int64(header.Number),
+			BlockHash:   header.Hash.Hex(),
+			ParentHash:  header.ParentHash.Hex(),
+		})
+	}
+	return blocks, headers, nil
+}
+
+// saveBlocks stores the tracked blocks, links the events to them and prunes
+// blocks that left the reorg window.
+func (l *EthereumListener) saveBlocks(tx *gorm.DB, blocks []models.SyncBlock, events []*models.SyncEvent) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	err := tx.Where("chain_id=? AND block_number>=? AND block_number<=?",
+		l.conf.ChainId, blocks[0].BlockNumber, blocks[len(blocks)-1].BlockNumber).
+		Delete(&models.SyncBlock{}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = tx.CreateInBatches(&blocks, 100).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	ids := make(map[int64]int64, len(blocks))
+	for _, syncBlock := range blocks {
+		ids[syncBlock.BlockNumber] = syncBlock.Id
+	}
+	for _, event := range events {
+		event.SyncBlockId = ids[event.BlockNumber]
+	}
+	err = tx.Where("chain_id=? AND block_number<?", l.conf.ChainId, l.getLatestBlock()-l.reorgWindow()).
+		Delete(&models.SyncBlock{}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// checkBlockHashes makes sure the logs belong to the tracked blocks.
+func checkBlockHashes(blocks []models.SyncBlock, events []*models.SyncEvent) error {
+	hashes := make(map[int64]string, len(blocks))
+	for _, syncBlock := range blocks {
+		hashes[syncBlock.BlockNumber] = syncBlock.BlockHash
+	}
+	for _, event := range events {
+		hash, ok := hashes[event.BlockNumber]
+		if ok && hash != event.BlockHash {
+			return errors.Errorf("block %d hash mismatch, chain reorganised during sync", event.BlockNumber)
+		}
+	}
+	return nil
+}
+
+// rollback moves the events from the orphaned blocks to the history, drops the
+// messages created from them that were not delivered yet and resets the tasks
+// to re-sync from the fork.
+func (l *EthereumListener) rollback(fork int64) error {
+	return l.db.Transaction(func(tx *gorm.DB) error {
+		var events []models.SyncEvent
+		err := tx.Where("chain_id=? AND block_number>=?", l.conf.ChainId, fork).Find(&events).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if len(events) > 0 {
+			eventIds := make([]int64, 0, len(events))
+			histories := make([]models.SyncEventHistory, 0, len(events))
+			for _, event := range events {
+				eventIds = append(eventIds, event.Id)
+				event.Status = models.EventRollback
+				histories = append(histories, models.SyncEventHistory(event))
+			}
+			err = tx.CreateInBatches(&histories, 100).Error
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			err = tx.Where("id in ?", eventIds).Delete(&models.SyncEvent{}).Error
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var messages []models.Message
+			err = tx.Where("chain_id=? AND event_id in ?", l.conf.ChainId, eventIds).Find(&messages).Error
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			withdraws, err := l.signedWithdraws(tx, messages)
+			if err != nil {
+				return err
+			}
+			messageIds := make([]int64, 0, len(messages))
+			for _, message := range messages {
+				if !message.Undelivered() || withdraws[message.Id] {
+					l.logger.Errorf("message %d delivered before reorg, tx hash: %s", message.Id, message.TxHash)
+					continue
+				}
+				messageIds = append(messageIds, message.Id)
+			}
+			if len(messageIds) > 0 {
+				err = tx.Where("message_id in ?", messageIds).Delete(&models.MessageSignature{}).Error
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				// withdrawals still collecting signatures release their inputs
+				err = tx.Where("withdraw_id in (?)", tx.Model(models.Withdraw{}).Select("id").Where("message_id in ?", messageIds)).
+					Delete(&models.WithdrawSignature{}).Error
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				err = tx.Where("message_id in ?", messageIds).Delete(&models.Withdraw{}).Error
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				err = tx.Where("id in ?", messageIds).Delete(&models.Message{}).Error
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			l.logger.Infof("rollback events: %d, messages: %d", len(eventIds), len(messageIds))
+		}
+
+		err = tx.Where("chain_id=? AND block_number>=?", l.conf.ChainId, fork).Delete(&models.SyncBlock{}).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		err = l.rollbackRegistry(tx, fork)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		// every task of the chain shares the rolled back events, a task done
+		// with the orphaned blocks runs again to index them in the new chain
+		err = tx.Model(models.SyncTask{}).
+			Where("chain_type=? AND chain_id=? AND latest_block>? AND status IN ?", enums.ChainTypeEVM, l.conf.ChainId, fork,
+				[]enums.TaskStatus{enums.TaskStatusPending, enums.TaskStatusDone}).
+			Updates(map[string]interface{}{
+				"latest_block": fork,
+				"status":       enums.TaskStatusPending,
+			}).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	})
+}
+
+// signedWithdraws returns the messages paid out by a withdrawal signed with
+// threshold signatures, the tx may be broadcast so the message is delivered.
+func (l *EthereumListener) signedWithdraws(tx *gorm.DB, messages []models.Message) (map[int64]bool, error) {
+	ids := make([]int64, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.Id)
+	}
+	var messageIds []int64
+	err := tx.Model(models.Withdraw{}).Where("message_id in ? AND status!=?", ids, enums.WithdrawStatusSigning).
+		Pluck("message_id", &messageIds).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	withdraws := make(map[int64]bool, len(messageIds))
+	for _, id := range messageIds {
+		withdraws[id] = true
+	}
+	return withdraws, nil
+}
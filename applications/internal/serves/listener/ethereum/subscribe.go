@@ -0,0 +1,228 @@
+package ethereum
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/block"
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// subscribe keeps a new head and a log subscription open. Every head
+// refreshes the latest block and wakes the task loop, the logs are buffered
+// and serve the task ranges the subscription covers instead of FilterLogs.
+// The events are still indexed once final. The polling loops keep running so
+// a dropped subscription only falls back to the polling latency, the tasks
+// filling the gap from their persisted block.
+func (l *EthereumListener) subscribe() {
+	go l.keepSubscribed("new head", l.subscribeNewHead)
+	l.keepSubscribed("logs", l.subscribeLogs)
+}
+
+func (l *EthereumListener) keepSubscribed(name string, subscribe func() error) {
+	duration := time.Millisecond * time.Duration(l.conf.BlockInterval) * 5
+	for {
+		err := subscribe()
+		l.logger.Errorf("subscribe %s dropped, fallback to polling: %v", name, err)
+		time.Sleep(duration)
+	}
+}
+
+func (l *EthereumListener) subscribeNewHead() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	heads := make(chan *types.Header, 16)
+	sub, err := l.rpc.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer sub.Unsubscribe()
+	// the tasks resume from their persisted block, waking them right after
+	// (re)subscribing fills the gap left while disconnected
+	notify(l.headNotify)
+	for {
+		select {
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return errors.WithStack(err)
+		case head := <-heads:
+			latest := head.Number.Int64() - l.conf.SafeBlockNumber
+			if l.conf.Finality != "" && l.conf.Finality != enums.FinalityConfirmations {
+				latest, err = block.FinalizedNumber(ctx, l.rpc, l.conf.Finality, l.conf.SafeBlockNumber)
+				if err != nil {
+					l.logger.Errorf("sync latest block error: %s", err.Error())
+					continue
+				}
+			}
+			l.setLatestBlock(latest, true)
+			notify(l.headNotify)
+		}
+	}
+}
+
+// subscribeLogs buffers the message and registry logs of every contract, the
+// tasks pick the logs of their contracts.
+func (l *EthereumListener) subscribeLogs() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logs := make(chan types.Log, 256)
+	sub, head, err := l.rpc.SubscribeFilterLogs(ctx, ethereum.FilterQuery{
+		Topics: [][]common.Hash{logTopics},
+	}, logs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer sub.Unsubscribe()
+	l.logBuffer.reset(head + 1)
+	defer l.logBuffer.reset(0)
+	for {
+		select {
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return errors.WithStack(err)
+		case vlog := <-logs:
+			l.logBuffer.add(vlog)
+			l.logBuffer.prune(l.getLatestBlock() - l.reorgWindow())
+		}
+	}
+}
+
+// logBuffer keeps the logs of the log subscription by block number, the
+// blocks from `from` on are complete, 0 while not subscribed.
+type logBuffer struct {
+	mu   sync.Mutex
+	from int64
+	logs map[int64][]types.Log
+}
+
+func newLogBuffer() *logBuffer {
+	return &logBuffer{logs: make(map[int64][]types.Log)}
+}
+
+// reset drops the buffered logs, the blocks are complete from `from` on.
+func (b *logBuffer) reset(from int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.from = from
+	b.logs = make(map[int64][]types.Log)
+}
+
+// add buffers a log, a removed log of an orphaned block is dropped.
+func (b *logBuffer) add(vlog types.Log) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	number := int64(vlog.BlockNumber)
+	list := b.logs[number]
+	for i, one := range list {
+		if one.BlockHash == vlog.BlockHash && one.Index == vlog.Index {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if !vlog.Removed {
+		list = append(list, vlog)
+	}
+	b.logs[number] = list
+}
+
+// prune drops the blocks below number.
+func (b *logBuffer) prune(number int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for n := range b.logs {
+		if n < number {
+			delete(b.logs, n)
+		}
+	}
+}
+
+// covers reports whether the subscription delivered every log from start on.
+func (b *logBuffer) covers(start int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.from > 0 && start >= b.from
+}
+
+// take returns the logs of the contracts in the canonical blocks of the
+// headers, false when the headers do not span [start, end] or a block whose
+// bloom may hold logs of the contracts has none buffered, its logs may still
+// be on the way.
+func (b *logBuffer) take(start int64, end int64, headers []*block.Header, contracts []common.Address) ([]types.Log, bool) {
+	if int64(len(headers)) != end-start+1 || int64(headers[0].Number) != start {
+		return nil, false
+	}
+	addresses := make(map[common.Address]bool, len(contracts))
+	for _, contract := range contracts {
+		addresses[contract] = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.from == 0 || start < b.from {
+		return nil, false
+	}
+	logs := make([]types.Log, 0)
+	for _, header := range headers {
+		found := false
+		for _, vlog := range b.logs[int64(header.Number)] {
+			if vlog.BlockHash == header.Hash && addresses[vlog.Address] {
+				logs = append(logs, vlog)
+				found = true
+			}
+		}
+		if !found && mayHoldLogs(header.Bloom, contracts) {
+			return nil, false
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+	return logs, true
+}
+
+// mayHoldLogs tests the bloom of a block for the indexed events of the contracts.
+func mayHoldLogs(bloom types.Bloom, contracts []common.Address) bool {
+	topic := false
+	for _, hash := range logTopics {
+		if types.BloomLookup(bloom, hash) {
+			topic = true
+			break
+		}
+	}
+	if !topic {
+		return false
+	}
+	for _, contract := range contracts {
+		if types.BloomLookup(bloom, contract) {
+			return true
+		}
+	}
+	return false
+}
+
+// notify wakes the loop waiting on ch without blocking, pending wake ups collapse into one.
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// wait sleeps for duration or until ch is notified.
+func wait(ch chan struct{}, duration time.Duration) {
+	select {
+	case <-ch:
+	case <-time.After(duration):
+	}
+}
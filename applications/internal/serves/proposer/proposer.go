@@ -1,19 +1,20 @@
 package proposer
 
 import (
+	"bsquared.network/message-sharing-applications/internal/adapter"
 	"bsquared.network/message-sharing-applications/internal/config"
 	"bsquared.network/message-sharing-applications/internal/enums"
 	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/registry"
 	"bsquared.network/message-sharing-applications/internal/utils/ethereum/message"
 	"bsquared.network/message-sharing-applications/internal/utils/log"
-	"bsquared.network/message-sharing-applications/internal/utils/tx"
+	"bsquared.network/message-sharing-applications/internal/utils/withdraw"
 	"bsquared.network/message-sharing-applications/internal/vo"
 	"bufio"
 	"context"
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
-	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
@@ -28,25 +29,37 @@ const (
 )
 
 type Proposer struct {
-	conf     config.Blockchain
-	particle config.Particle
-	host     host.Host
-	db       *gorm.DB
-	pk       *ecdsa.PrivateKey
-	client   *vo.RpcClient
+	conf    config.Blockchain
+	host    host.Host
+	db      *gorm.DB
+	pk      *ecdsa.PrivateKey
+	adapter adapter.ChainAdapter
+	// adapters of all chains, the source of the messages paid out by the wallet
+	adapters map[int64]adapter.ChainAdapter
+	wallet   *withdraw.Wallet
+	registry *registry.Registry
 	logger   *log.Logger
 	smap     map[string]common.Address
 	rws      map[string]*bufio.ReadWriter
 }
 
-func NewProposer(pk *ecdsa.PrivateKey, host host.Host, db *gorm.DB, client *vo.RpcClient, logger *log.Logger, conf config.Blockchain, particle config.Particle) *Proposer {
+// messageTarget is the contract a call message is delivered to, every target
+// has its own validator set and signature weight.
+type messageTarget struct {
+	ToChainId       int64
+	ToMessageBridge string
+}
+
+func NewProposer(pk *ecdsa.PrivateKey, host host.Host, db *gorm.DB, adapters map[int64]adapter.ChainAdapter, wallet *withdraw.Wallet, logger *log.Logger, conf config.Blockchain) *Proposer {
 	return &Proposer{
 		conf:     conf,
-		particle: particle,
 		pk:       pk,
 		host:     host,
 		db:       db,
-		client:   client,
+		adapter:  adapters[conf.ChainId],
+		adapters: adapters,
+		wallet:   wallet,
+		registry: registry.NewRegistry(db),
 		logger:   logger,
 		smap:     make(map[string]common.Address, 0),
 		rws:      make(map[string]*bufio.ReadWriter, 0),
@@ -64,6 +77,11 @@ func (p *Proposer) Start() {
 	go p.listen()
 	go p.proposal()
 	go p.submit()
+	if p.wallet != nil {
+		go p.withdraw()
+		go p.proposeWithdraws()
+		go p.finalizeWithdraws()
+	}
 	p.logger.Infof("proposer-node start success ,node-port: %d ,node-id: %s", p.conf.NodePort, p.host.ID())
 	<-ctx.Done()
 }
@@ -128,6 +146,19 @@ func (p *Proposer) accept(sid string, rw *bufio.ReadWriter) {
 					p.logger.Errorf("handle message signature err: %s", err)
 				}
 			}()
+		} else if messageWrap.MessageType == enums.P2PMessageTypeWithdrawSign {
+			var withdrawSignature vo.WithdrawSignature
+			err = json.Unmarshal([]byte(messageWrap.Data), &withdrawSignature)
+			if err != nil {
+				p.logger.Errorf("json unmarshal err: %s", err)
+				continue
+			}
+			go func() {
+				err = p.handleWithdrawSignature(sid, withdrawSignature)
+				if err != nil {
+					p.logger.Errorf("handle withdraw signature err: %s", err)
+				}
+			}()
 		}
 	}
 }
@@ -135,33 +166,58 @@ func (p *Proposer) accept(sid string, rw *bufio.ReadWriter) {
 func (p *Proposer) submit() {
 	for {
 		time.Sleep(time.Second * 3)
-		result := p.db.Model(models.Message{}).
-			Where("status=? AND signatures_count>=?", enums.MessageStatusValidating, p.conf.SignatureWeight).
-			Update("status", enums.MessageStatusPending)
-		if result.Error != nil {
-			p.logger.Errorf("submit message err: %s", result.Error)
+		targets, err := p.validatingTargets()
+		if err != nil {
+			p.logger.Errorf("validating targets err: %s", err)
 			continue
 		}
-		p.logger.Infof("submit message count: %d", result.RowsAffected)
+		for _, target := range targets {
+			weight, err := p.registry.Weight(target.ToChainId, target.ToMessageBridge, p.conf.ChainId, p.conf.SignatureWeight)
+			if err != nil {
+				p.logger.Errorf("get weight err: %s", err)
+				continue
+			}
+			result := p.db.Model(models.Message{}).
+				Where("chain_id=? AND type=? AND to_chain_id=? AND to_message_bridge=? AND status=? AND signatures_count>=?",
+					p.conf.ChainId, enums.MessageTypeCall, target.ToChainId, target.ToMessageBridge, enums.MessageStatusValidating, weight).
+				Update("status", enums.MessageStatusPending)
+			if result.Error != nil {
+				p.logger.Errorf("submit message err: %s", result.Error)
+				continue
+			}
+			p.logger.Infof("submit message count: %d", result.RowsAffected)
+		}
 	}
 }
 
 func (p *Proposer) proposal() {
 	for {
 		time.Sleep(time.Second * 3)
-		list, err := p.getValidatingMessages(p.conf.SignatureWeight, 10)
+		targets, err := p.validatingTargets()
 		if err != nil {
-			p.logger.Errorf("validating call message", err)
+			p.logger.Errorf("validating targets err: %s", err)
 			continue
 		}
-		if len(list) == 0 {
-			p.logger.Info("message length is 0")
-			continue
-		}
-		for _, message := range list {
-			err = p.send(message)
+		for _, target := range targets {
+			weight, err := p.registry.Weight(target.ToChainId, target.ToMessageBridge, p.conf.ChainId, p.conf.SignatureWeight)
+			if err != nil {
+				p.logger.Errorf("get weight err: %s", err)
+				continue
+			}
+			list, err := p.getValidatingMessages(target, weight, 10)
 			if err != nil {
-				p.logger.Errorf("send message err: %s", err)
+				p.logger.Errorf("validating call message err: %s", err)
+				continue
+			}
+			if len(list) == 0 {
+				p.logger.Info("message length is 0")
+				continue
+			}
+			for _, message := range list {
+				err = p.send(message)
+				if err != nil {
+					p.logger.Errorf("send message err: %s", err)
+				}
 			}
 		}
 	}
@@ -180,9 +236,16 @@ func (p *Proposer) handleSignature(sid string, messageSignature vo.MessageSignat
 	if !verify {
 		return errors.New("invalid signature")
 	}
+	validators, err := p.registry.Validators(messageSignature.ToChainId, messageSignature.ToMessageContract, messageSignature.FromChainId, p.conf.Validators)
+	if err != nil {
+		return err
+	}
+	if !registry.Contains(validators, signer.Hex()) {
+		return errors.New("signer not in validator set")
+	}
 	err = p.db.Transaction(func(tx *gorm.DB) error {
 		var count int64
-		err = tx.Model(models.MessageSignature{}).Where("`message_id`=? AND `signer`=?", messageSignature.MessageId, signer.Hex()).Count(&count).Error
+		err = tx.Model(models.MessageSignature{}).Where("message_id=? AND signer=?", messageSignature.MessageId, signer.Hex()).Count(&count).Error
 		if err != nil {
 			return err
 		}
@@ -197,8 +260,8 @@ func (p *Proposer) handleSignature(sid string, messageSignature vo.MessageSignat
 		if err != nil {
 			return err
 		}
-		err = tx.Exec(fmt.Sprintf("UPDATE %s set signatures_count=signatures_count+1 WHERE id =?", models.Message{}.TableName()),
-			messageSignature.MessageId).Error
+		err = tx.Model(models.Message{}).Where("id=?", messageSignature.MessageId).
+			Update("signatures_count", gorm.Expr("signatures_count+1")).Error
 		if err != nil {
 			return err
 		}
@@ -219,14 +282,11 @@ func (p *Proposer) handleLogin(sid string, l vo.Login) error {
 	if t < -60 || t > 60 {
 		return errors.New("invalid timestamp")
 	}
-	var valid bool
-	for _, validator := range p.conf.Validators {
-		if common.HexToAddress(validator) == common.HexToAddress(l.Account) {
-			valid = true
-			break
-		}
+	validators, err := p.registry.SourceValidators(p.conf.ChainId, p.conf.Validators)
+	if err != nil {
+		return err
 	}
-	if !valid {
+	if !registry.Contains(validators, l.Account) {
 		return errors.New("invalid validator account")
 	}
 	verify, err := message.VerifyLogin(l.ChainId, l.Account, l.Timestamp, l.Account, l.Signature)
@@ -242,72 +302,36 @@ func (p *Proposer) handleLogin(sid string, l vo.Login) error {
 }
 
 func (p *Proposer) send(message models.Message) error {
-	if p.client.EthRpc != nil {
-		verify, err := tx.VerifyEthTx(p.client.EthRpc, message.TxHash, message.LogIndex, message.FromMessageBridge, message.FromChainId,
-			message.FromId, message.FromSender, message.ToChainId, message.ToContractAddress, message.ToBytes)
-		if err != nil {
-			p.logger.Errorf("verify eth tx err: %s", err)
-			return err
-		}
-		p.logger.Infof("verify eth tx: %t", verify)
-		if !verify {
-			err = p.db.Model(models.Message{}).
-				Where("id=?", message.Id).
-				Update("status", enums.MessageStatusInvalid).Error
-			if err != nil {
-				p.logger.Errorf("update message err: %s", err)
-				return err
-			}
-			return errors.New("verify message failed")
-		}
-	} else if p.client.BtcRpc != nil {
-		var chainParams *chaincfg.Params
-		if p.conf.Mainnet {
-			chainParams = &chaincfg.MainNetParams
-		} else {
-			chainParams = &chaincfg.TestNet3Params
-		}
-		verify, err := tx.VerifyBtcTx(p.client.BtcRpc, chainParams, p.particle, message.FromMessageBridge, message.TxHash, message.FromId, message.ToBytes)
+	proposal := voMessage(message)
+	verify, err := p.adapter.VerifyMessage(context.Background(), proposal)
+	if err != nil {
+		p.logger.Errorf("verify tx err: %s", err)
+		return err
+	}
+	p.logger.Infof("verify tx: %t", verify)
+	if !verify {
+		err = p.db.Model(models.Message{}).
+			Where("id=?", message.Id).
+			Update("status", enums.MessageStatusInvalid).Error
 		if err != nil {
-			p.logger.Errorf("verify btc tx err: %s", err)
+			p.logger.Errorf("update message err: %s", err)
 			return err
 		}
-		p.logger.Infof("verify btc tx: %t", verify)
-		if !verify {
-			err = p.db.Model(models.Message{}).
-				Where("id=?", message.Id).
-				Update("status", enums.MessageStatusInvalid).Error
-			if err != nil {
-				p.logger.Errorf("update message err: %s", err)
-				return err
-			}
-			return errors.New("verify message failed")
-		}
-	} else {
-		return errors.New("rpc invalid")
+		return errors.New("verify message failed")
 	}
-	proposal := vo.Message{
-		MessageId:           message.Id,
-		ChainId:             message.ToChainId,
-		FromChainId:         message.FromChainId,
-		FromMessageContract: message.FromMessageBridge,
-		FromId:              message.FromId,
-		FromSender:          message.FromSender,
-		ToChainId:           message.ToChainId,
-		ToMessageContract:   message.ToMessageBridge,
-		ToContractAddress:   message.ToContractAddress,
-		Data:                message.ToBytes,
-		TxHash:              message.TxHash,
-		LogIndex:            message.LogIndex,
-	}
-	value, err := json.Marshal(&proposal)
+	return p.broadcast(enums.P2PMessageTypeProposal, &proposal)
+}
+
+// broadcast writes the value to every logged in validator.
+func (p *Proposer) broadcast(messageType enums.P2PMessageType, value interface{}) error {
+	data, err := json.Marshal(value)
 	if err != nil {
 		p.logger.Errorf("json marshal err: %s", err)
 		return err
 	}
 	msg := vo.MessageWrap{
-		MessageType: enums.P2PMessageTypeProposal,
-		Data:        string(value),
+		MessageType: messageType,
+		Data:        string(data),
 	}
 	msgValue, err := json.Marshal(&msg)
 	if err != nil {
@@ -340,10 +364,54 @@ func (p *Proposer) send(message models.Message) error {
 	return nil
 }
 
-func (p *Proposer) getValidatingMessages(weight int64, limit int) ([]models.Message, error) {
+func voMessage(message models.Message) vo.Message {
+	return vo.Message{
+		MessageId:           message.Id,
+		ChainId:             message.ToChainId,
+		FromChainId:         message.FromChainId,
+		FromMessageContract: message.FromMessageBridge,
+		FromId:              message.FromId,
+		FromSender:          message.FromSender,
+		ToChainId:           message.ToChainId,
+		ToMessageContract:   message.ToMessageBridge,
+		ToContractAddress:   message.ToContractAddress,
+		Data:                message.ToBytes,
+		TxHash:              message.TxHash,
+		LogIndex:            message.LogIndex,
+		BlockNumber:         message.BlockNumber,
+	}
+}
+
+func (p *Proposer) validatingTargets() ([]messageTarget, error) {
+	var targets []messageTarget
+	err := p.db.Model(models.Message{}).Distinct("to_chain_id", "to_message_bridge").
+		Where("chain_id=? AND type=? AND status=?", p.conf.ChainId, enums.MessageTypeCall, enums.MessageStatusValidating).
+		Find(&targets).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// messages to utxo chains are paid out by the withdrawals of the proposer of
+	// the utxo chain, a target chain missing from the config may be one of them
+	list := make([]messageTarget, 0, len(targets))
+	for _, target := range targets {
+		chainAdapter, ok := p.adapters[target.ToChainId]
+		if !ok {
+			p.logger.Errorf("target chain %d is not configured, its messages are not proposed, bridge: %s",
+				target.ToChainId, target.ToMessageBridge)
+			continue
+		}
+		if chainAdapter.Network().ChainType == enums.ChainTypeUTXO {
+			continue
+		}
+		list = append(list, target)
+	}
+	return list, nil
+}
+
+func (p *Proposer) getValidatingMessages(target messageTarget, weight int64, limit int) ([]models.Message, error) {
 	var list []models.Message
-	err := p.db.Where("`chain_id`=? AND `type`=? AND `status`=? AND signatures_count<?",
-		p.conf.ChainId, enums.MessageTypeCall, enums.MessageStatusValidating, weight).Limit(limit).Order("signatures_count").Find(&list).Error
+	err := p.db.Where("chain_id=? AND type=? AND to_chain_id=? AND to_message_bridge=? AND status=? AND signatures_count<?",
+		p.conf.ChainId, enums.MessageTypeCall, target.ToChainId, target.ToMessageBridge, enums.MessageStatusValidating, weight).Limit(limit).Order("signatures_count").Find(&list).Error
 	if err != nil {
 		p.logger.Errorf("get message err: %s", err)
 		return nil, errors.WithStack(err)
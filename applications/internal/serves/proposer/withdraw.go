@@ -0,0 +1,379 @@
+package proposer
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/message"
+	"bsquared.network/message-sharing-applications/internal/utils/psbt"
+	"bsquared.network/message-sharing-applications/internal/utils/withdraw"
+	"bsquared.network/message-sharing-applications/internal/vo"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// withdraw builds the psbts paying out the call messages targeting the utxo chain.
+func (p *Proposer) withdraw() {
+	for {
+		time.Sleep(time.Second * 3)
+		var list []models.Message
+		err := p.db.Where("to_chain_id=? AND type=? AND status=?", p.conf.ChainId, enums.MessageTypeCall, enums.MessageStatusValidating).
+			Order("id").Limit(10).Find(&list).Error
+		if err != nil {
+			p.logger.Errorf("get withdraw messages err: %s", err)
+			continue
+		}
+		for _, msg := range list {
+			err = p.buildWithdraw(msg)
+			if err != nil {
+				p.logger.Errorf("build withdraw %d err: %s", msg.Id, err)
+			}
+		}
+	}
+}
+
+func (p *Proposer) buildWithdraw(msg models.Message) error {
+	chainAdapter, ok := p.adapters[msg.FromChainId]
+	if !ok {
+		return fmt.Errorf("no adapter of chain: %d", msg.FromChainId)
+	}
+	if !p.conf.WithdrawAllowed(msg.FromChainId, msg.FromMessageBridge, msg.FromSender) {
+		return p.invalidMessage(msg.Id, "withdraw source not allowed")
+	}
+	verify, err := chainAdapter.VerifyMessage(context.Background(), voMessage(msg))
+	if err != nil {
+		return err
+	}
+	if !verify {
+		return p.invalidMessage(msg.Id, "verify message failed")
+	}
+	to, amount, err := message.DecodeWithdrawData(msg.ToBytes)
+	if err != nil {
+		return p.invalidMessage(msg.Id, err.Error())
+	}
+	exclude, err := p.reservedOutPoints()
+	if err != nil {
+		return err
+	}
+	utxos, err := p.wallet.Unspent(exclude)
+	if err != nil {
+		return err
+	}
+	// a refund pays back the outputs of its deposit, a withdrawal spends the others
+	refund := msg.FromChainId == p.conf.ChainId
+	if refund {
+		utxos = depositOutputs(utxos, msg.TxHash)
+	} else {
+		utxos, err = p.withoutDeposits(utxos)
+		if err != nil {
+			return err
+		}
+	}
+	packet, fee, err := p.wallet.Build(utxos, to, amount)
+	if errors.Is(err, withdraw.ErrInsufficientFunds) && !refund {
+		// retried once deposits or change are confirmed
+		return err
+	} else if err != nil {
+		// a bad address, an amount not covering the fee or the spent outputs of a refund
+		return p.invalidMessage(msg.Id, err.Error())
+	}
+	value, err := packet.B64Encode()
+	if err != nil {
+		return err
+	}
+	inputs := make([]string, 0, len(packet.UnsignedTx.TxIn))
+	for _, in := range packet.UnsignedTx.TxIn {
+		inputs = append(inputs, in.PreviousOutPoint.String())
+	}
+	btcInputs, err := json.Marshal(inputs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(models.Message{}).Where("id=? AND status=?", msg.Id, enums.MessageStatusValidating).
+			Update("status", enums.MessageStatusPending)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if refund {
+			err := tx.Model(models.Deposit{}).Where("id=?", msg.EventId).
+				Update(models.Deposit{}.Column().RefundTxHash, packet.UnsignedTx.TxHash().String()).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(&models.Withdraw{
+			ChainId:     p.conf.ChainId,
+			MessageId:   msg.Id,
+			FromChainId: msg.FromChainId,
+			FromId:      msg.FromId,
+			BtcTo:       to,
+			BtcValue:    amount,
+			BtcFee:      fee,
+			BtcInputs:   string(btcInputs),
+			// the txid of segwit spends does not change with the signatures
+			BtcTxHash: packet.UnsignedTx.TxHash().String(),
+			Psbt:      value,
+			Status:    enums.WithdrawStatusSigning,
+		}).Error
+	})
+}
+
+func (p *Proposer) invalidMessage(id int64, reason string) error {
+	err := p.db.Model(models.Message{}).Where("id=?", id).Update("status", enums.MessageStatusInvalid).Error
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("invalid withdraw message: %s", reason)
+}
+
+// reservedOutPoints returns the inputs of the withdrawals not confirmed yet,
+// the utxo set still lists them until they are mined.
+func (p *Proposer) reservedOutPoints() (map[wire.OutPoint]bool, error) {
+	var list []models.Withdraw
+	err := p.db.Select("btc_inputs").
+		Where("chain_id=? AND status IN ?", p.conf.ChainId,
+			[]enums.WithdrawStatus{enums.WithdrawStatusSigning, enums.WithdrawStatusSigned, enums.WithdrawStatusBroadcast}).
+		Find(&list).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	reserved := make(map[wire.OutPoint]bool)
+	for _, item := range list {
+		var inputs []string
+		err = json.Unmarshal([]byte(item.BtcInputs), &inputs)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, input := range inputs {
+			outPoint, err := wire.NewOutPointFromString(input)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			reserved[*outPoint] = true
+		}
+	}
+	return reserved, nil
+}
+
+// withoutDeposits drops the outputs of the deposits withdrawals must not spend.
+func (p *Proposer) withoutDeposits(utxos []rpcpool.Utxo) ([]rpcpool.Utxo, error) {
+	hashes := make([]string, 0, len(utxos))
+	for _, utxo := range utxos {
+		hashes = append(hashes, utxo.OutPoint.Hash.String())
+	}
+	held, err := withdraw.HeldDeposits(p.db, p.conf.ChainId, hashes)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]rpcpool.Utxo, 0, len(utxos))
+	for _, utxo := range utxos {
+		if !held[utxo.OutPoint.Hash.String()] {
+			list = append(list, utxo)
+		}
+	}
+	return list, nil
+}
+
+// depositOutputs returns the utxos created by the deposit tx.
+func depositOutputs(utxos []rpcpool.Utxo, txHash string) []rpcpool.Utxo {
+	list := make([]rpcpool.Utxo, 0)
+	for _, utxo := range utxos {
+		if common.HexToHash(utxo.OutPoint.Hash.String()) == common.HexToHash(txHash) {
+			list = append(list, utxo)
+		}
+	}
+	return list
+}
+
+// proposeWithdraws sends the withdrawals collecting signatures to the validators.
+func (p *Proposer) proposeWithdraws() {
+	for {
+		time.Sleep(time.Second * 3)
+		var list []models.Withdraw
+		err := p.db.Where("chain_id=? AND status=? AND signatures_count<?", p.conf.ChainId, enums.WithdrawStatusSigning, p.wallet.Threshold()).
+			Order("id").Limit(10).Find(&list).Error
+		if err != nil {
+			p.logger.Errorf("get signing withdraws err: %s", err)
+			continue
+		}
+		for _, item := range list {
+			var msg models.Message
+			err = p.db.Where("id=?", item.MessageId).First(&msg).Error
+			if err != nil {
+				p.logger.Errorf("get withdraw message err: %s", err)
+				continue
+			}
+			err = p.broadcast(enums.P2PMessageTypeWithdrawProposal, &vo.WithdrawProposal{
+				WithdrawId: item.Id,
+				Message:    voMessage(msg),
+				Psbt:       item.Psbt,
+			})
+			if err != nil {
+				p.logger.Errorf("send withdraw err: %s", err)
+			}
+		}
+	}
+}
+
+func (p *Proposer) handleWithdrawSignature(sid string, withdrawSignature vo.WithdrawSignature) error {
+	signer, ok := p.smap[sid]
+	if !ok {
+		return errors.New("no login")
+	}
+	var item models.Withdraw
+	err := p.db.Where("id=? AND chain_id=?", withdrawSignature.WithdrawId, p.conf.ChainId).First(&item).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if item.Status != enums.WithdrawStatusSigning {
+		return nil
+	}
+	packet, err := psbt.ParseBase64(item.Psbt)
+	if err != nil {
+		return err
+	}
+	signed, err := psbt.ParseBase64(withdrawSignature.Psbt)
+	if err != nil {
+		return err
+	}
+	err = p.wallet.Combine(packet, signed, signer)
+	if err != nil {
+		return err
+	}
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err = tx.Model(models.WithdrawSignature{}).Where("withdraw_id=? AND signer=?", item.Id, signer.Hex()).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		err = tx.Create(&models.WithdrawSignature{
+			WithdrawId: item.Id,
+			Signer:     signer.Hex(),
+			Psbt:       withdrawSignature.Psbt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(models.Withdraw{}).Where("id=?", item.Id).
+			Update("signatures_count", gorm.Expr("signatures_count+1")).Error
+	})
+}
+
+// finalizeWithdraws combines the signatures of the withdrawals reaching the
+// threshold and broadcasts the signed txs.
+func (p *Proposer) finalizeWithdraws() {
+	for {
+		time.Sleep(time.Second * 3)
+		var list []models.Withdraw
+		err := p.db.Where("chain_id=? AND status=? AND signatures_count>=?", p.conf.ChainId, enums.WithdrawStatusSigning, p.wallet.Threshold()).
+			Order("id").Limit(10).Find(&list).Error
+		if err != nil {
+			p.logger.Errorf("get signed withdraws err: %s", err)
+			continue
+		}
+		for _, item := range list {
+			err = p.finalizeWithdraw(item)
+			if err != nil {
+				p.logger.Errorf("finalize withdraw %d err: %s", item.Id, err)
+			}
+		}
+		list = make([]models.Withdraw, 0)
+		err = p.db.Where("chain_id=? AND status=?", p.conf.ChainId, enums.WithdrawStatusSigned).
+			Order("id").Limit(10).Find(&list).Error
+		if err != nil {
+			p.logger.Errorf("get finalized withdraws err: %s", err)
+			continue
+		}
+		for _, item := range list {
+			err = p.broadcastWithdraw(item)
+			if err != nil {
+				p.logger.Errorf("broadcast withdraw %d err: %s", item.Id, err)
+			}
+		}
+	}
+}
+
+func (p *Proposer) finalizeWithdraw(item models.Withdraw) error {
+	var signatures []models.WithdrawSignature
+	err := p.db.Where("withdraw_id=?", item.Id).Order("id").Find(&signatures).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	packet, err := psbt.ParseBase64(item.Psbt)
+	if err != nil {
+		return err
+	}
+	for _, signature := range signatures {
+		signed, err := psbt.ParseBase64(signature.Psbt)
+		if err != nil {
+			return err
+		}
+		err = p.wallet.Combine(packet, signed, common.HexToAddress(signature.Signer))
+		if err != nil {
+			return err
+		}
+	}
+	tx, err := p.wallet.Finalize(packet)
+	if err != nil {
+		return err
+	}
+	if tx.TxHash().String() != item.BtcTxHash {
+		return fmt.Errorf("finalized tx hash %s, expected %s", tx.TxHash(), item.BtcTxHash)
+	}
+	var buf bytes.Buffer
+	err = tx.Serialize(&buf)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	value, err := packet.B64Encode()
+	if err != nil {
+		return err
+	}
+	return p.db.Model(models.Withdraw{}).Where("id=? AND status=?", item.Id, enums.WithdrawStatusSigning).
+		Updates(map[string]interface{}{
+			"btc_tx": hex.EncodeToString(buf.Bytes()),
+			"psbt":   value,
+			"status": enums.WithdrawStatusSigned,
+		}).Error
+}
+
+func (p *Proposer) broadcastWithdraw(item models.Withdraw) error {
+	data, err := hex.DecodeString(item.BtcTx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var tx wire.MsgTx
+	err = tx.Deserialize(bytes.NewReader(data))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = p.wallet.Broadcast(&tx)
+	if err != nil {
+		return err
+	}
+	p.logger.Infof("broadcast withdraw %d: %s", item.Id, tx.TxHash())
+	return p.db.Transaction(func(db *gorm.DB) error {
+		err = db.Model(models.Withdraw{}).Where("id=? AND status=?", item.Id, enums.WithdrawStatusSigned).
+			Update("status", enums.WithdrawStatusBroadcast).Error
+		if err != nil {
+			return err
+		}
+		return db.Model(models.Message{}).Where("id=? AND status=?", item.MessageId, enums.MessageStatusPending).
+			Update("status", enums.MessageStatusBroadcast).Error
+	})
+}
@@ -1,18 +1,18 @@
 package validator
 
 import (
+	"bsquared.network/message-sharing-applications/internal/adapter"
 	"bsquared.network/message-sharing-applications/internal/config"
 	"bsquared.network/message-sharing-applications/internal/enums"
 	"bsquared.network/message-sharing-applications/internal/utils/ethereum/message"
 	"bsquared.network/message-sharing-applications/internal/utils/log"
-	"bsquared.network/message-sharing-applications/internal/utils/tx"
+	"bsquared.network/message-sharing-applications/internal/utils/withdraw"
 	"bsquared.network/message-sharing-applications/internal/vo"
 	"bufio"
 	"context"
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
-	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/ethereum/go-ethereum/common"
 	crypto_ "github.com/ethereum/go-ethereum/crypto"
 	"github.com/libp2p/go-libp2p/core/host"
@@ -20,6 +20,8 @@ import (
 	"github.com/libp2p/go-libp2p/core/peerstore"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"sync"
 	"time"
 )
 
@@ -28,23 +30,30 @@ const (
 )
 
 type Validator struct {
-	particle config.Particle
-	conf     config.Blockchain
-	host     host.Host
-	rw       *bufio.ReadWriter
-	pk       *ecdsa.PrivateKey
-	logger   *log.Logger
-	client   *vo.RpcClient
+	conf    config.Blockchain
+	host    host.Host
+	rw      *bufio.ReadWriter
+	pk      *ecdsa.PrivateKey
+	logger  *log.Logger
+	adapter adapter.ChainAdapter
+	// adapters of all chains, the source of the messages paid out by the wallet
+	adapters map[int64]adapter.ChainAdapter
+	wallet   *withdraw.Wallet
+	// db of the validator alone, with the withdrawal txs it signed
+	db *gorm.DB
+	mu sync.Mutex
 }
 
-func NewValidator(pk *ecdsa.PrivateKey, host host.Host, logger *log.Logger, client *vo.RpcClient, particle config.Particle, conf config.Blockchain) *Validator {
+func NewValidator(pk *ecdsa.PrivateKey, host host.Host, db *gorm.DB, logger *log.Logger, adapters map[int64]adapter.ChainAdapter, wallet *withdraw.Wallet, conf config.Blockchain) *Validator {
 	return &Validator{
 		conf:     conf,
-		particle: particle,
 		host:     host,
 		pk:       pk,
 		logger:   logger,
-		client:   client,
+		adapter:  adapters[conf.ChainId],
+		adapters: adapters,
+		wallet:   wallet,
+		db:       db,
 	}
 }
 
@@ -164,37 +173,31 @@ func (v *Validator) accept() {
 					v.logger.Errorf("handle message signature err: %s", err)
 				}
 			}()
+		} else if messageWrap.MessageType == enums.P2PMessageTypeWithdrawProposal {
+			var proposal vo.WithdrawProposal
+			err = json.Unmarshal([]byte(messageWrap.Data), &proposal)
+			if err != nil {
+				v.logger.Errorf("withdraw proposal json unmarshal err: %s", err)
+				continue
+			}
+			go func() {
+				err = v.handleWithdraw(proposal)
+				if err != nil {
+					v.logger.Errorf("handle withdraw err: %s", err)
+				}
+			}()
 		}
 	}
 }
 
 func (v *Validator) handleMessage(msg vo.Message) error {
-	if v.client.EthRpc != nil {
-		verify, err := tx.VerifyEthTx(v.client.EthRpc, msg.TxHash, msg.LogIndex, msg.FromMessageContract, msg.FromChainId, msg.FromId, msg.FromSender, msg.ToChainId, msg.ToContractAddress, msg.Data)
-		if err != nil {
-			v.logger.Errorf("verify eth tx err: %s", err)
-			return err
-		}
-		if !verify {
-			return errors.New("verify message failed")
-		}
-	} else if v.client.BtcRpc != nil {
-		var chainParams *chaincfg.Params
-		if v.conf.Mainnet {
-			chainParams = &chaincfg.MainNetParams
-		} else {
-			chainParams = &chaincfg.TestNet3Params
-		}
-		verify, err := tx.VerifyBtcTx(v.client.BtcRpc, chainParams, v.particle, msg.FromMessageContract, msg.TxHash, msg.FromId, msg.Data)
-		if err != nil {
-			v.logger.Errorf("verify btc tx err: %s", err)
-			return err
-		}
-		if !verify {
-			return errors.New("verify message failed")
-		}
-	} else {
-		return errors.New("rpc invalid")
+	verify, err := v.adapter.VerifyMessage(context.Background(), msg)
+	if err != nil {
+		v.logger.Errorf("verify tx err: %s", err)
+		return err
+	}
+	if !verify {
+		return errors.New("verify message failed")
 	}
 	fmt.Printf("data :%s\n", msg.Data)
 	fmt.Printf("validator :%s\n", crypto_.PubkeyToAddress(v.pk.PublicKey))
@@ -0,0 +1,156 @@
+package validator
+
+import (
+	"bsquared.network/message-sharing-applications/internal/adapter"
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/message"
+	"bsquared.network/message-sharing-applications/internal/utils/psbt"
+	"bsquared.network/message-sharing-applications/internal/vo"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ethereum/go-ethereum/common"
+	crypto_ "github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// handleWithdraw signs the psbt paying out a message targeting the utxo chain,
+// after checking the message on its source chain and the psbt against the wallet.
+func (v *Validator) handleWithdraw(proposal vo.WithdrawProposal) error {
+	if v.wallet == nil {
+		return errors.New("withdrawals are disabled")
+	}
+	msg := proposal.Message
+	if msg.ToChainId != v.conf.ChainId {
+		return fmt.Errorf("invalid withdraw chain id: %d", msg.ToChainId)
+	}
+	chainAdapter, ok := v.adapters[msg.FromChainId]
+	if !ok {
+		return fmt.Errorf("no adapter of chain: %d", msg.FromChainId)
+	}
+	if !v.conf.WithdrawAllowed(msg.FromChainId, msg.FromMessageContract, msg.FromSender) {
+		return fmt.Errorf("withdraw source not allowed: %d#%s#%s", msg.FromChainId, msg.FromMessageContract, msg.FromSender)
+	}
+	verify, err := chainAdapter.VerifyMessage(context.Background(), msg)
+	if err != nil {
+		return err
+	}
+	if !verify {
+		return errors.New("verify message failed")
+	}
+	to, amount, err := message.DecodeWithdrawData(msg.Data)
+	if err != nil {
+		return err
+	}
+	packet, err := psbt.ParseBase64(proposal.Psbt)
+	if err != nil {
+		return err
+	}
+	err = v.wallet.Verify(packet, to, amount)
+	if err != nil {
+		return err
+	}
+	// a refund pays back the outputs of its deposit only, a withdrawal the others
+	if msg.FromChainId == v.conf.ChainId {
+		for _, in := range packet.UnsignedTx.TxIn {
+			if common.HexToHash(in.PreviousOutPoint.Hash.String()) != common.HexToHash(msg.TxHash) {
+				return fmt.Errorf("refund input %s is not an output of %s", in.PreviousOutPoint, msg.TxHash)
+			}
+		}
+	} else {
+		err = v.checkInputs(packet)
+		if err != nil {
+			return err
+		}
+	}
+	err = v.saveSigned(msg, packet.UnsignedTx.TxHash().String())
+	if err != nil {
+		return err
+	}
+	privKey, _ := btcec.PrivKeyFromBytes(crypto_.FromECDSA(v.pk))
+	err = v.wallet.Sign(packet, privKey)
+	if err != nil {
+		return err
+	}
+	value, err := packet.B64Encode()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&vo.WithdrawSignature{
+		WithdrawId: proposal.WithdrawId,
+		Psbt:       value,
+	})
+	if err != nil {
+		v.logger.Errorf("value json marshal err: %s", err)
+		return err
+	}
+	valueWrap, err := json.Marshal(&vo.MessageWrap{
+		MessageType: enums.P2PMessageTypeWithdrawSign,
+		Data:        string(data),
+	})
+	if err != nil {
+		v.logger.Errorf("valueWrap json marshal err: %s", err)
+		return err
+	}
+	v.rw.WriteString(fmt.Sprintf("%s\n", string(valueWrap)))
+	err = v.rw.Flush()
+	if err != nil {
+		v.logger.Errorf("validator flush err: %s", err)
+		return err
+	}
+	return nil
+}
+
+// checkInputs fails when the psbt spends the outputs of a deposit held back
+// from the withdrawals, as read by the validator from the chain.
+func (v *Validator) checkInputs(packet *psbt.Packet) error {
+	bitcoinAdapter, ok := v.adapter.(*adapter.BitcoinAdapter)
+	if !ok {
+		return fmt.Errorf("no bitcoin adapter of chain: %d", v.conf.ChainId)
+	}
+	checked := make(map[chainhash.Hash]bool)
+	for _, in := range packet.UnsignedTx.TxIn {
+		hash := in.PreviousOutPoint.Hash
+		if checked[hash] {
+			continue
+		}
+		held, err := bitcoinAdapter.HeldDeposit(context.Background(), &hash)
+		if err != nil {
+			return err
+		}
+		if held {
+			return fmt.Errorf("withdraw input %s is a held deposit", in.PreviousOutPoint)
+		}
+		checked[hash] = true
+	}
+	return nil
+}
+
+// saveSigned records the tx signed for a message, a message is paid out once,
+// it fails when another tx was signed for the message before.
+func (v *Validator) saveSigned(msg vo.Message, txHash string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var signed models.ValidatorWithdraw
+	err := v.db.Where("chain_id=? AND from_chain_id=? AND from_id=?", v.conf.ChainId, msg.FromChainId, msg.FromId).
+		First(&signed).Error
+	if err == nil {
+		if signed.BtcTxHash != txHash {
+			return fmt.Errorf("message %d#%s already signed in tx %s", msg.FromChainId, msg.FromId, signed.BtcTxHash)
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(v.db.Create(&models.ValidatorWithdraw{
+		ChainId:     v.conf.ChainId,
+		FromChainId: msg.FromChainId,
+		FromId:      msg.FromId,
+		BtcTxHash:   txHash,
+	}).Error)
+}
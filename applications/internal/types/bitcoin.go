@@ -1,11 +1,5 @@
 package types
 
-import (
-	"bytes"
-	"encoding/hex"
-	"github.com/ethereum/go-ethereum/common"
-)
-
 const (
 	BitcoinFromTypeBtc = 0
 	BitcoinFromTypeEvm = 1
@@ -16,6 +10,14 @@ const (
 	BitcoinToTypeNullData = 1
 )
 
+const (
+	BitcoinTokenTypeBtc   = 0
+	BitcoinTokenTypeBrc20 = 1
+	BitcoinTokenTypeRunes = 2
+	// BitcoinTokenTypeInscription is an inscription other than a brc-20 transfer
+	BitcoinTokenTypeInscription = 3
+)
+
 type BitcoinTxParseResult struct {
 	// from is l2 user address, by parse bitcoin get the address
 	From []BitcoinFrom
@@ -31,6 +33,15 @@ type BitcoinTxParseResult struct {
 	Index int64
 	// tos tx all to info
 	Tos []BitcoinTo
+	// token_type is the token deposited next to the btc value
+	TokenType int
+	// token_id is the brc-20 ticker or the rune id
+	TokenId string
+	// token_amount is the token amount in base units
+	TokenAmount string
+	// token_verified tells ord verified the token amount, only such a token
+	// deposit gets a message
+	TokenVerified bool
 }
 
 type BitcoinFrom struct {
@@ -46,29 +57,18 @@ type BitcoinTo struct {
 	NullData string
 }
 
+// ParseEvmAddressFromNullData takes the recipient of the deposit payload as
+// the evm address of the senders.
 func ParseEvmAddressFromNullData(parseResult *BitcoinTxParseResult) (*BitcoinTxParseResult, bool, string, error) {
-	existsEvmAddressData := false // The evm address is processed only if it exists. Otherwise, aa is used
-	parsedEvmAddress := ""        // evm address
-	for _, v := range parseResult.Tos {
-		// only handle first null data
-		if existsEvmAddressData {
-			continue
-		}
-		if v.Type == BitcoinToTypeNullData {
-			decodeNullData, err := hex.DecodeString(v.NullData)
-			if err != nil {
-				continue
-			}
-			evmAddress := bytes.TrimSpace(decodeNullData[1:])
-			if common.IsHexAddress(string(evmAddress)) {
-				existsEvmAddressData = true
-				parsedEvmAddress = string(evmAddress)
-				for k := range parseResult.From {
-					parseResult.From[k].Type = BitcoinFromTypeEvm
-					parseResult.From[k].EvmAddress = parsedEvmAddress
-				}
-			}
-		}
+	payload := FindDepositPayload(parseResult.Tos)
+	if payload == nil {
+		// The evm address is processed only if it exists. Otherwise, aa is used
+		return parseResult, false, "", nil
+	}
+	parsedEvmAddress := payload.Recipient.Hex()
+	for k := range parseResult.From {
+		parseResult.From[k].Type = BitcoinFromTypeEvm
+		parseResult.From[k].EvmAddress = parsedEvmAddress
 	}
-	return parseResult, existsEvmAddressData, parsedEvmAddress, nil
+	return parseResult, true, parsedEvmAddress, nil
 }
@@ -0,0 +1,179 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DepositPayloadLegacy is a null data holding the recipient address as text
+	DepositPayloadLegacy = 0
+	DepositPayloadV1     = 1
+)
+
+const (
+	// payload flags
+	DepositPayloadFlagContract     = 0x01
+	DepositPayloadFlagCallDataHash = 0x02
+)
+
+const depositPayloadChecksumLen = 4
+
+var (
+	DepositPayloadMagic = []byte("b2")
+
+	ErrDepositPayload = errors.New("invalid deposit payload")
+)
+
+// DepositPayload is the OP_RETURN payload of a bitcoin deposit, binary encoded as
+//
+//	magic "b2" | version | flags | to chain id (uint64) | recipient (20 bytes)
+//	| contract (20 bytes, flag 0x01) | calldata hash (32 bytes, flag 0x02) | checksum (4 bytes)
+//
+// the checksum is the first 4 bytes of the double sha256 of the bytes before it.
+type DepositPayload struct {
+	Version   uint8
+	ToChainId int64
+	Recipient common.Address
+	// Contract is the business contract called instead of the contract of the route
+	Contract *common.Address
+	// CallDataHash commits to the calldata the business contract is called with
+	CallDataHash *common.Hash
+}
+
+// Encode encodes a v1 payload.
+func (p *DepositPayload) Encode() []byte {
+	flags := byte(0)
+	if p.Contract != nil {
+		flags |= DepositPayloadFlagContract
+	}
+	if p.CallDataHash != nil {
+		flags |= DepositPayloadFlagCallDataHash
+	}
+	data := append([]byte{}, DepositPayloadMagic...)
+	data = append(data, DepositPayloadV1, flags)
+	data = binary.BigEndian.AppendUint64(data, uint64(p.ToChainId))
+	data = append(data, p.Recipient.Bytes()...)
+	if p.Contract != nil {
+		data = append(data, p.Contract.Bytes()...)
+	}
+	if p.CallDataHash != nil {
+		data = append(data, p.CallDataHash.Bytes()...)
+	}
+	return append(data, chainhash.DoubleHashB(data)[:depositPayloadChecksumLen]...)
+}
+
+// Destination returns the destination of the deposit given the destination of
+// its route. A payload targeting another chain has to name its contract, the
+// message bridge is then left to the bridge of that chain.
+func (p *DepositPayload) Destination(toChainId int64, toMessageBridge string, toContractAddress string) (int64, string, string, bool) {
+	if p == nil || p.Version == DepositPayloadLegacy {
+		return toChainId, toMessageBridge, toContractAddress, true
+	}
+	if p.ToChainId != toChainId {
+		if p.Contract == nil {
+			return 0, "", "", false
+		}
+		return p.ToChainId, "", p.Contract.Hex(), true
+	}
+	if p.Contract != nil {
+		toContractAddress = p.Contract.Hex()
+	}
+	return toChainId, toMessageBridge, toContractAddress, true
+}
+
+// ParseDepositPayload parses the data pushed by an OP_RETURN output.
+func ParseDepositPayload(data []byte) (*DepositPayload, error) {
+	if !bytes.HasPrefix(data, DepositPayloadMagic) {
+		// legacy recipient address
+		recipient := bytes.TrimSpace(data)
+		if !common.IsHexAddress(string(recipient)) {
+			return nil, errors.Wrap(ErrDepositPayload, "unknown format")
+		}
+		return &DepositPayload{
+			Version:   DepositPayloadLegacy,
+			Recipient: common.HexToAddress(string(recipient)),
+		}, nil
+	}
+	size := len(DepositPayloadMagic) + 2 + 8 + common.AddressLength + depositPayloadChecksumLen
+	if len(data) < size {
+		return nil, errors.Wrapf(ErrDepositPayload, "length: %d", len(data))
+	}
+	body, checksum := data[:len(data)-depositPayloadChecksumLen], data[len(data)-depositPayloadChecksumLen:]
+	if !bytes.Equal(chainhash.DoubleHashB(body)[:depositPayloadChecksumLen], checksum) {
+		return nil, errors.Wrap(ErrDepositPayload, "checksum mismatch")
+	}
+	body = body[len(DepositPayloadMagic):]
+	version, flags := body[0], body[1]
+	if version != DepositPayloadV1 {
+		return nil, errors.Wrapf(ErrDepositPayload, "unsupported version: %d", version)
+	}
+	if flags&^(DepositPayloadFlagContract|DepositPayloadFlagCallDataHash) != 0 {
+		return nil, errors.Wrapf(ErrDepositPayload, "unknown flags: %#x", flags)
+	}
+	if flags&DepositPayloadFlagContract != 0 {
+		size += common.AddressLength
+	}
+	if flags&DepositPayloadFlagCallDataHash != 0 {
+		size += common.HashLength
+	}
+	if len(data) != size {
+		return nil, errors.Wrapf(ErrDepositPayload, "length: %d, expected: %d", len(data), size)
+	}
+	body = body[2:]
+	chainId := binary.BigEndian.Uint64(body)
+	if chainId == 0 || chainId > 1<<63-1 {
+		return nil, errors.Wrapf(ErrDepositPayload, "chain id: %d", chainId)
+	}
+	body = body[8:]
+	payload := &DepositPayload{
+		Version:   version,
+		ToChainId: int64(chainId),
+		Recipient: common.BytesToAddress(body[:common.AddressLength]),
+	}
+	body = body[common.AddressLength:]
+	if flags&DepositPayloadFlagContract != 0 {
+		contract := common.BytesToAddress(body[:common.AddressLength])
+		payload.Contract = &contract
+		body = body[common.AddressLength:]
+	}
+	if flags&DepositPayloadFlagCallDataHash != 0 {
+		hash := common.BytesToHash(body[:common.HashLength])
+		payload.CallDataHash = &hash
+	}
+	return payload, nil
+}
+
+// ParseNullDataPayload parses the payload of a null data output, hex of the
+// pk script without the OP_RETURN opcode.
+func ParseNullDataPayload(nullData string) (*DepositPayload, error) {
+	script, err := hex.DecodeString(nullData)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	pushes, err := txscript.PushedData(append([]byte{txscript.OP_RETURN}, script...))
+	if err != nil {
+		return nil, errors.Wrap(ErrDepositPayload, err.Error())
+	}
+	return ParseDepositPayload(bytes.Join(pushes, nil))
+}
+
+// FindDepositPayload returns the payload of the first null data output holding
+// one, nil if there is none.
+func FindDepositPayload(tos []BitcoinTo) *DepositPayload {
+	for _, v := range tos {
+		if v.Type != BitcoinToTypeNullData {
+			continue
+		}
+		payload, err := ParseNullDataPayload(v.NullData)
+		if err == nil {
+			return payload
+		}
+	}
+	return nil
+}
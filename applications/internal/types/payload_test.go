@@ -0,0 +1,186 @@
+package types
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"testing"
+)
+
+var (
+	testRecipient = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testContract  = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testHash      = common.HexToHash("0x3333333333333333333333333333333333333333333333333333333333333333")
+)
+
+// withChecksum replaces the checksum of a payload after its body was changed.
+func withChecksum(data []byte) []byte {
+	body := append([]byte{}, data[:len(data)-depositPayloadChecksumLen]...)
+	return append(body, chainhash.DoubleHashB(body)[:depositPayloadChecksumLen]...)
+}
+
+// nullData returns the null data of an OP_RETURN output pushing data.
+func nullData(t *testing.T, data ...[]byte) string {
+	builder := txscript.NewScriptBuilder()
+	for _, push := range data {
+		builder.AddData(push)
+	}
+	script, err := builder.Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(script)
+}
+
+func TestParseDepositPayload(t *testing.T) {
+	full := (&DepositPayload{ToChainId: 1123, Recipient: testRecipient, Contract: &testContract, CallDataHash: &testHash}).Encode()
+	plain := (&DepositPayload{ToChainId: 1123, Recipient: testRecipient}).Encode()
+
+	for name, test := range map[string]struct {
+		data     []byte
+		expected *DepositPayload
+	}{
+		"recipient": {
+			data:     plain,
+			expected: &DepositPayload{Version: DepositPayloadV1, ToChainId: 1123, Recipient: testRecipient},
+		},
+		"contract": {
+			data:     (&DepositPayload{ToChainId: 1, Recipient: testRecipient, Contract: &testContract}).Encode(),
+			expected: &DepositPayload{Version: DepositPayloadV1, ToChainId: 1, Recipient: testRecipient, Contract: &testContract},
+		},
+		"call data hash": {
+			data:     (&DepositPayload{ToChainId: 1, Recipient: testRecipient, CallDataHash: &testHash}).Encode(),
+			expected: &DepositPayload{Version: DepositPayloadV1, ToChainId: 1, Recipient: testRecipient, CallDataHash: &testHash},
+		},
+		"all flags": {
+			data: full,
+			expected: &DepositPayload{Version: DepositPayloadV1, ToChainId: 1123, Recipient: testRecipient,
+				Contract: &testContract, CallDataHash: &testHash},
+		},
+		"legacy": {
+			data:     []byte(testRecipient.Hex()),
+			expected: &DepositPayload{Version: DepositPayloadLegacy, Recipient: testRecipient},
+		},
+		"legacy lowercase with spaces": {
+			data:     []byte(" " + hex.EncodeToString(testRecipient.Bytes()) + "\n"),
+			expected: &DepositPayload{Version: DepositPayloadLegacy, Recipient: testRecipient},
+		},
+	} {
+		payload, err := ParseDepositPayload(test.data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if payload.Version != test.expected.Version || payload.ToChainId != test.expected.ToChainId ||
+			payload.Recipient != test.expected.Recipient ||
+			(payload.Contract == nil) != (test.expected.Contract == nil) ||
+			(payload.Contract != nil && *payload.Contract != *test.expected.Contract) ||
+			(payload.CallDataHash == nil) != (test.expected.CallDataHash == nil) ||
+			(payload.CallDataHash != nil && *payload.CallDataHash != *test.expected.CallDataHash) {
+			t.Errorf("%s: payload: %+v", name, payload)
+		}
+	}
+}
+
+func TestParseDepositPayloadInvalid(t *testing.T) {
+	full := (&DepositPayload{ToChainId: 1123, Recipient: testRecipient, Contract: &testContract, CallDataHash: &testHash}).Encode()
+	plain := (&DepositPayload{ToChainId: 1123, Recipient: testRecipient}).Encode()
+
+	badChecksum := append([]byte{}, plain...)
+	badChecksum[len(badChecksum)-1] ^= 0xff
+	changed := append([]byte{}, plain...)
+	changed[len(DepositPayloadMagic)+2+8] ^= 0x01
+	version := append([]byte{}, plain...)
+	version[len(DepositPayloadMagic)] = 2
+	flags := append([]byte{}, plain...)
+	flags[len(DepositPayloadMagic)+1] = 0x04
+	// the contract flag set without a contract
+	missing := append([]byte{}, plain...)
+	missing[len(DepositPayloadMagic)+1] = DepositPayloadFlagContract
+	// a hash left after the flags were cleared
+	trailing := append([]byte{}, full...)
+	trailing[len(DepositPayloadMagic)+1] = DepositPayloadFlagContract
+	zeroChain := append([]byte{}, plain...)
+	copy(zeroChain[len(DepositPayloadMagic)+2:], make([]byte, 8))
+	overflowChain := append([]byte{}, plain...)
+	copy(overflowChain[len(DepositPayloadMagic)+2:], bytes.Repeat([]byte{0xff}, 8))
+	magic := append([]byte("b3"), plain[len(DepositPayloadMagic):]...)
+
+	for name, data := range map[string][]byte{
+		"empty":                 nil,
+		"magic only":            DepositPayloadMagic,
+		"truncated":             plain[:len(plain)-1],
+		"truncated before hash": withChecksum(full[:len(full)-common.HashLength]),
+		"bad checksum":          badChecksum,
+		"changed recipient":     changed,
+		"version":               withChecksum(version),
+		"legacy version":        withChecksum(append(append([]byte{}, DepositPayloadMagic...), append([]byte{DepositPayloadLegacy}, plain[3:]...)...)),
+		"unknown flag":          withChecksum(flags),
+		"flag without contract": withChecksum(missing),
+		"trailing bytes":        withChecksum(trailing),
+		"zero chain id":         withChecksum(zeroChain),
+		"chain id overflow":     withChecksum(overflowChain),
+		"wrong magic":           withChecksum(magic),
+		"legacy text":           []byte("not an address"),
+		"legacy short address":  []byte("0x1111"),
+	} {
+		payload, err := ParseDepositPayload(data)
+		if !errors.Is(err, ErrDepositPayload) {
+			t.Errorf("%s: expected an invalid payload, got: %+v, err: %v", name, payload, err)
+		}
+	}
+}
+
+func TestFindDepositPayload(t *testing.T) {
+	payload := (&DepositPayload{ToChainId: 1123, Recipient: testRecipient, Contract: &testContract}).Encode()
+	other := common.HexToAddress("0x4444444444444444444444444444444444444444")
+
+	for name, test := range map[string]struct {
+		tos      []BitcoinTo
+		expected *common.Address
+	}{
+		"none": {
+			tos: []BitcoinTo{{Address: "bc1q", Value: 1000, Type: BitcoinToTypeNormal}},
+		},
+		"payload": {
+			tos: []BitcoinTo{
+				{Address: "bc1q", Value: 1000, Type: BitcoinToTypeNormal},
+				{Type: BitcoinToTypeNullData, NullData: nullData(t, payload)},
+			},
+			expected: &testRecipient,
+		},
+		// the payload may be split over several pushes
+		"split pushes": {
+			tos:      []BitcoinTo{{Type: BitcoinToTypeNullData, NullData: nullData(t, payload[:10], payload[10:])}},
+			expected: &testRecipient,
+		},
+		"legacy": {
+			tos:      []BitcoinTo{{Type: BitcoinToTypeNullData, NullData: nullData(t, []byte(other.Hex()))}},
+			expected: &other,
+		},
+		"first valid": {
+			tos: []BitcoinTo{
+				{Type: BitcoinToTypeNullData, NullData: nullData(t, []byte("memo"))},
+				{Type: BitcoinToTypeNullData, NullData: "zz"},
+				{Type: BitcoinToTypeNullData, NullData: nullData(t, payload)},
+				{Type: BitcoinToTypeNullData, NullData: nullData(t, []byte(other.Hex()))},
+			},
+			expected: &testRecipient,
+		},
+		"invalid": {
+			tos: []BitcoinTo{{Type: BitcoinToTypeNullData, NullData: nullData(t, payload[:len(payload)-1])}},
+		},
+		// a normal output is never read as a payload
+		"normal output": {
+			tos: []BitcoinTo{{Type: BitcoinToTypeNormal, NullData: nullData(t, payload)}},
+		},
+	} {
+		found := FindDepositPayload(test.tos)
+		if (found == nil) != (test.expected == nil) || (found != nil && found.Recipient != *test.expected) {
+			t.Errorf("%s: payload: %+v", name, found)
+		}
+	}
+}
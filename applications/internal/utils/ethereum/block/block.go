@@ -0,0 +1,122 @@
+package block
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+// Header keeps the fields reported by the node, the locally computed header
+// hash is not reliable on every EVM chain.
+type Header struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+	Time       hexutil.Uint64 `json:"timestamp"`
+	Bloom      types.Bloom    `json:"logsBloom"`
+}
+
+// Caller is the part of the rpc client the helpers use, *rpc.Client
+// implements it.
+type Caller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// batchSize keeps a single batch request below the limits of public nodes.
+const batchSize = 100
+
+// HeaderByNumber fetches the header of a block number or tag (latest, safe, finalized).
+func HeaderByNumber(ctx context.Context, client Caller, number rpc.BlockNumber) (*Header, error) {
+	var header *Header
+	err := client.CallContext(ctx, &header, "eth_getBlockByNumber", number.String(), false)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if header == nil {
+		return nil, errors.Errorf("block %s not found", number.String())
+	}
+	return header, nil
+}
+
+// HeadersByNumber fetches the headers of the block numbers with batched requests.
+func HeadersByNumber(ctx context.Context, client Caller, numbers []int64) ([]*Header, error) {
+	args := make([]interface{}, 0, len(numbers))
+	for _, number := range numbers {
+		args = append(args, rpc.BlockNumber(number).String())
+	}
+	return batchHeaders(ctx, client, "eth_getBlockByNumber", args)
+}
+
+// HeadersByHash fetches the headers of the block hashes with batched requests.
+func HeadersByHash(ctx context.Context, client Caller, hashes []common.Hash) ([]*Header, error) {
+	args := make([]interface{}, 0, len(hashes))
+	for _, hash := range hashes {
+		args = append(args, hash)
+	}
+	return batchHeaders(ctx, client, "eth_getBlockByHash", args)
+}
+
+func batchHeaders(ctx context.Context, client Caller, method string, args []interface{}) ([]*Header, error) {
+	headers := make([]*Header, len(args))
+	for start := 0; start < len(args); start += batchSize {
+		end := start + batchSize
+		if end > len(args) {
+			end = len(args)
+		}
+		batch := make([]rpc.BatchElem, 0, end-start)
+		for i := start; i < end; i++ {
+			batch = append(batch, rpc.BatchElem{
+				Method: method,
+				Args:   []interface{}{args[i], false},
+				Result: &headers[i],
+			})
+		}
+		err := client.BatchCallContext(ctx, batch)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for i, elem := range batch {
+			if elem.Error != nil {
+				return nil, errors.WithStack(elem.Error)
+			}
+			if headers[start+i] == nil {
+				return nil, errors.Errorf("block %v not found", args[start+i])
+			}
+		}
+	}
+	return headers, nil
+}
+
+// FinalizedNumber returns the highest block number that is final under the
+// finality mode, confirmations is used when the mode is empty.
+func FinalizedNumber(ctx context.Context, client Caller, finality enums.Finality, confirmations int64) (int64, error) {
+	switch finality {
+	case "", enums.FinalityConfirmations:
+		var latest hexutil.Uint64
+		err := client.CallContext(ctx, &latest, "eth_blockNumber")
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return int64(latest) - confirmations, nil
+	case enums.FinalitySafe:
+		header, err := HeaderByNumber(ctx, client, rpc.SafeBlockNumber)
+		if err != nil {
+			return 0, err
+		}
+		return int64(header.Number), nil
+	case enums.FinalityFinalized:
+		header, err := HeaderByNumber(ctx, client, rpc.FinalizedBlockNumber)
+		if err != nil {
+			return 0, err
+		}
+		return int64(header.Number), nil
+	default:
+		return 0, fmt.Errorf("unsupported finality: %s", finality)
+	}
+}
@@ -1,17 +1,18 @@
 package message
 
 import (
+	"bsquared.network/message-sharing-applications/internal/contracts/messagesharing"
 	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event"
 	"encoding/json"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/shopspring/decimal"
 )
 
 var (
 	MessageCallName = "message#call"
-	MessageCallHash = crypto.Keccak256([]byte("Call(uint256,uint256,address,uint256,address,bytes)"))
+	MessageCallHash = messagesharing.CallEventId.Bytes()
 )
 
 type MessageCall struct {
@@ -40,13 +41,25 @@ func (t *MessageCall) ToObj(data string) error {
 }
 
 func (*MessageCall) Data(log types.Log) (string, error) {
+	call, err := messagesharing.ParseCall(log)
+	if err != nil {
+		return "", err
+	}
+	fromChainId, err := toInt64(call.FromChainId)
+	if err != nil {
+		return "", err
+	}
+	toChainId, err := toInt64(call.ToChainId)
+	if err != nil {
+		return "", err
+	}
 	transfer := &MessageCall{
-		FromChainId:     event.DataToInt64(log, 0),
-		FromId:          event.DataToDecimal(log, 1, 0),
-		FromSender:      event.DataToAddress(log, 2).Hex(),
-		ToChainId:       event.DataToInt64(log, 3),
-		ContractAddress: event.DataToAddress(log, 4).Hex(),
-		Bytes:           event.DataToBytes(log, 5),
+		FromChainId:     fromChainId,
+		FromId:          decimal.NewFromBigInt(call.FromId, 0),
+		FromSender:      call.FromSender.Hex(),
+		ToChainId:       toChainId,
+		ContractAddress: call.ToBusinessContract.Hex(),
+		Bytes:           hexutil.Encode(call.ToMessage),
 	}
 	data, err := event.ToJson(transfer)
 	if err != nil {
@@ -0,0 +1,14 @@
+package message
+
+import (
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+// toInt64 converts an abi uint256 that must fit an int64, such as a chain id.
+func toInt64(value *big.Int) (int64, error) {
+	if value == nil || !value.IsInt64() {
+		return 0, errors.Errorf("value out of int64 range: %v", value)
+	}
+	return value.Int64(), nil
+}
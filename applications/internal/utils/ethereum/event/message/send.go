@@ -1,17 +1,18 @@
 package message
 
 import (
+	"bsquared.network/message-sharing-applications/internal/contracts/messagesharing"
 	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event"
 	"encoding/json"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/shopspring/decimal"
 )
 
 var (
 	MessageSendName = "message#send"
-	MessageSendHash = crypto.Keccak256([]byte("Send(uint256,uint256,address,uint256,address,bytes)"))
+	MessageSendHash = messagesharing.SendEventId.Bytes()
 )
 
 type MessageSend struct {
@@ -40,13 +41,25 @@ func (t *MessageSend) ToObj(data string) error {
 }
 
 func (*MessageSend) Data(log types.Log) (string, error) {
+	send, err := messagesharing.ParseSend(log)
+	if err != nil {
+		return "", err
+	}
+	fromChainId, err := toInt64(send.FromChainId)
+	if err != nil {
+		return "", err
+	}
+	toChainId, err := toInt64(send.ToChainId)
+	if err != nil {
+		return "", err
+	}
 	transfer := &MessageSend{
-		FromChainId:     event.DataToInt64(log, 0),
-		FromId:          event.DataToDecimal(log, 1, 0),
-		FromSender:      event.DataToAddress(log, 2).Hex(),
-		ToChainId:       event.DataToInt64(log, 3),
-		ContractAddress: event.DataToAddress(log, 4).Hex(),
-		Bytes:           event.DataToBytes(log, 5),
+		FromChainId:     fromChainId,
+		FromId:          decimal.NewFromBigInt(send.FromId, 0),
+		FromSender:      send.FromSender.Hex(),
+		ToChainId:       toChainId,
+		ContractAddress: send.ToBusinessContract.Hex(),
+		Bytes:           hexutil.Encode(send.ToMessage),
 	}
 	data, err := event.ToJson(transfer)
 	if err != nil {
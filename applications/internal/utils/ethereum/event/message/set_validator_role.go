@@ -0,0 +1,57 @@
+package message
+
+import (
+	"bsquared.network/message-sharing-applications/internal/contracts/messagesharing"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	SetValidatorRoleName = "message#set_validator_role"
+	SetValidatorRoleHash = messagesharing.SetValidatorRoleEventId.Bytes()
+)
+
+type SetValidatorRole struct {
+	ChainId int64  `json:"chain_id"`
+	Account string `json:"account"`
+	Valid   bool   `json:"valid"`
+}
+
+func (*SetValidatorRole) Name() string {
+	return SetValidatorRoleName
+}
+
+func (*SetValidatorRole) EventHash() common.Hash {
+	return common.BytesToHash(SetValidatorRoleHash)
+}
+
+func (t *SetValidatorRole) ToObj(data string) error {
+	err := json.Unmarshal([]byte(data), &t)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*SetValidatorRole) Data(log types.Log) (string, error) {
+	parsed, err := messagesharing.ParseSetValidatorRole(log)
+	if err != nil {
+		return "", err
+	}
+	chainId, err := toInt64(parsed.ChainId)
+	if err != nil {
+		return "", err
+	}
+	setValidatorRole := &SetValidatorRole{
+		ChainId: chainId,
+		Account: parsed.Account.Hex(),
+		Valid:   parsed.Valid,
+	}
+	data, err := event.ToJson(setValidatorRole)
+	if err != nil {
+		return "", err
+	}
+	return data, nil
+}
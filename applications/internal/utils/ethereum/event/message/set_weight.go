@@ -0,0 +1,59 @@
+package message
+
+import (
+	"bsquared.network/message-sharing-applications/internal/contracts/messagesharing"
+	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	SetWeightName = "message#set_weight"
+	SetWeightHash = messagesharing.SetWeightEventId.Bytes()
+)
+
+type SetWeight struct {
+	ChainId int64 `json:"chain_id"`
+	Weight  int64 `json:"weight"`
+}
+
+func (*SetWeight) Name() string {
+	return SetWeightName
+}
+
+func (*SetWeight) EventHash() common.Hash {
+	return common.BytesToHash(SetWeightHash)
+}
+
+func (t *SetWeight) ToObj(data string) error {
+	err := json.Unmarshal([]byte(data), &t)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (*SetWeight) Data(log types.Log) (string, error) {
+	parsed, err := messagesharing.ParseSetWeight(log)
+	if err != nil {
+		return "", err
+	}
+	chainId, err := toInt64(parsed.ChainId)
+	if err != nil {
+		return "", err
+	}
+	weight, err := toInt64(parsed.Weight)
+	if err != nil {
+		return "", err
+	}
+	setWeight := &SetWeight{
+		ChainId: chainId,
+		Weight:  weight,
+	}
+	data, err := event.ToJson(setWeight)
+	if err != nil {
+		return "", err
+	}
+	return data, nil
+}
@@ -1,9 +1,11 @@
 package message
 
 import (
+	"bsquared.network/message-sharing-applications/internal/contracts/messagesharing"
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -167,7 +169,6 @@ func VerifyLogin(chainId int64, account string, timestamp int64, signer, signatu
 		_signature,
 	)
 	if err != nil || !verify {
-		fmt.Errorf("Verify signature error: %v\n", err)
 		return false, errors.New("Verify signature failed")
 	}
 	return true, nil
@@ -222,62 +223,19 @@ func VerifyMessageSend(chainId int64, messageContract string, fromChainId int64,
 		_signature,
 	)
 	if err != nil || !verify {
-		fmt.Errorf("Verify signature error: %v\n", err)
 		return false, errors.New("Verify signature failed")
 	}
 	return true, nil
 }
 
-func Send(fromChainId int64, fromId *big.Int, fromSender string, contractAddress string, toBytes string, signatures []string) []byte {
-	// function send(uint256 from_chain_id, uint256 from_id, address from_sender, address contract_address, bytes calldata data, bytes[] calldata signatures) external
-	Method := crypto.Keccak256([]byte("send(uint256,uint256,address,address,bytes,bytes[])"))[:4]
-	FromChainId := common.BytesToHash(big.NewInt(fromChainId).Bytes()).Bytes()
-	FromId := common.BytesToHash(fromId.Bytes()).Bytes()
-	FromSender := common.BytesToHash(common.HexToAddress(fromSender).Bytes()).Bytes()
-	ContractAddress := common.BytesToHash(common.HexToAddress(contractAddress).Bytes()).Bytes()
-
-	ToBytes := common.FromHex(toBytes)
-	ToBytesDataOffset := common.BytesToHash(big.NewInt(192).Bytes()).Bytes()
-	ToBytesDataLength := common.BytesToHash(big.NewInt(int64(len(ToBytes))).Bytes()).Bytes()
-
-	if len(ToBytes)%32 > 0 {
-		ToBytes = append(ToBytes, make([]byte, 32-len(ToBytes)%32)...)
-	}
-
-	SignaturesDataOffset := common.BytesToHash(big.NewInt(int64(224 + len(ToBytes))).Bytes()).Bytes()
-	SignaturesDataLength := common.BytesToHash(big.NewInt(int64(len(signatures))).Bytes()).Bytes()
-
-	var streamOffsets []byte
-	var streamData []byte
-	streamIndex := int64(32 * len(signatures))
-	for _, _signature := range signatures {
-		signatureDataOffset := common.BytesToHash(big.NewInt(streamIndex).Bytes()).Bytes()
-		streamOffsets = append(streamOffsets, signatureDataOffset...)
-
-		signature := common.FromHex(_signature)
-		signatureDataLength := common.BytesToHash(big.NewInt(int64(len(signature))).Bytes()).Bytes()
-		streamData = append(streamData, signatureDataLength...)
-		if len(signature)%32 > 0 {
-			signature = append(signature, make([]byte, 32-len(signature)%32)...)
-		}
-		streamData = append(streamData, signature...)
-		streamIndex = int64(32*len(signatures) + len(streamData))
+// Send encodes the calldata of MessageSharing.send from the message fields.
+func Send(fromChainId int64, fromId *big.Int, fromSender string, contractAddress string, toBytes string, signatures []string) ([]byte, error) {
+	_signatures := make([][]byte, 0, len(signatures))
+	for _, signature := range signatures {
+		_signatures = append(_signatures, common.FromHex(signature))
 	}
-
-	var stream []byte
-	stream = append(stream, Method...)
-	stream = append(stream, FromChainId...)
-	stream = append(stream, FromId...)
-	stream = append(stream, FromSender...)
-	stream = append(stream, ContractAddress...)
-	stream = append(stream, ToBytesDataOffset...)
-	stream = append(stream, SignaturesDataOffset...)
-	stream = append(stream, ToBytesDataLength...)
-	stream = append(stream, ToBytes...)
-	stream = append(stream, SignaturesDataLength...)
-	stream = append(stream, streamOffsets...)
-	stream = append(stream, streamData...)
-	return stream
+	return messagesharing.PackSend(big.NewInt(fromChainId), fromId, common.HexToAddress(fromSender),
+		common.HexToAddress(contractAddress), common.FromHex(toBytes), _signatures)
 }
 
 func EncodeSendData(txId string, fromAddress string, toAddress string, amount decimal.Decimal) []byte {
@@ -303,3 +261,101 @@ func EncodeSendData(txId string, fromAddress string, toAddress string, amount de
 
 	return stream
 }
+
+var callDataArguments = abi.Arguments{
+	{Type: mustType("bytes32")},
+	{Type: mustType("string")},
+	{Type: mustType("address")},
+	{Type: mustType("uint256")},
+	{Type: mustType("bytes32")},
+}
+
+// EncodeCallData encodes the data of a deposit calling a business contract,
+// abi.encode(bytes32 txId, string from, address to, uint256 amount, bytes32 callDataHash).
+func EncodeCallData(txId string, fromAddress string, toAddress string, amount decimal.Decimal, callDataHash common.Hash) ([]byte, error) {
+	data, err := callDataArguments.Pack(common.HexToHash(txId), fromAddress, common.HexToAddress(toAddress),
+		amount.BigInt(), callDataHash)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+// EncodeDepositData encodes the data of a bitcoin deposit message, a payload
+// committing to calldata calls its business contract with EncodeCallData.
+func EncodeDepositData(txId string, fromAddress string, toAddress string, amount decimal.Decimal, callDataHash *common.Hash) ([]byte, error) {
+	if callDataHash != nil {
+		return EncodeCallData(txId, fromAddress, toAddress, amount, *callDataHash)
+	}
+	return EncodeSendData(txId, fromAddress, toAddress, amount), nil
+}
+
+var tokenDataArguments = abi.Arguments{
+	{Type: mustType("bytes32")},
+	{Type: mustType("string")},
+	{Type: mustType("address")},
+	{Type: mustType("uint256")},
+	{Type: mustType("uint8")},
+	{Type: mustType("string")},
+	{Type: mustType("uint256")},
+	{Type: mustType("bytes32")},
+}
+
+func mustType(t string) abi.Type {
+	_type, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return _type
+}
+
+// EncodeTokenData encodes the data of a brc-20 or runes deposit message,
+// abi.encode(bytes32 txId, string from, address to, uint256 amount, uint8 tokenType,
+// string tokenId, uint256 tokenAmount, bytes32 callDataHash), amount is the btc
+// value carrying the token and the call data hash is zero without a commitment.
+func EncodeTokenData(txId string, fromAddress string, toAddress string, amount decimal.Decimal, tokenType int,
+	tokenId string, tokenAmount *big.Int, callDataHash *common.Hash) ([]byte, error) {
+	var _callDataHash common.Hash
+	if callDataHash != nil {
+		_callDataHash = *callDataHash
+	}
+	data, err := tokenDataArguments.Pack(common.HexToHash(txId), fromAddress, common.HexToAddress(toAddress),
+		amount.BigInt(), uint8(tokenType), tokenId, tokenAmount, _callDataHash)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+var withdrawDataArguments = abi.Arguments{
+	{Type: mustType("string")},
+	{Type: mustType("uint256")},
+}
+
+// EncodeWithdrawData encodes the data of a call message paying out bitcoin,
+// abi.encode(string to, uint256 amount), the amount is in satoshis.
+func EncodeWithdrawData(to string, amount int64) ([]byte, error) {
+	data, err := withdrawDataArguments.Pack(to, big.NewInt(amount))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+// DecodeWithdrawData decodes the hex data of a withdrawal message into the
+// bitcoin address and amount.
+func DecodeWithdrawData(data string) (string, int64, error) {
+	values, err := withdrawDataArguments.Unpack(common.FromHex(data))
+	if err != nil {
+		return "", 0, errors.WithStack(err)
+	}
+	to, ok := values[0].(string)
+	if !ok {
+		return "", 0, errors.New("invalid withdraw address")
+	}
+	amount, ok := values[1].(*big.Int)
+	if !ok || !amount.IsInt64() || amount.Sign() <= 0 {
+		return "", 0, errors.New("invalid withdraw amount")
+	}
+	return to, amount.Int64(), nil
+}
@@ -0,0 +1,183 @@
+package ord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const clientTimeout = 30 * time.Second
+
+var (
+	// ErrNotIndexed is an output of a block ord has not indexed yet
+	ErrNotIndexed = errors.New("output not indexed by ord")
+	// ErrNotAcceptable is a content ord serves compressed only
+	ErrNotAcceptable = errors.New("content not acceptable")
+)
+
+// Client reads the json api of an ord server, e.g. http://127.0.0.1:80, which
+// indexes the runes and the inscriptions of the chain.
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+func NewClient(url string) *Client {
+	return &Client{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: clientTimeout},
+	}
+}
+
+// Output is what ord knows of an output, the runes are keyed by spaced name
+// with their amount in base units.
+type Output struct {
+	Inscriptions []string
+	Runes        map[string]*big.Int
+}
+
+// Output returns the runes and the inscriptions an output holds, it fails with
+// ErrNotIndexed until ord indexed the block of the output.
+func (c *Client) Output(outPoint wire.OutPoint) (*Output, error) {
+	body, _, err := c.get("/output/" + outPoint.String())
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Indexed      bool            `json:"indexed"`
+		Inscriptions []string        `json:"inscriptions"`
+		Runes        json.RawMessage `json:"runes"`
+	}
+	err = unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Indexed {
+		return nil, errors.Wrap(ErrNotIndexed, outPoint.String())
+	}
+	runes, err := parseRunes(result.Runes)
+	if err != nil {
+		return nil, err
+	}
+	return &Output{
+		Inscriptions: result.Inscriptions,
+		Runes:        runes,
+	}, nil
+}
+
+// parseRunes decodes the runes of an output, a map of ord 0.21 and later or a
+// list of name and balance pairs of older versions.
+func parseRunes(raw json.RawMessage) (map[string]*big.Int, error) {
+	type balance struct {
+		Amount json.Number `json:"amount"`
+	}
+	runes := make(map[string]*big.Int)
+	if len(raw) == 0 || string(raw) == "null" {
+		return runes, nil
+	}
+	balances := make(map[string]balance)
+	if raw[0] == '[' {
+		var pairs [][2]json.RawMessage
+		err := unmarshal(raw, &pairs)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			var name string
+			var value balance
+			err = unmarshal(pair[0], &name)
+			if err != nil {
+				return nil, err
+			}
+			err = unmarshal(pair[1], &value)
+			if err != nil {
+				return nil, err
+			}
+			balances[name] = value
+		}
+	} else {
+		err := unmarshal(raw, &balances)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for name, value := range balances {
+		amount, ok := new(big.Int).SetString(value.Amount.String(), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid rune amount: %s %s", name, value.Amount)
+		}
+		runes[name] = amount
+	}
+	return runes, nil
+}
+
+// RuneName returns the spaced name of a rune.
+func (c *Client) RuneName(id RuneId) (string, error) {
+	body, _, err := c.get("/rune/" + id.String())
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Entry struct {
+			SpacedRune string `json:"spaced_rune"`
+		} `json:"entry"`
+	}
+	err = unmarshal(body, &result)
+	if err != nil {
+		return "", err
+	}
+	if result.Entry.SpacedRune == "" {
+		return "", fmt.Errorf("rune not found: %s", id)
+	}
+	return result.Entry.SpacedRune, nil
+}
+
+// Content returns an inscription with its content, it fails with
+// ErrNotAcceptable for a content ord only serves compressed.
+func (c *Client) Content(id string) (*Inscription, error) {
+	body, header, err := c.get("/content/" + id)
+	if err != nil {
+		return nil, err
+	}
+	return &Inscription{
+		ContentType: header.Get("Content-Type"),
+		Body:        body,
+	}, nil
+}
+
+func (c *Client) get(path string) ([]byte, http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	if resp.StatusCode == http.StatusNotAcceptable {
+		return nil, nil, errors.Wrap(ErrNotAcceptable, path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("ord status %d: %s %s", resp.StatusCode, path, strings.TrimSpace(string(data)))
+	}
+	return data, resp.Header, nil
+}
+
+func unmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return errors.WithStack(decoder.Decode(v))
+}
@@ -0,0 +1,148 @@
+package ord
+
+import (
+	"bsquared.network/message-sharing-applications/internal/types"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"math/big"
+	"sort"
+)
+
+// Deposit is the token a tx deposits to the listen address.
+type Deposit struct {
+	// Type is one of types.BitcoinTokenTypeBrc20, BitcoinTokenTypeRunes,
+	// BitcoinTokenTypeInscription
+	Type int
+	// Id is the ticker of a brc-20 token, the block:tx id of a rune or its
+	// spaced name when the runestone does not send it, the id of an inscription
+	Id     string
+	Amount *big.Int
+	// Verified tells the amount is the balance ord indexed for the listen
+	// outputs, a rune sent by the edicts of the runestone alone. Inscriptions,
+	// brc-20 transfers included, are not verified, ord tracks no brc-20 balance.
+	Verified bool
+}
+
+// ParseDeposit returns the token a tx deposits to the outputs paying the listen
+// address, nil for a btc deposit. The runes and the inscriptions the outputs
+// hold are read from ord, it fails with ErrNotIndexed until ord indexed the tx.
+func ParseDeposit(indexer *Client, tx *wire.MsgTx, listen []bool) (*Deposit, error) {
+	runes := make(map[string]*big.Int)
+	inscriptions := make([]string, 0)
+	for i, ok := range listen {
+		if !ok {
+			continue
+		}
+		output, err := indexer.Output(wire.OutPoint{Hash: tx.TxHash(), Index: uint32(i)})
+		if err != nil {
+			return nil, err
+		}
+		inscriptions = append(inscriptions, output.Inscriptions...)
+		for name, amount := range output.Runes {
+			if _, ok := runes[name]; !ok {
+				runes[name] = new(big.Int)
+			}
+			runes[name].Add(runes[name], amount)
+		}
+	}
+	if len(inscriptions) > 0 {
+		return parseInscriptionDeposit(indexer, inscriptions)
+	}
+	if len(runes) > 0 {
+		return parseRunesDeposit(indexer, tx, listen, runes)
+	}
+	return nil, nil
+}
+
+// parseRunesDeposit takes the rune the edicts of the runestone send to the
+// listen outputs, with the balance ord indexed for them. Runes the outputs got
+// otherwise, by default or next to another rune, leave the deposit unverified.
+func parseRunesDeposit(indexer *Client, tx *wire.MsgTx, listen []bool, runes map[string]*big.Int) (*Deposit, error) {
+	id, err := edictRune(tx, listen)
+	if err != nil {
+		return nil, err
+	}
+	if id != nil && len(runes) == 1 {
+		name, err := indexer.RuneName(*id)
+		if err != nil {
+			return nil, err
+		}
+		if amount, ok := runes[name]; ok {
+			return &Deposit{
+				Type:     types.BitcoinTokenTypeRunes,
+				Id:       id.String(),
+				Amount:   amount,
+				Verified: true,
+			}, nil
+		}
+	}
+	names := make([]string, 0, len(runes))
+	for name := range runes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &Deposit{
+		Type:   types.BitcoinTokenTypeRunes,
+		Id:     names[0],
+		Amount: runes[names[0]],
+	}, nil
+}
+
+// edictRune returns the first rune a non zero edict of the runestone sends to
+// a listen output, nil if there is none. Edicts of a rune etched by the tx
+// itself are not counted, a cenotaph burns its runes.
+func edictRune(tx *wire.MsgTx, listen []bool) (*RuneId, error) {
+	runestone, err := ParseRunestone(tx)
+	if err != nil {
+		if errors.Is(err, ErrCenotaph) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if runestone == nil {
+		return nil, nil
+	}
+	for _, edict := range runestone.Edicts {
+		if edict.Amount.Sign() == 0 || edict.Id == (RuneId{}) {
+			continue
+		}
+		if int(edict.Output) == len(tx.TxOut) {
+			for i, out := range tx.TxOut {
+				// the runestone itself is not standard null data
+				if listen[i] && (len(out.PkScript) == 0 || out.PkScript[0] != txscript.OP_RETURN) {
+					return &edict.Id, nil
+				}
+			}
+		} else if listen[edict.Output] {
+			return &edict.Id, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseInscriptionDeposit records the inscriptions the listen outputs hold as
+// an unverified deposit, the first one names it, a brc-20 transfer by its tick.
+func parseInscriptionDeposit(indexer *Client, inscriptions []string) (*Deposit, error) {
+	deposit := &Deposit{
+		Type:   types.BitcoinTokenTypeInscription,
+		Id:     inscriptions[0],
+		Amount: big.NewInt(int64(len(inscriptions))),
+	}
+	inscription, err := indexer.Content(inscriptions[0])
+	if err != nil {
+		if errors.Is(err, ErrNotAcceptable) {
+			return deposit, nil
+		}
+		return nil, err
+	}
+	transfer, err := inscription.Brc20Transfer()
+	if err != nil || transfer == nil {
+		return deposit, nil
+	}
+	return &Deposit{
+		Type:   types.BitcoinTokenTypeBrc20,
+		Id:     transfer.Tick,
+		Amount: transfer.Amount,
+	}, nil
+}
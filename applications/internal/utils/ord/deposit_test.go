@@ -0,0 +1,127 @@
+package ord
+
+import (
+	"bsquared.network/message-sharing-applications/internal/types"
+	"fmt"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ordServer is an ord stand-in serving the outputs, runes and contents given.
+func ordServer(t *testing.T, outputs map[string]string, contents map[string]string) *Client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/output/", func(w http.ResponseWriter, r *http.Request) {
+		output, ok := outputs[r.URL.Path[len("/output/"):]]
+		if !ok {
+			output = `{"indexed":true,"inscriptions":[],"runes":{}}`
+		}
+		fmt.Fprint(w, output)
+	})
+	mux.HandleFunc("/rune/840000:3", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"entry":{"spaced_rune":"UNCOMMON•GOODS"},"id":"840000:3"}`)
+	})
+	mux.HandleFunc("/content/", func(w http.ResponseWriter, r *http.Request) {
+		content, ok := contents[r.URL.Path[len("/content/"):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		fmt.Fprint(w, content)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL + "/")
+}
+
+func TestParseDeposit(t *testing.T) {
+	// the runestone sends 90 of 840000:3 to the listen output 2
+	runes := runestoneTx(runestoneScript(integers(0, 840000, 3, 90, 2)), 2)
+	split := runestoneTx(runestoneScript(integers(0, 840000, 3, 90, 3)), 2)
+	plain := runestoneTx(append([]byte{0x00, 0x14}, make([]byte, 20)...), 2)
+	listen := []bool{false, false, true}
+	out := func(tx *wire.MsgTx) string {
+		return wire.OutPoint{Hash: tx.TxHash(), Index: 2}.String()
+	}
+	inscription := plain.TxHash().String() + "i0"
+
+	for name, test := range map[string]struct {
+		tx       *wire.MsgTx
+		outputs  map[string]string
+		contents map[string]string
+		expected *Deposit
+	}{
+		"btc": {tx: plain},
+		"rune": {
+			tx:       runes,
+			outputs:  map[string]string{out(runes): `{"indexed":true,"runes":{"UNCOMMON•GOODS":{"amount":90,"divisibility":0,"symbol":"⧉"}}}`},
+			expected: &Deposit{Type: types.BitcoinTokenTypeRunes, Id: "840000:3", Amount: bigInt("90"), Verified: true},
+		},
+		"split rune": {
+			tx:       split,
+			outputs:  map[string]string{out(split): `{"indexed":true,"runes":{"UNCOMMON•GOODS":{"amount":45}}}`},
+			expected: &Deposit{Type: types.BitcoinTokenTypeRunes, Id: "840000:3", Amount: bigInt("45"), Verified: true},
+		},
+		// ord before 0.21 lists name and balance pairs
+		"rune pairs": {
+			tx:       runes,
+			outputs:  map[string]string{out(runes): `{"indexed":true,"runes":[["UNCOMMON•GOODS",{"amount":90}]]}`},
+			expected: &Deposit{Type: types.BitcoinTokenTypeRunes, Id: "840000:3", Amount: bigInt("90"), Verified: true},
+		},
+		"rune without edict": {
+			tx:       plain,
+			outputs:  map[string]string{out(plain): `{"indexed":true,"runes":{"UNCOMMON•GOODS":{"amount":90}}}`},
+			expected: &Deposit{Type: types.BitcoinTokenTypeRunes, Id: "UNCOMMON•GOODS", Amount: bigInt("90")},
+		},
+		"other rune": {
+			tx:       runes,
+			outputs:  map[string]string{out(runes): `{"indexed":true,"runes":{"DOG•GO•TO•THE•MOON":{"amount":90}}}`},
+			expected: &Deposit{Type: types.BitcoinTokenTypeRunes, Id: "DOG•GO•TO•THE•MOON", Amount: bigInt("90")},
+		},
+		"brc-20": {
+			tx:       plain,
+			outputs:  map[string]string{out(plain): `{"indexed":true,"inscriptions":["` + inscription + `"]}`},
+			contents: map[string]string{inscription: `{"p":"brc-20","op":"transfer","tick":"ORDI","amt":"12.5"}`},
+			expected: &Deposit{Type: types.BitcoinTokenTypeBrc20, Id: "ordi", Amount: bigInt("12500000000000000000")},
+		},
+		"inscription": {
+			tx:       plain,
+			outputs:  map[string]string{out(plain): `{"indexed":true,"inscriptions":["` + inscription + `"]}`},
+			contents: map[string]string{inscription: "hello"},
+			expected: &Deposit{Type: types.BitcoinTokenTypeInscription, Id: inscription, Amount: bigInt("1")},
+		},
+		"compressed inscription": {
+			tx:       plain,
+			outputs:  map[string]string{out(plain): `{"indexed":true,"inscriptions":["` + inscription + `"]}`},
+			expected: &Deposit{Type: types.BitcoinTokenTypeInscription, Id: inscription, Amount: bigInt("1")},
+		},
+	} {
+		deposit, err := ParseDeposit(ordServer(t, test.outputs, test.contents), test.tx, listen)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if (deposit == nil) != (test.expected == nil) {
+			t.Errorf("%s: deposit: %+v", name, deposit)
+			continue
+		}
+		if deposit != nil && (deposit.Type != test.expected.Type || deposit.Id != test.expected.Id ||
+			deposit.Amount.Cmp(test.expected.Amount) != 0 || deposit.Verified != test.expected.Verified) {
+			t.Errorf("%s: deposit: %+v", name, deposit)
+		}
+	}
+
+	indexer := ordServer(t, map[string]string{out(runes): `{"indexed":false}`}, nil)
+	if _, err := ParseDeposit(indexer, runes, listen); !errors.Is(err, ErrNotIndexed) {
+		t.Fatalf("expected not indexed, got: %v", err)
+	}
+}
+
+func bigInt(s string) *big.Int {
+	value, _ := new(big.Int).SetString(s, 10)
+	return value
+}
@@ -0,0 +1,67 @@
+package ord
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// decimals brc-20 amounts are scaled to
+const Brc20Decimals = 18
+
+var (
+	brc20Amount = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+	ErrInvalidBrc20 = errors.New("invalid brc-20 inscription")
+)
+
+// Inscription is the content of an inscription served by ord.
+type Inscription struct {
+	ContentType string
+	Body        []byte
+}
+
+// Brc20Transfer is a brc-20 transfer inscription.
+type Brc20Transfer struct {
+	Tick string
+	// Amount is scaled to Brc20Decimals
+	Amount *big.Int
+}
+
+// Brc20Transfer parses the inscription as a brc-20 transfer, nil if it is an
+// inscription of another kind.
+func (i *Inscription) Brc20Transfer() (*Brc20Transfer, error) {
+	contentType := strings.TrimSpace(strings.Split(i.ContentType, ";")[0])
+	if contentType != "text/plain" && contentType != "application/json" {
+		return nil, nil
+	}
+	var content struct {
+		P    string `json:"p"`
+		Op   string `json:"op"`
+		Tick string `json:"tick"`
+		Amt  string `json:"amt"`
+	}
+	if err := json.Unmarshal(i.Body, &content); err != nil {
+		return nil, nil
+	}
+	if content.P != "brc-20" || content.Op != "transfer" {
+		return nil, nil
+	}
+	if n := len([]byte(content.Tick)); n != 4 && n != 5 {
+		return nil, errors.Wrapf(ErrInvalidBrc20, "tick: %s", content.Tick)
+	}
+	if !brc20Amount.MatchString(content.Amt) {
+		return nil, errors.Wrapf(ErrInvalidBrc20, "amt: %s", content.Amt)
+	}
+	amount, err := decimal.NewFromString(content.Amt)
+	if err != nil || -amount.Exponent() > Brc20Decimals || !amount.IsPositive() {
+		return nil, errors.Wrapf(ErrInvalidBrc20, "amt: %s", content.Amt)
+	}
+	return &Brc20Transfer{
+		Tick:   strings.ToLower(content.Tick),
+		Amount: amount.Shift(Brc20Decimals).BigInt(),
+	}, nil
+}
@@ -0,0 +1,55 @@
+package ord
+
+import (
+	"github.com/pkg/errors"
+	"math/big"
+	"testing"
+)
+
+func TestBrc20Transfer(t *testing.T) {
+	ordi, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	fraction, _ := new(big.Int).SetString("1500000000000000000", 10)
+	for name, test := range map[string]struct {
+		contentType string
+		body        string
+		tick        string
+		amount      *big.Int
+	}{
+		"transfer":       {"text/plain;charset=utf-8", `{"p":"brc-20","op":"transfer","tick":"ordi","amt":"1000"}`, "ordi", ordi},
+		"json":           {"application/json", `{"p":"brc-20","op":"transfer","tick":"ORDI","amt":"1000"}`, "ordi", ordi},
+		"fraction":       {"text/plain", `{"p":"brc-20","op":"transfer","tick":"sats","amt":"1.5"}`, "sats", fraction},
+		"five byte tick": {"text/plain", `{"p":"brc-20","op":"transfer","tick":"pizza","amt":"1"}`, "pizza", big.NewInt(1e18)},
+		"deploy":         {"text/plain", `{"p":"brc-20","op":"deploy","tick":"ordi","max":"21000000","lim":"1000"}`, "", nil},
+		"mint":           {"text/plain", `{"p":"brc-20","op":"mint","tick":"ordi","amt":"1000"}`, "", nil},
+		"other protocol": {"text/plain", `{"p":"brc-21","op":"transfer","tick":"ordi","amt":"1000"}`, "", nil},
+		"text":           {"text/plain", "hello", "", nil},
+		"image":          {"image/png", `{"p":"brc-20","op":"transfer","tick":"ordi","amt":"1000"}`, "", nil},
+		// an amount that is no json string is no brc-20 json
+		"number amount": {"text/plain", `{"p":"brc-20","op":"transfer","tick":"ordi","amt":1000}`, "", nil},
+	} {
+		transfer, err := (&Inscription{ContentType: test.contentType, Body: []byte(test.body)}).Brc20Transfer()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if (transfer == nil) != (test.amount == nil) ||
+			(transfer != nil && (transfer.Tick != test.tick || transfer.Amount.Cmp(test.amount) != 0)) {
+			t.Errorf("%s: transfer: %+v", name, transfer)
+		}
+	}
+
+	for name, body := range map[string]string{
+		"short tick":  `{"p":"brc-20","op":"transfer","tick":"ord","amt":"1"}`,
+		"long tick":   `{"p":"brc-20","op":"transfer","tick":"ordinal","amt":"1"}`,
+		"zero":        `{"p":"brc-20","op":"transfer","tick":"ordi","amt":"0"}`,
+		"negative":    `{"p":"brc-20","op":"transfer","tick":"ordi","amt":"-1"}`,
+		"exponent":    `{"p":"brc-20","op":"transfer","tick":"ordi","amt":"1e3"}`,
+		"dot only":    `{"p":"brc-20","op":"transfer","tick":"ordi","amt":"1."}`,
+		"19 decimals": `{"p":"brc-20","op":"transfer","tick":"ordi","amt":"0.0000000000000000001"}`,
+	} {
+		transfer, err := (&Inscription{ContentType: "text/plain", Body: []byte(body)}).Brc20Transfer()
+		if !errors.Is(err, ErrInvalidBrc20) {
+			t.Errorf("%s: expected an invalid brc-20, got: %+v, err: %v", name, transfer, err)
+		}
+	}
+}
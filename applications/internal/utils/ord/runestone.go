@@ -0,0 +1,202 @@
+package ord
+
+import (
+	"fmt"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"math/big"
+)
+
+const (
+	// runestone field tags, an unknown even tag makes a cenotaph
+	tagBody         = 0
+	tagDivisibility = 1
+	tagFlags        = 2
+	tagSpacers      = 3
+	tagRune         = 4
+	tagSymbol       = 5
+	tagPremine      = 6
+	tagCap          = 8
+	tagAmount       = 10
+	tagHeightStart  = 12
+	tagHeightEnd    = 14
+	tagOffsetStart  = 16
+	tagOffsetEnd    = 18
+	tagMint         = 20
+	tagRunePointer  = 22
+	tagCenotaph     = 126
+
+	// known flags, etching, terms and turbo
+	knownFlags = 1<<0 | 1<<1 | 1<<2
+
+	// bytes of a leb128 encoded u128
+	maxVarintLen = 19
+)
+
+var (
+	maxU128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+	ErrCenotaph = errors.New("cenotaph")
+)
+
+// RuneId is the block and the tx index of the etching of a rune.
+type RuneId struct {
+	Block uint64
+	Tx    uint32
+}
+
+func (r RuneId) String() string {
+	return fmt.Sprintf("%d:%d", r.Block, r.Tx)
+}
+
+// Edict transfers an amount of a rune to an output, an output equal to the
+// number of outputs splits it across the outputs other than OP_RETURN.
+type Edict struct {
+	Id     RuneId
+	Amount *big.Int
+	Output uint32
+}
+
+// Runestone is the transfer part of a runestone, the etching and mint fields
+// are checked but not kept.
+type Runestone struct {
+	Edicts []Edict
+}
+
+// ParseRunestone decodes the runestone of a tx, nil if the tx has none. A
+// malformed runestone fails with ErrCenotaph, its runes are burned.
+func ParseRunestone(tx *wire.MsgTx) (*Runestone, error) {
+	payload, ok, err := runestonePayload(tx)
+	if err != nil || !ok {
+		return nil, err
+	}
+	integers := make([]*big.Int, 0)
+	for len(payload) > 0 {
+		value, n, err := readVarint(payload)
+		if err != nil {
+			return nil, err
+		}
+		integers = append(integers, value)
+		payload = payload[n:]
+	}
+	runestone := &Runestone{}
+	for i := 0; i < len(integers); i += 2 {
+		tag := integers[i]
+		if tag.IsUint64() && tag.Uint64() == tagBody {
+			edicts, err := parseEdicts(integers[i+1:], len(tx.TxOut))
+			if err != nil {
+				return nil, err
+			}
+			runestone.Edicts = edicts
+			break
+		}
+		if i+1 >= len(integers) {
+			return nil, errors.Wrap(ErrCenotaph, "truncated field")
+		}
+		value := integers[i+1]
+		if !tag.IsUint64() {
+			if tag.Bit(0) == 0 {
+				return nil, errors.Wrapf(ErrCenotaph, "unknown even tag: %s", tag)
+			}
+			continue
+		}
+		switch tag.Uint64() {
+		case tagFlags:
+			if new(big.Int).AndNot(value, big.NewInt(knownFlags)).Sign() != 0 {
+				return nil, errors.Wrapf(ErrCenotaph, "unknown flags: %s", value)
+			}
+		case tagRunePointer:
+			if !value.IsUint64() || value.Uint64() >= uint64(len(tx.TxOut)) {
+				return nil, errors.Wrapf(ErrCenotaph, "pointer: %s", value)
+			}
+		case tagCenotaph:
+			return nil, errors.Wrap(ErrCenotaph, "cenotaph tag")
+		case tagDivisibility, tagSpacers, tagRune, tagSymbol, tagPremine, tagCap, tagAmount,
+			tagHeightStart, tagHeightEnd, tagOffsetStart, tagOffsetEnd, tagMint:
+		default:
+			if tag.Bit(0) == 0 {
+				return nil, errors.Wrapf(ErrCenotaph, "unknown even tag: %s", tag)
+			}
+		}
+	}
+	return runestone, nil
+}
+
+// runestonePayload joins the data pushed after OP_RETURN OP_13 by the first
+// output starting with them.
+func runestonePayload(tx *wire.MsgTx) ([]byte, bool, error) {
+	for _, out := range tx.TxOut {
+		script := out.PkScript
+		if len(script) < 2 || script[0] != txscript.OP_RETURN || script[1] != txscript.OP_13 {
+			continue
+		}
+		payload := make([]byte, 0)
+		tokenizer := txscript.MakeScriptTokenizer(0, script[2:])
+		for tokenizer.Next() {
+			if tokenizer.Opcode() > txscript.OP_PUSHDATA4 {
+				return nil, false, errors.Wrap(ErrCenotaph, "opcode in runestone")
+			}
+			payload = append(payload, tokenizer.Data()...)
+		}
+		if tokenizer.Err() != nil {
+			return nil, false, errors.Wrap(ErrCenotaph, tokenizer.Err().Error())
+		}
+		return payload, true, nil
+	}
+	return nil, false, nil
+}
+
+func readVarint(data []byte) (*big.Int, int, error) {
+	value := new(big.Int)
+	for i := 0; i < len(data) && i < maxVarintLen; i++ {
+		part := new(big.Int).SetUint64(uint64(data[i] & 0x7f))
+		value.Or(value, part.Lsh(part, uint(7*i)))
+		if data[i]&0x80 == 0 {
+			if value.Cmp(maxU128) > 0 {
+				return nil, 0, errors.Wrap(ErrCenotaph, "varint overflow")
+			}
+			return value, i + 1, nil
+		}
+	}
+	return nil, 0, errors.Wrap(ErrCenotaph, "truncated varint")
+}
+
+// parseEdicts decodes the delta encoded edicts of the runestone body.
+func parseEdicts(integers []*big.Int, outputs int) ([]Edict, error) {
+	if len(integers)%4 != 0 {
+		return nil, errors.Wrap(ErrCenotaph, "trailing integers")
+	}
+	edicts := make([]Edict, 0, len(integers)/4)
+	var id RuneId
+	for i := 0; i < len(integers); i += 4 {
+		blockDelta, txDelta, amount, output := integers[i], integers[i+1], integers[i+2], integers[i+3]
+		if !blockDelta.IsUint64() || !txDelta.IsUint64() || txDelta.Uint64() > 1<<32-1 {
+			return nil, errors.Wrap(ErrCenotaph, "rune id overflow")
+		}
+		if blockDelta.Sign() == 0 {
+			if uint64(id.Tx)+txDelta.Uint64() > 1<<32-1 {
+				return nil, errors.Wrap(ErrCenotaph, "rune id overflow")
+			}
+			id.Tx += uint32(txDelta.Uint64())
+		} else {
+			if id.Block+blockDelta.Uint64() < id.Block {
+				return nil, errors.Wrap(ErrCenotaph, "rune id overflow")
+			}
+			id.Block += blockDelta.Uint64()
+			id.Tx = uint32(txDelta.Uint64())
+		}
+		if id.Block == 0 && id.Tx > 0 {
+			return nil, errors.Wrapf(ErrCenotaph, "rune id: %s", id)
+		}
+		if !output.IsUint64() || output.Uint64() > uint64(outputs) {
+			return nil, errors.Wrapf(ErrCenotaph, "edict output: %s", output)
+		}
+		edicts = append(edicts, Edict{
+			Id:     id,
+			Amount: amount,
+			Output: uint32(output.Uint64()),
+		})
+	}
+	return edicts, nil
+}
@@ -0,0 +1,214 @@
+package ord
+
+import (
+	"bytes"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"math/big"
+	"testing"
+)
+
+// varint encodes a leb128 integer of the runestone payload.
+func varint(value *big.Int) []byte {
+	value = new(big.Int).Set(value)
+	data := make([]byte, 0)
+	for value.BitLen() > 7 {
+		data = append(data, byte(value.Uint64()&0x7f)|0x80)
+		value.Rsh(value, 7)
+	}
+	return append(data, byte(value.Uint64()))
+}
+
+func integers(values ...uint64) []byte {
+	data := make([]byte, 0)
+	for _, value := range values {
+		data = append(data, varint(new(big.Int).SetUint64(value))...)
+	}
+	return data
+}
+
+// runestoneScript is OP_RETURN OP_13 pushing the payload in chunks of at most
+// 20 bytes, the pushes are not minimal so a small number is no OP_1..OP_16.
+func runestoneScript(payload []byte) []byte {
+	script := []byte{txscript.OP_RETURN, txscript.OP_13}
+	for len(payload) > 0 {
+		n := min(len(payload), 20)
+		script = append(append(script, byte(txscript.OP_DATA_1+n-1)), payload[:n]...)
+		payload = payload[n:]
+	}
+	return script
+}
+
+// runestoneTx is a tx with the runestone in its first output and outputs
+// more p2wpkh outputs.
+func runestoneTx(script []byte, outputs int) *wire.MsgTx {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(0, script))
+	for i := 0; i < outputs; i++ {
+		tx.AddTxOut(wire.NewTxOut(546, append([]byte{0x00, 0x14}, bytes.Repeat([]byte{byte(i + 1)}, 20)...)))
+	}
+	return tx
+}
+
+func TestParseRunestone(t *testing.T) {
+	amount := new(big.Int).Lsh(big.NewInt(1), 100)
+	for name, test := range map[string]struct {
+		payload []byte
+		edicts  []Edict
+	}{
+		"empty": {payload: nil, edicts: nil},
+		"etching fields": {
+			// divisibility, flags etching and terms, spacers, rune, symbol, premine, cap, amount
+			payload: integers(1, 2, 2, 3, 3, 1, 4, 12345, 5, 0x24, 6, 1000, 8, 10, 10, 100),
+		},
+		"unknown odd tag": {payload: integers(23, 7, 127, 1)},
+		"pointer":         {payload: integers(22, 1)},
+		"one edict": {
+			payload: append(integers(0, 840000, 3), append(varint(amount), integers(1)...)...),
+			edicts:  []Edict{{Id: RuneId{Block: 840000, Tx: 3}, Amount: amount, Output: 1}},
+		},
+		// the rune ids are deltas, the tx absolute once the block moves
+		"edict deltas": {
+			payload: integers(0, 2, 1, 5, 0, 0, 2, 6, 1, 1, 3, 7, 2, 0, 0, 8, 2),
+			edicts: []Edict{
+				{Id: RuneId{Block: 2, Tx: 1}, Amount: big.NewInt(5), Output: 0},
+				{Id: RuneId{Block: 2, Tx: 3}, Amount: big.NewInt(6), Output: 1},
+				{Id: RuneId{Block: 3, Tx: 3}, Amount: big.NewInt(7), Output: 2},
+				{Id: RuneId{Block: 3, Tx: 3}, Amount: big.NewInt(8), Output: 2},
+			},
+		},
+		// an output equal to the number of outputs splits the amount
+		"split edict": {
+			payload: integers(0, 840000, 3, 90, 3),
+			edicts:  []Edict{{Id: RuneId{Block: 840000, Tx: 3}, Amount: big.NewInt(90), Output: 3}},
+		},
+		// an edict of the rune etched by the tx
+		"etched rune edict": {
+			payload: integers(2, 1, 4, 99, 0, 0, 0, 50, 1),
+			edicts:  []Edict{{Id: RuneId{}, Amount: big.NewInt(50), Output: 1}},
+		},
+	} {
+		runestone, err := ParseRunestone(runestoneTx(runestoneScript(test.payload), 2))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if runestone == nil || len(runestone.Edicts) != len(test.edicts) {
+			t.Errorf("%s: runestone: %+v", name, runestone)
+			continue
+		}
+		for i, edict := range runestone.Edicts {
+			expected := test.edicts[i]
+			if edict.Id != expected.Id || edict.Amount.Cmp(expected.Amount) != 0 || edict.Output != expected.Output {
+				t.Errorf("%s: edict %d: %+v", name, i, edict)
+			}
+		}
+	}
+}
+
+func TestParseRunestoneCenotaph(t *testing.T) {
+	overflow := varint(new(big.Int).Add(maxU128, big.NewInt(1)))
+	for name, payload := range map[string][]byte{
+		"unknown even tag":         integers(24, 1),
+		"unknown even big tag":     append(varint(new(big.Int).Lsh(big.NewInt(1), 70)), integers(1)...),
+		"cenotaph tag":             integers(126, 0),
+		"unknown flag":             integers(2, 1<<3),
+		"truncated field":          integers(1, 2, 3),
+		"trailing integers":        integers(0, 840000, 3, 90, 1, 0),
+		"pointer out of range":     integers(22, 3),
+		"edict output over range":  integers(0, 840000, 3, 90, 4),
+		"rune id tx without block": integers(0, 0, 1, 90, 1),
+		"tx delta overflow":        integers(0, 1, 1<<32, 90, 1),
+		"tx sum overflow":          integers(0, 1, 1<<32-1, 90, 1, 0, 1, 90, 1),
+		"block delta overflow":     integers(0, 1<<64-1, 0, 90, 1, 1, 0, 90, 1),
+		"varint overflow":          append(integers(1), overflow...),
+		"varint over 19 bytes":     append(bytes.Repeat([]byte{0x80}, 19), 0x00),
+		"truncated varint":         {0x80},
+	} {
+		runestone, err := ParseRunestone(runestoneTx(runestoneScript(payload), 2))
+		if !errors.Is(err, ErrCenotaph) {
+			t.Errorf("%s: expected a cenotaph, got: %+v, err: %v", name, runestone, err)
+		}
+	}
+
+	// a runestone pushes data only
+	script := []byte{txscript.OP_RETURN, txscript.OP_13, txscript.OP_1}
+	if _, err := ParseRunestone(runestoneTx(script, 1)); !errors.Is(err, ErrCenotaph) {
+		t.Errorf("opcode: expected a cenotaph, got: %v", err)
+	}
+	script = []byte{txscript.OP_RETURN, txscript.OP_13, txscript.OP_DATA_2, 0x00}
+	if _, err := ParseRunestone(runestoneTx(script, 1)); !errors.Is(err, ErrCenotaph) {
+		t.Errorf("truncated push: expected a cenotaph, got: %v", err)
+	}
+}
+
+func TestParseRunestoneNone(t *testing.T) {
+	for name, script := range map[string][]byte{
+		"p2wpkh":      append([]byte{0x00, 0x14}, bytes.Repeat([]byte{1}, 20)...),
+		"op_return":   {txscript.OP_RETURN, txscript.OP_DATA_1, 0x0d},
+		"bare op_13":  {txscript.OP_13},
+		"op_return 1": {txscript.OP_RETURN, txscript.OP_1},
+	} {
+		runestone, err := ParseRunestone(runestoneTx(script, 1))
+		if err != nil || runestone != nil {
+			t.Errorf("%s: runestone: %+v, err: %v", name, runestone, err)
+		}
+	}
+}
+
+func TestEdictRune(t *testing.T) {
+	id := RuneId{Block: 840000, Tx: 3}
+	for name, test := range map[string]struct {
+		payload []byte
+		// outputs after the runestone, listen flags include the runestone
+		outputs  int
+		listen   []bool
+		expected *RuneId
+	}{
+		"to listen output": {
+			payload: integers(0, 840000, 3, 90, 2), outputs: 2,
+			listen: []bool{false, false, true}, expected: &id,
+		},
+		"to other output": {
+			payload: integers(0, 840000, 3, 90, 1), outputs: 2,
+			listen: []bool{false, false, true},
+		},
+		"zero amount": {
+			payload: integers(0, 840000, 3, 0, 2), outputs: 2,
+			listen: []bool{false, false, true},
+		},
+		"split to listen output": {
+			payload: integers(0, 840000, 3, 90, 3), outputs: 2,
+			listen: []bool{false, false, true}, expected: &id,
+		},
+		// the split skips the OP_RETURN output
+		"split to runestone only": {
+			payload: integers(0, 840000, 3, 90, 3), outputs: 2,
+			listen: []bool{true, false, false},
+		},
+		"second edict": {
+			payload: integers(0, 2, 1, 5, 1, 840000-2, 3, 90, 2), outputs: 2,
+			listen: []bool{false, false, true}, expected: &id,
+		},
+		"etched rune": {
+			payload: integers(2, 1, 4, 99, 0, 0, 0, 50, 2), outputs: 2,
+			listen: []bool{false, false, true},
+		},
+		"cenotaph": {
+			payload: integers(24, 1, 0, 840000, 3, 90, 2), outputs: 2,
+			listen: []bool{false, false, true},
+		},
+	} {
+		found, err := edictRune(runestoneTx(runestoneScript(test.payload), test.outputs), test.listen)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if (found == nil) != (test.expected == nil) || (found != nil && *found != *test.expected) {
+			t.Errorf("%s: rune: %v", name, found)
+		}
+	}
+}
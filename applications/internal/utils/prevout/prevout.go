@@ -0,0 +1,128 @@
+package prevout
+
+import (
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"sync"
+)
+
+const (
+	// DefaultCacheSize is the number of outputs kept by the local cache.
+	DefaultCacheSize = 100000
+	// blocks remembered as loaded, only the blocks being indexed are looked up again
+	loadedBlocks = 16
+)
+
+var ErrNotFound = errors.New("prevout not found")
+
+// Resolver resolves the outputs spent by tx inputs without -txindex. It looks
+// up a local cache of the outputs seen lately, then the prevouts of the block
+// of the tx (getblock verbosity 3), then the output itself (gettxout for the
+// outputs still unspent), the raw prev tx is the last resort for bitcoin core
+// nodes running -txindex.
+type Resolver struct {
+	rpc   rpcpool.BtcSource
+	mu    sync.Mutex
+	size  int
+	cache map[wire.OutPoint][]byte
+	order []wire.OutPoint
+	// blocks already loaded in the cache
+	blocks map[chainhash.Hash]bool
+}
+
+func NewResolver(rpc rpcpool.BtcSource, size int) *Resolver {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &Resolver{
+		rpc:    rpc,
+		size:   size,
+		cache:  make(map[wire.OutPoint][]byte),
+		blocks: make(map[chainhash.Hash]bool),
+	}
+}
+
+// PkScript returns the pk script of the output spent by an input of a tx mined
+// in blockHash, nil for a mempool tx.
+func (r *Resolver) PkScript(outPoint wire.OutPoint, blockHash *chainhash.Hash) ([]byte, error) {
+	if pkScript, ok := r.get(outPoint); ok {
+		return pkScript, nil
+	}
+	// a node older than bitcoin core 23.0 falls back to the other sources
+	var blockErr error
+	if blockHash != nil && !r.loaded(*blockHash) {
+		_, blockErr = r.Block(blockHash)
+		if pkScript, ok := r.get(outPoint); ok {
+			return pkScript, nil
+		}
+	}
+	pkScript, err := r.rpc.GetTxOutScript(&outPoint.Hash, outPoint.Index)
+	if err != nil {
+		return nil, err
+	}
+	if pkScript != nil {
+		r.put(outPoint, pkScript)
+		return pkScript, nil
+	}
+	tx, err := r.rpc.GetRawTransaction(&outPoint.Hash)
+	if err != nil {
+		if blockErr != nil {
+			return nil, errors.Wrapf(ErrNotFound, "%s: %s, getblock: %s", outPoint, err, blockErr)
+		}
+		return nil, errors.Wrapf(ErrNotFound, "%s: %s", outPoint, err)
+	}
+	if int(outPoint.Index) >= len(tx.MsgTx().TxOut) {
+		return nil, errors.Wrapf(ErrNotFound, "%s", outPoint)
+	}
+	pkScript = tx.MsgTx().TxOut[outPoint.Index].PkScript
+	r.put(outPoint, pkScript)
+	return pkScript, nil
+}
+
+// Block loads the prevouts of the block in the cache and returns its txs.
+func (r *Resolver) Block(blockHash *chainhash.Hash) ([]*wire.MsgTx, error) {
+	block, err := r.rpc.GetBlockPrevouts(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	for outPoint, pkScript := range block.Prevouts {
+		r.put(outPoint, pkScript)
+	}
+	r.mu.Lock()
+	if len(r.blocks) >= loadedBlocks {
+		r.blocks = make(map[chainhash.Hash]bool)
+	}
+	r.blocks[*blockHash] = true
+	r.mu.Unlock()
+	return block.Txs, nil
+}
+
+func (r *Resolver) loaded(blockHash chainhash.Hash) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.blocks[blockHash]
+}
+
+func (r *Resolver) get(outPoint wire.OutPoint) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pkScript, ok := r.cache[outPoint]
+	return pkScript, ok
+}
+
+// put caches an output, the oldest outputs are evicted first.
+func (r *Resolver) put(outPoint wire.OutPoint, pkScript []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.cache[outPoint]; ok {
+		return
+	}
+	for len(r.order) >= r.size {
+		delete(r.cache, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.cache[outPoint] = pkScript
+	r.order = append(r.order, outPoint)
+}
@@ -0,0 +1,337 @@
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/pkg/errors"
+	"io"
+)
+
+// key types of BIP 174 used by the withdrawals, other keys are kept as unknowns
+const (
+	globalUnsignedTx = 0x00
+
+	inputWitnessUtxo        = 0x01
+	inputPartialSig         = 0x02
+	inputSighashType        = 0x03
+	inputWitnessScript      = 0x05
+	inputFinalScriptWitness = 0x08
+
+	outputWitnessScript = 0x01
+
+	// largest key or value read
+	maxPsbtItemLen = 4000000
+)
+
+var (
+	magic = []byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+	ErrInvalidPsbt = errors.New("invalid psbt")
+)
+
+// Packet is a partially signed bitcoin transaction, the BIP 174 fields of
+// segwit v0 spends are decoded.
+type Packet struct {
+	UnsignedTx *wire.MsgTx
+	Inputs     []Input
+	Outputs    []Output
+	Unknowns   []Unknown
+}
+
+type Input struct {
+	WitnessUtxo        *wire.TxOut
+	PartialSigs        []PartialSig
+	SighashType        txscript.SigHashType
+	WitnessScript      []byte
+	FinalScriptWitness wire.TxWitness
+	Unknowns           []Unknown
+}
+
+type Output struct {
+	WitnessScript []byte
+	Unknowns      []Unknown
+}
+
+// PartialSig is the signature of an input by a compressed pubkey, sighash type included.
+type PartialSig struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// New returns the packet of a tx without signatures.
+func New(tx *wire.MsgTx) (*Packet, error) {
+	for _, in := range tx.TxIn {
+		if len(in.SignatureScript) > 0 || len(in.Witness) > 0 {
+			return nil, errors.Wrap(ErrInvalidPsbt, "unsigned tx has signatures")
+		}
+	}
+	return &Packet{
+		UnsignedTx: tx,
+		Inputs:     make([]Input, len(tx.TxIn)),
+		Outputs:    make([]Output, len(tx.TxOut)),
+	}, nil
+}
+
+// B64Encode serializes the packet in base64, the format exchanged by wallets.
+func (p *Packet) B64Encode() (string, error) {
+	var buf bytes.Buffer
+	err := p.Serialize(&buf)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func (p *Packet) Serialize(w io.Writer) error {
+	if _, err := w.Write(magic); err != nil {
+		return errors.WithStack(err)
+	}
+	var tx bytes.Buffer
+	if err := p.UnsignedTx.SerializeNoWitness(&tx); err != nil {
+		return errors.WithStack(err)
+	}
+	pairs := []Unknown{{Key: []byte{globalUnsignedTx}, Value: tx.Bytes()}}
+	if err := writeMap(w, append(pairs, p.Unknowns...)); err != nil {
+		return err
+	}
+	for _, in := range p.Inputs {
+		pairs = make([]Unknown, 0)
+		if in.WitnessUtxo != nil {
+			var out bytes.Buffer
+			if err := wire.WriteTxOut(&out, 0, 0, in.WitnessUtxo); err != nil {
+				return errors.WithStack(err)
+			}
+			pairs = append(pairs, Unknown{Key: []byte{inputWitnessUtxo}, Value: out.Bytes()})
+		}
+		for _, sig := range in.PartialSigs {
+			pairs = append(pairs, Unknown{Key: append([]byte{inputPartialSig}, sig.PubKey...), Value: sig.Signature})
+		}
+		if in.SighashType != 0 {
+			pairs = append(pairs, Unknown{
+				Key:   []byte{inputSighashType},
+				Value: binary.LittleEndian.AppendUint32(nil, uint32(in.SighashType)),
+			})
+		}
+		if in.WitnessScript != nil {
+			pairs = append(pairs, Unknown{Key: []byte{inputWitnessScript}, Value: in.WitnessScript})
+		}
+		if in.FinalScriptWitness != nil {
+			var witness bytes.Buffer
+			if err := writeWitness(&witness, in.FinalScriptWitness); err != nil {
+				return err
+			}
+			pairs = append(pairs, Unknown{Key: []byte{inputFinalScriptWitness}, Value: witness.Bytes()})
+		}
+		if err := writeMap(w, append(pairs, in.Unknowns...)); err != nil {
+			return err
+		}
+	}
+	for _, out := range p.Outputs {
+		pairs = make([]Unknown, 0)
+		if out.WitnessScript != nil {
+			pairs = append(pairs, Unknown{Key: []byte{outputWitnessScript}, Value: out.WitnessScript})
+		}
+		if err := writeMap(w, append(pairs, out.Unknowns...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseBase64 parses a base64 encoded packet.
+func ParseBase64(input string) (*Packet, error) {
+	data, err := base64.StdEncoding.DecodeString(input)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidPsbt, err.Error())
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Packet, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return nil, errors.Wrap(ErrInvalidPsbt, "magic")
+	}
+	r := bytes.NewReader(data[len(magic):])
+	globals, err := readMap(r)
+	if err != nil {
+		return nil, err
+	}
+	packet := &Packet{}
+	for _, pair := range globals {
+		if pair.Key[0] != globalUnsignedTx {
+			packet.Unknowns = append(packet.Unknowns, pair)
+			continue
+		}
+		if len(pair.Key) != 1 {
+			return nil, errors.Wrap(ErrInvalidPsbt, "unsigned tx key")
+		}
+		var tx wire.MsgTx
+		if err := tx.DeserializeNoWitness(bytes.NewReader(pair.Value)); err != nil {
+			return nil, errors.Wrap(ErrInvalidPsbt, err.Error())
+		}
+		packet.UnsignedTx = &tx
+	}
+	if packet.UnsignedTx == nil {
+		return nil, errors.Wrap(ErrInvalidPsbt, "no unsigned tx")
+	}
+	for _, in := range packet.UnsignedTx.TxIn {
+		if len(in.SignatureScript) > 0 {
+			return nil, errors.Wrap(ErrInvalidPsbt, "unsigned tx has signatures")
+		}
+	}
+	for range packet.UnsignedTx.TxIn {
+		pairs, err := readMap(r)
+		if err != nil {
+			return nil, err
+		}
+		input, err := parseInput(pairs)
+		if err != nil {
+			return nil, err
+		}
+		packet.Inputs = append(packet.Inputs, input)
+	}
+	for range packet.UnsignedTx.TxOut {
+		pairs, err := readMap(r)
+		if err != nil {
+			return nil, err
+		}
+		var output Output
+		for _, pair := range pairs {
+			if pair.Key[0] == outputWitnessScript && len(pair.Key) == 1 {
+				output.WitnessScript = pair.Value
+				continue
+			}
+			output.Unknowns = append(output.Unknowns, pair)
+		}
+		packet.Outputs = append(packet.Outputs, output)
+	}
+	if r.Len() > 0 {
+		return nil, errors.Wrap(ErrInvalidPsbt, "trailing bytes")
+	}
+	return packet, nil
+}
+
+func parseInput(pairs []Unknown) (Input, error) {
+	var input Input
+	for _, pair := range pairs {
+		switch {
+		case pair.Key[0] == inputWitnessUtxo && len(pair.Key) == 1:
+			out, err := readTxOut(pair.Value)
+			if err != nil {
+				return Input{}, err
+			}
+			input.WitnessUtxo = out
+		case pair.Key[0] == inputPartialSig:
+			if len(pair.Key) != 34 {
+				return Input{}, errors.Wrap(ErrInvalidPsbt, "partial sig pubkey")
+			}
+			input.PartialSigs = append(input.PartialSigs, PartialSig{PubKey: pair.Key[1:], Signature: pair.Value})
+		case pair.Key[0] == inputSighashType && len(pair.Key) == 1:
+			if len(pair.Value) != 4 {
+				return Input{}, errors.Wrap(ErrInvalidPsbt, "sighash type")
+			}
+			input.SighashType = txscript.SigHashType(binary.LittleEndian.Uint32(pair.Value))
+		case pair.Key[0] == inputWitnessScript && len(pair.Key) == 1:
+			input.WitnessScript = pair.Value
+		case pair.Key[0] == inputFinalScriptWitness && len(pair.Key) == 1:
+			witness, err := readWitness(pair.Value)
+			if err != nil {
+				return Input{}, err
+			}
+			input.FinalScriptWitness = witness
+		default:
+			// non witness utxos and final script sigs of legacy spends too
+			input.Unknowns = append(input.Unknowns, pair)
+		}
+	}
+	return input, nil
+}
+
+func readTxOut(data []byte) (*wire.TxOut, error) {
+	if len(data) < 9 {
+		return nil, errors.Wrap(ErrInvalidPsbt, "witness utxo")
+	}
+	r := bytes.NewReader(data[8:])
+	pkScript, err := wire.ReadVarBytes(r, 0, maxPsbtItemLen, "pkScript")
+	if err != nil || r.Len() > 0 {
+		return nil, errors.Wrap(ErrInvalidPsbt, "witness utxo")
+	}
+	return wire.NewTxOut(int64(binary.LittleEndian.Uint64(data[:8])), pkScript), nil
+}
+
+func readWitness(data []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(data)
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil || count > maxPsbtItemLen {
+		return nil, errors.Wrap(ErrInvalidPsbt, "witness")
+	}
+	witness := make(wire.TxWitness, 0, count)
+	for i := uint64(0); i < count; i++ {
+		item, err := wire.ReadVarBytes(r, 0, maxPsbtItemLen, "witness")
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidPsbt, "witness")
+		}
+		witness = append(witness, item)
+	}
+	if r.Len() > 0 {
+		return nil, errors.Wrap(ErrInvalidPsbt, "witness")
+	}
+	return witness, nil
+}
+
+func writeWitness(w io.Writer, witness wire.TxWitness) error {
+	if err := wire.WriteVarInt(w, 0, uint64(len(witness))); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, item := range witness {
+		if err := wire.WriteVarBytes(w, 0, item); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// readMap reads the key value pairs up to the 0x00 separator, a key appears once.
+func readMap(r *bytes.Reader) ([]Unknown, error) {
+	pairs := make([]Unknown, 0)
+	keys := make(map[string]bool)
+	for {
+		key, err := wire.ReadVarBytes(r, 0, maxPsbtItemLen, "key")
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidPsbt, err.Error())
+		}
+		if len(key) == 0 {
+			return pairs, nil
+		}
+		if keys[string(key)] {
+			return nil, errors.Wrapf(ErrInvalidPsbt, "duplicate key: %x", key)
+		}
+		keys[string(key)] = true
+		value, err := wire.ReadVarBytes(r, 0, maxPsbtItemLen, "value")
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidPsbt, err.Error())
+		}
+		pairs = append(pairs, Unknown{Key: key, Value: value})
+	}
+}
+
+func writeMap(w io.Writer, pairs []Unknown) error {
+	for _, pair := range pairs {
+		if err := wire.WriteVarBytes(w, 0, pair.Key); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := wire.WriteVarBytes(w, 0, pair.Value); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	_, err := w.Write([]byte{0x00})
+	return errors.WithStack(err)
+}
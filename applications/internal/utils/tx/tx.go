@@ -2,24 +2,26 @@ package tx
 
 import (
 	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/contracts/messagesharing"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
 	"bsquared.network/message-sharing-applications/internal/types"
 	"bsquared.network/message-sharing-applications/internal/utils/aa"
-	"bsquared.network/message-sharing-applications/internal/utils/ethereum/event"
 	"bsquared.network/message-sharing-applications/internal/utils/ethereum/message"
-	"bytes"
+	"bsquared.network/message-sharing-applications/internal/utils/ord"
+	"bsquared.network/message-sharing-applications/internal/utils/prevout"
 	"context"
 	"encoding/hex"
 	"fmt"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
-	"github.com/btcsuite/btcd/rpcclient"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
+	"math/big"
 )
 
 var (
@@ -31,7 +33,7 @@ var (
 	ErrParsePkScriptNotNullData = errors.New("parse pkscript not null data err")
 )
 
-func VerifyEthTx(rpc *ethclient.Client, txHash string, logIndex int64, fromMessageAddress string,
+func VerifyEthTx(rpc *rpcpool.EthClient, txHash string, logIndex int64, fromMessageAddress string,
 	fromChainId int64, fromId string, fromSender string, toChainId int64, toContractAddress string, toBytes string) (bool, error) {
 	tx, err := rpc.TransactionReceipt(context.Background(), common.HexToHash(txHash))
 	if err != nil {
@@ -39,13 +41,20 @@ func VerifyEthTx(rpc *ethclient.Client, txHash string, logIndex int64, fromMessa
 	}
 	for _, log := range tx.Logs {
 		if log.Index == uint(logIndex) {
-			if common.HexToAddress(fromMessageAddress) == log.Address &&
-				fromChainId == event.DataToInt64(*log, 0) &&
-				common.HexToHash(fromId).Big().Text(16) == event.DataToDecimal(*log, 1, 0).BigInt().Text(16) &&
-				common.HexToAddress(fromSender) == event.DataToAddress(*log, 2) &&
-				toChainId == event.DataToInt64(*log, 3) &&
-				common.HexToAddress(toContractAddress) == event.DataToAddress(*log, 4) &&
-				toBytes == event.DataToBytes(*log, 5) {
+			if common.HexToAddress(fromMessageAddress) != log.Address {
+				return false, nil
+			}
+			call, err := messagesharing.ParseCall(*log)
+			if err != nil {
+				// not a well formed call log
+				return false, nil
+			}
+			if call.FromChainId.Cmp(big.NewInt(fromChainId)) == 0 &&
+				call.FromId.Cmp(common.HexToHash(fromId).Big()) == 0 &&
+				common.HexToAddress(fromSender) == call.FromSender &&
+				call.ToChainId.Cmp(big.NewInt(toChainId)) == 0 &&
+				common.HexToAddress(toContractAddress) == call.ToBusinessContract &&
+				toBytes == hexutil.Encode(call.ToMessage) {
 				return true, nil
 			}
 		}
@@ -53,70 +62,242 @@ func VerifyEthTx(rpc *ethclient.Client, txHash string, logIndex int64, fromMessa
 	return false, nil
 }
 
-func VerifyBtcTx(rpc *rpcclient.Client, chainParams *chaincfg.Params, particle config.Particle, listenAddress string, txHash string, fromId string, data string) (bool, error) {
+// VerifyBtcTx checks a deposit message against its bitcoin tx, the message has
+// to target the destination of the deposit payload, by default the one of the
+// route of the deposit address. The tx is looked up in the block at
+// blockNumber, a message without block number needs a node running -txindex.
+// With an ord indexer the runes of the tx are encoded as the listener does, a
+// token deposit ord did not verify has no message.
+func VerifyBtcTx(rpc rpcpool.BtcSource, prevouts *prevout.Resolver, chainParams *chaincfg.Params, particle config.Particle,
+	route config.BitcoinRoute, indexer *ord.Client, blockNumber int64, txHash string, fromId string, toChainId int64, toMessageBridge string,
+	toContractAddress string, data string) (bool, error) {
 	_txHash, err := chainhash.NewHashFromStr(txHash[2:])
 	if err != nil {
 		return false, err
 	}
-	tx, err := rpc.GetRawTransaction(_txHash)
+	txResult, blockHash, err := getBtcTx(rpc, prevouts, blockNumber, _txHash)
 	if err != nil {
 		return false, err
 	}
-	txResult := tx.MsgTx()
-	_listenAddress, err := btcutil.DecodeAddress(listenAddress, chainParams)
+	totalValue, payload, listen, err := parseListenOutputs(chainParams, txResult, route.ListenAddress)
 	if err != nil {
 		return false, err
 	}
-	var totalValue int64
+	expectChainId, expectMessageBridge, expectContractAddress, ok := payload.Destination(route.ToChainId,
+		route.ToMessageBridge, route.ToContractAddress)
+	if !ok || toChainId != expectChainId ||
+		common.HexToAddress(toContractAddress) != common.HexToAddress(expectContractAddress) ||
+		(expectMessageBridge != "" && common.HexToAddress(toMessageBridge) != common.HexToAddress(expectMessageBridge)) {
+		return false, nil
+	}
+	fromAddress, err := parseFromAddress(prevouts, chainParams, txResult, blockHash)
+	if err != nil {
+		return false, err
+	}
+	if len(fromAddress) == 0 {
+		return false, errors.New("fromAddress invalid")
+	}
 	var depositAddress string
-	for _, v := range txResult.TxOut {
-		pkAddress, err := parseAddress(chainParams, v.PkScript)
+	var callDataHash *common.Hash
+	if payload != nil {
+		depositAddress = payload.Recipient.Hex()
+		callDataHash = payload.CallDataHash
+	} else {
+		_depositAddress, err := getAADepositAddress(particle, fromAddress[0].Address)
 		if err != nil {
-			if errors.Is(err, ErrParsePkScript) {
-				continue
-			}
-			if errors.Is(err, ErrParsePkScriptNullData) {
-				nullData, err := parseNullData(v.PkScript)
-				if err != nil {
-					continue
-				}
-				evmAddress, err := parseEvmAddress(nullData)
-				if err != nil {
-					continue
-				}
-				fmt.Println("evmAddress:", evmAddress)
-				if depositAddress == "" {
-					depositAddress = evmAddress
-				}
-			} else {
+			return false, err
+		}
+		depositAddress = _depositAddress
+	}
+	_data, err := message.EncodeDepositData(txResult.TxHash().String(), fromAddress[0].Address, depositAddress,
+		decimal.New(totalValue, 0), callDataHash)
+	if err != nil {
+		return false, err
+	}
+	if indexer != nil {
+		token, err := ord.ParseDeposit(indexer, txResult, listen)
+		if err != nil {
+			return false, err
+		}
+		if token != nil && !token.Verified {
+			return false, nil
+		}
+		if token != nil {
+			_data, err = message.EncodeTokenData(txResult.TxHash().String(), fromAddress[0].Address, depositAddress,
+				decimal.New(totalValue, 0), token.Type, token.Id, token.Amount, callDataHash)
+			if err != nil {
 				return false, err
 			}
 		}
-		if pkAddress == _listenAddress.EncodeAddress() {
-			totalValue += v.Value
-		}
 	}
-	fromAddress, err := parseFromAddress(rpc, chainParams, txResult)
+	if common.HexToHash(fromId) == common.HexToHash(txHash) && data == "0x"+hex.EncodeToString(_data) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// VerifyBtcRefund checks a refund message pays the value a deposit sent to the
+// listen address back to its sender, and that the listener can build no message
+// for the deposit. A missing bridge is only told with the bridges of the listener,
+// a token deposit ord did not verify only with an ord indexer.
+func VerifyBtcRefund(rpc rpcpool.BtcSource, prevouts *prevout.Resolver, chainParams *chaincfg.Params, particle config.Particle,
+	route config.BitcoinRoute, bridges map[int64]string, indexer *ord.Client, blockNumber int64, txHash string, fromId string, data string) (bool, error) {
+	_txHash, err := chainhash.NewHashFromStr(txHash[2:])
+	if err != nil {
+		return false, err
+	}
+	txResult, blockHash, err := getBtcTx(rpc, prevouts, blockNumber, _txHash)
+	if err != nil {
+		return false, err
+	}
+	totalValue, payload, listen, err := parseListenOutputs(chainParams, txResult, route.ListenAddress)
+	if err != nil {
+		return false, err
+	}
+	if totalValue == 0 {
+		return false, nil
+	}
+	fromAddress, err := parseFromAddress(prevouts, chainParams, txResult, blockHash)
 	if err != nil {
 		return false, err
 	}
 	if len(fromAddress) == 0 {
 		return false, errors.New("fromAddress invalid")
 	}
-	if depositAddress == "" {
-		_depositAddress, err := getAADepositAddress(particle, fromAddress[0].Address)
+	unroutable, err := unroutableDeposit(particle, route, bridges, payload, fromAddress[0].Address)
+	if err != nil {
+		return false, err
+	}
+	if !unroutable && indexer != nil {
+		token, err := ord.ParseDeposit(indexer, txResult, listen)
 		if err != nil {
 			return false, err
 		}
-		depositAddress = _depositAddress
+		unroutable = token != nil && !token.Verified
+	}
+	if !unroutable {
+		return false, nil
+	}
+	_data, err := message.EncodeWithdrawData(fromAddress[0].Address, totalValue)
+	if err != nil {
+		return false, err
 	}
-	_data := message.EncodeSendData(txResult.TxHash().String(), fromAddress[0].Address, depositAddress, decimal.New(totalValue, 0))
 	if common.HexToHash(fromId) == common.HexToHash(txHash) && data == "0x"+hex.EncodeToString(_data) {
 		return true, nil
 	}
 	return false, nil
 }
 
+// HeldDeposit tells from the chain whether a withdrawal must not spend the
+// outputs of the tx, a token deposit, it would send the tokens to the recipient
+// or burn them, or a deposit the listener refunds. The change of a withdrawal,
+// a tx spending a listen address, is no deposit.
+func HeldDeposit(rpc rpcpool.BtcSource, prevouts *prevout.Resolver, chainParams *chaincfg.Params, particle config.Particle,
+	routes map[string]config.BitcoinRoute, bridges map[int64]string, indexer *ord.Client, txHash *chainhash.Hash) (bool, error) {
+	txResult, err := rpc.GetRawTransaction(txHash)
+	if err != nil {
+		return false, err
+	}
+	fromAddress, err := parseFromAddress(prevouts, chainParams, txResult.MsgTx(), nil)
+	if err != nil {
+		return false, err
+	}
+	if len(fromAddress) == 0 {
+		return false, nil
+	}
+	if _, ok := routes[fromAddress[0].Address]; ok {
+		return false, nil
+	}
+	// the first routed address paid by the tx takes the deposit, as in the listener
+	for _, out := range txResult.MsgTx().TxOut {
+		pkAddress, err := parseAddress(chainParams, out.PkScript)
+		if err != nil {
+			continue
+		}
+		route, ok := routes[pkAddress]
+		if !ok {
+			continue
+		}
+		_, payload, listen, err := parseListenOutputs(chainParams, txResult.MsgTx(), route.ListenAddress)
+		if err != nil {
+			return false, err
+		}
+		if indexer != nil {
+			token, err := ord.ParseDeposit(indexer, txResult.MsgTx(), listen)
+			if err != nil {
+				return false, err
+			}
+			if token != nil {
+				return true, nil
+			}
+		}
+		return unroutableDeposit(particle, route, bridges, payload, fromAddress[0].Address)
+	}
+	return false, nil
+}
+
+// unroutableDeposit follows the listener, the payload targets a chain without
+// contract, the sender has no aa account or no bridge serves the destination.
+func unroutableDeposit(particle config.Particle, route config.BitcoinRoute, bridges map[int64]string,
+	payload *types.DepositPayload, btcFrom string) (bool, error) {
+	toChainId, toMessageBridge, _, ok := payload.Destination(route.ToChainId, route.ToMessageBridge, route.ToContractAddress)
+	if !ok {
+		return true, nil
+	}
+	if payload == nil {
+		_, err := getAADepositAddress(particle, btcFrom)
+		if err != nil && err.Error() == "AAGetBTCAccount not found" {
+			return true, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+	if toMessageBridge == "" && len(bridges) > 0 {
+		if _, ok := bridges[toChainId]; !ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseListenOutputs sums the outputs paying the listen address and returns
+// the first deposit payload of the tx.
+func parseListenOutputs(chainParams *chaincfg.Params, txResult *wire.MsgTx, listenAddress string) (int64, *types.DepositPayload, []bool, error) {
+	_listenAddress, err := btcutil.DecodeAddress(listenAddress, chainParams)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	var totalValue int64
+	var payload *types.DepositPayload
+	// outputs paying the listen address
+	listen := make([]bool, len(txResult.TxOut))
+	for k, v := range txResult.TxOut {
+		pkAddress, err := parseAddress(chainParams, v.PkScript)
+		if err != nil {
+			if errors.Is(err, ErrParsePkScript) {
+				continue
+			}
+			if errors.Is(err, ErrParsePkScriptNullData) {
+				nullData, err := parseNullData(v.PkScript)
+				if err != nil {
+					continue
+				}
+				// only the first payload counts
+				if payload == nil {
+					payload, _ = types.ParseNullDataPayload(nullData)
+				}
+			} else {
+				return 0, nil, nil, err
+			}
+		}
+		if pkAddress == _listenAddress.EncodeAddress() {
+			totalValue += v.Value
+			listen[k] = true
+		}
+	}
+	return totalValue, payload, listen, nil
+}
+
 func parseAddress(chainParams *chaincfg.Params, pkScript []byte) (string, error) {
 	pk, err := txscript.ParsePkScript(pkScript)
 	if err != nil {
@@ -152,30 +333,39 @@ func parseNullData(pkScript []byte) (string, error) {
 	return hex.EncodeToString(pkScript[1:]), nil
 }
 
-func parseEvmAddress(nullData string) (string, error) {
-	decodeNullData, err := hex.DecodeString(nullData)
+// getBtcTx returns the tx and the hash of its block, the tx is fetched by hash
+// alone when the block number is unknown.
+func getBtcTx(rpc rpcpool.BtcSource, prevouts *prevout.Resolver, blockNumber int64, txHash *chainhash.Hash) (*wire.MsgTx, *chainhash.Hash, error) {
+	if blockNumber <= 0 {
+		tx, err := rpc.GetRawTransaction(txHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tx.MsgTx(), nil, nil
+	}
+	blockHash, err := rpc.GetBlockHash(blockNumber)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-	evmAddress := bytes.TrimSpace(decodeNullData[1:])
-	if common.IsHexAddress(string(evmAddress)) {
-		return string(evmAddress), nil
+	txs, err := prevouts.Block(blockHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, tx := range txs {
+		if tx.TxHash() == *txHash {
+			return tx, blockHash, nil
+		}
 	}
-	return "", nil
+	// reorged or not indexed yet, verified again later on
+	return nil, nil, errors.Errorf("tx %s not found in block %d", txHash, blockNumber)
 }
 
-func parseFromAddress(rpc *rpcclient.Client, chainParams *chaincfg.Params, txResult *wire.MsgTx) (fromAddress []types.BitcoinFrom, err error) {
+func parseFromAddress(prevouts *prevout.Resolver, chainParams *chaincfg.Params, txResult *wire.MsgTx, blockHash *chainhash.Hash) (fromAddress []types.BitcoinFrom, err error) {
 	for _, vin := range txResult.TxIn {
-		// get prev tx hash
-		prevTxID := vin.PreviousOutPoint.Hash
-		vinResult, err := rpc.GetRawTransaction(&prevTxID)
+		vinPKScript, err := prevouts.PkScript(vin.PreviousOutPoint, blockHash)
 		if err != nil {
-			return nil, fmt.Errorf("vin get raw transaction err:%w", err)
-		}
-		if len(vinResult.MsgTx().TxOut) == 0 {
-			return nil, fmt.Errorf("vin txOut is null")
+			return nil, fmt.Errorf("vin resolve prevout err:%w", err)
 		}
-		vinPKScript := vinResult.MsgTx().TxOut[vin.PreviousOutPoint.Index].PkScript
 		//  script to address
 		vinPkAddress, err := parseAddress(chainParams, vinPKScript)
 		if err != nil {
@@ -0,0 +1,31 @@
+package withdraw
+
+import (
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/models"
+	"bsquared.network/message-sharing-applications/internal/types"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// HeldDeposits returns the txs among hashes whose outputs a withdrawal of the
+// chain must not spend, token deposits, it would send the tokens to the
+// recipient or burn them, and the deposits waiting for their refund.
+func HeldDeposits(db *gorm.DB, chainId int64, hashes []string) (map[string]bool, error) {
+	held := make(map[string]bool)
+	if len(hashes) == 0 {
+		return held, nil
+	}
+	var deposits []string
+	err := db.Model(models.Deposit{}).
+		Where("chain_id=? AND btc_tx_hash IN ? AND (btc_tx_type!=? OR status IN ?)", chainId, hashes, types.BitcoinTokenTypeBtc,
+			[]enums.DepositStatus{enums.DepositStatusInvalid, enums.DepositStatusRefunding}).
+		Pluck("btc_tx_hash", &deposits).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, hash := range deposits {
+		held[hash] = true
+	}
+	return held, nil
+}
@@ -0,0 +1,440 @@
+package withdraw
+
+import (
+	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"bsquared.network/message-sharing-applications/internal/utils/psbt"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ethereum/go-ethereum/common"
+	crypto_ "github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"sort"
+)
+
+const (
+	// DustLimit is the smallest output relayed by bitcoin core
+	DustLimit = 546
+	// maxMultisigKeys is the largest n of a p2wsh OP_CHECKMULTISIG
+	maxMultisigKeys = 20
+)
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrAmountTooSmall is an amount not covering the fee and the dust limit
+	ErrAmountTooSmall = errors.New("withdraw amount too small")
+)
+
+// Wallet is the m of n p2wsh multisig of the validator node keys holding the
+// deposits of a utxo chain, it builds, signs and finalizes withdrawal psbts.
+type Wallet struct {
+	rpc       rpcpool.BtcSource
+	params    *chaincfg.Params
+	threshold int
+	// pubKeys are sorted as in the witness script
+	pubKeys  [][]byte
+	signers  map[common.Address][]byte
+	script   []byte
+	pkScript []byte
+	address  string
+	feeRate  int64
+}
+
+func NewWallet(conf config.Blockchain, rpc rpcpool.BtcSource) (*Wallet, error) {
+	if len(conf.WithdrawPubKeys) > maxMultisigKeys {
+		return nil, fmt.Errorf("too many withdraw pubkeys: %d", len(conf.WithdrawPubKeys))
+	}
+	params := conf.BitcoinParams()
+	pubKeys := make([][]byte, 0, len(conf.WithdrawPubKeys))
+	signers := make(map[common.Address][]byte)
+	for _, value := range conf.WithdrawPubKeys {
+		pubKey, err := btcec.ParsePubKey(common.FromHex(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid withdraw pubkey: %s, %w", value, err)
+		}
+		signer := crypto_.PubkeyToAddress(*pubKey.ToECDSA())
+		if _, ok := signers[signer]; ok {
+			return nil, fmt.Errorf("duplicate withdraw pubkey: %s", value)
+		}
+		signers[signer] = pubKey.SerializeCompressed()
+		pubKeys = append(pubKeys, pubKey.SerializeCompressed())
+	}
+	// bip 67, the multisig does not depend on the config order
+	sort.Slice(pubKeys, func(i, j int) bool {
+		return bytes.Compare(pubKeys[i], pubKeys[j]) < 0
+	})
+	builder := txscript.NewScriptBuilder().AddInt64(int64(conf.WithdrawThreshold))
+	for _, pubKey := range pubKeys {
+		builder.AddData(pubKey)
+	}
+	script, err := builder.AddInt64(int64(len(pubKeys))).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	hash := sha256.Sum256(script)
+	address, err := btcutil.NewAddressWitnessScriptHash(hash[:], params)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	listenAddress, err := btcutil.DecodeAddress(conf.ListenAddress, params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address: %s, %w", conf.ListenAddress, err)
+	}
+	if listenAddress.EncodeAddress() != address.EncodeAddress() {
+		return nil, fmt.Errorf("listen address %s is not the withdraw multisig %s", conf.ListenAddress, address.EncodeAddress())
+	}
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Wallet{
+		rpc:       rpc,
+		params:    params,
+		threshold: conf.WithdrawThreshold,
+		pubKeys:   pubKeys,
+		signers:   signers,
+		script:    script,
+		pkScript:  pkScript,
+		address:   address.EncodeAddress(),
+		feeRate:   conf.FeeRate,
+	}, nil
+}
+
+// Threshold returns the number of signatures a withdrawal needs.
+func (w *Wallet) Threshold() int {
+	return w.threshold
+}
+
+func (w *Wallet) Address() string {
+	return w.address
+}
+
+// Unspent returns the confirmed outputs of the multisig, except the reserved ones.
+func (w *Wallet) Unspent(exclude map[wire.OutPoint]bool) ([]rpcpool.Utxo, error) {
+	utxos, err := w.rpc.ListUnspent(w.address)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]rpcpool.Utxo, 0, len(utxos))
+	for _, utxo := range utxos {
+		if !exclude[utxo.OutPoint] {
+			list = append(list, utxo)
+		}
+	}
+	return list, nil
+}
+
+// Build spends the largest utxos to pay amount to the address, the fee is paid
+// out of the amount and the change goes back to the multisig.
+func (w *Wallet) Build(utxos []rpcpool.Utxo, to string, amount int64) (*psbt.Packet, int64, error) {
+	toPkScript, err := w.payToScript(to)
+	if err != nil {
+		return nil, 0, err
+	}
+	utxos = append([]rpcpool.Utxo{}, utxos...)
+	sort.Slice(utxos, func(i, j int) bool {
+		return utxos[i].Value > utxos[j].Value
+	})
+	tx := wire.NewMsgTx(2)
+	var sum int64
+	for _, utxo := range utxos {
+		if sum >= amount {
+			break
+		}
+		outPoint := utxo.OutPoint
+		in := wire.NewTxIn(&outPoint, nil, nil)
+		// signal replaceability, a stuck withdrawal can be bumped by hand
+		in.Sequence = wire.MaxTxInSequenceNum - 2
+		tx.AddTxIn(in)
+		sum += utxo.Value
+	}
+	if sum < amount {
+		return nil, 0, errors.Wrapf(ErrInsufficientFunds, "need %d, have %d", amount, sum)
+	}
+	change := sum - amount
+	outputs := []int{len(toPkScript)}
+	if change >= DustLimit {
+		outputs = append(outputs, len(w.pkScript))
+	}
+	fee := w.fee(len(tx.TxIn), outputs)
+	if amount-fee < DustLimit {
+		return nil, 0, errors.Wrapf(ErrAmountTooSmall, "amount %d, fee %d", amount, fee)
+	}
+	tx.AddTxOut(wire.NewTxOut(amount-fee, toPkScript))
+	if change >= DustLimit {
+		tx.AddTxOut(wire.NewTxOut(change, w.pkScript))
+	}
+	packet, err := psbt.New(tx)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range tx.TxIn {
+		packet.Inputs[i] = psbt.Input{
+			WitnessUtxo:   wire.NewTxOut(utxos[i].Value, w.pkScript),
+			SighashType:   txscript.SigHashAll,
+			WitnessScript: w.script,
+		}
+	}
+	if change >= DustLimit {
+		packet.Outputs[1].WitnessScript = w.script
+	}
+	return packet, fee, nil
+}
+
+// Verify checks a proposed packet spends unspent outputs of the multisig to pay
+// amount to the address, with at most the fee of the fee rate and the exact change.
+func (w *Wallet) Verify(packet *psbt.Packet, to string, amount int64) error {
+	toPkScript, err := w.payToScript(to)
+	if err != nil {
+		return err
+	}
+	tx := packet.UnsignedTx
+	if len(tx.TxIn) == 0 || len(tx.TxOut) == 0 || len(tx.TxOut) > 2 {
+		return errors.New("invalid withdraw tx shape")
+	}
+	utxos, err := w.rpc.ListUnspent(w.address)
+	if err != nil {
+		return err
+	}
+	values := make(map[wire.OutPoint]int64, len(utxos))
+	for _, utxo := range utxos {
+		values[utxo.OutPoint] = utxo.Value
+	}
+	var sum int64
+	spent := make(map[wire.OutPoint]bool)
+	for i, in := range tx.TxIn {
+		value, ok := values[in.PreviousOutPoint]
+		if !ok || spent[in.PreviousOutPoint] {
+			return fmt.Errorf("input %s is not an unspent output of %s", in.PreviousOutPoint, w.address)
+		}
+		spent[in.PreviousOutPoint] = true
+		input := packet.Inputs[i]
+		if input.WitnessUtxo == nil || input.WitnessUtxo.Value != value || !bytes.Equal(input.WitnessUtxo.PkScript, w.pkScript) {
+			return fmt.Errorf("invalid witness utxo of input %d", i)
+		}
+		if !bytes.Equal(input.WitnessScript, w.script) {
+			return fmt.Errorf("invalid witness script of input %d", i)
+		}
+		if input.SighashType != 0 && input.SighashType != txscript.SigHashAll {
+			return fmt.Errorf("invalid sighash type of input %d", i)
+		}
+		sum += value
+	}
+	if sum < amount {
+		return errors.Wrapf(ErrInsufficientFunds, "need %d, have %d", amount, sum)
+	}
+	change := sum - amount
+	outputs := []int{len(toPkScript)}
+	if len(tx.TxOut) == 2 {
+		if !bytes.Equal(tx.TxOut[1].PkScript, w.pkScript) || tx.TxOut[1].Value != change || change < DustLimit {
+			return errors.New("invalid withdraw change")
+		}
+		outputs = append(outputs, len(w.pkScript))
+	} else if change >= DustLimit {
+		return errors.New("missing withdraw change")
+	}
+	if !bytes.Equal(tx.TxOut[0].PkScript, toPkScript) {
+		return errors.New("invalid withdraw recipient")
+	}
+	fee := w.fee(len(tx.TxIn), outputs)
+	if tx.TxOut[0].Value > amount || tx.TxOut[0].Value < amount-fee {
+		return fmt.Errorf("invalid withdraw value: %d, amount %d, fee %d", tx.TxOut[0].Value, amount, fee)
+	}
+	return nil
+}
+
+// Sign adds the signatures of the key to every input of the packet.
+func (w *Wallet) Sign(packet *psbt.Packet, key *btcec.PrivateKey) error {
+	pubKey := key.PubKey().SerializeCompressed()
+	if !w.contains(pubKey) {
+		return errors.New("key is not a withdraw signer")
+	}
+	sigHashes, err := w.sigHashes(packet)
+	if err != nil {
+		return err
+	}
+	for i := range packet.Inputs {
+		input := &packet.Inputs[i]
+		signature, err := txscript.RawTxInWitnessSignature(packet.UnsignedTx, sigHashes, i, input.WitnessUtxo.Value,
+			w.script, txscript.SigHashAll, key)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		partialSigs := make([]psbt.PartialSig, 0, len(input.PartialSigs)+1)
+		for _, partialSig := range input.PartialSigs {
+			if !bytes.Equal(partialSig.PubKey, pubKey) {
+				partialSigs = append(partialSigs, partialSig)
+			}
+		}
+		input.PartialSigs = append(partialSigs, psbt.PartialSig{PubKey: pubKey, Signature: signature})
+	}
+	return nil
+}
+
+// Combine verifies the signatures of the signer in the signed copy of the
+// packet and adds them to the packet.
+func (w *Wallet) Combine(packet *psbt.Packet, signed *psbt.Packet, signer common.Address) error {
+	pubKey, ok := w.signers[signer]
+	if !ok {
+		return fmt.Errorf("%s is not a withdraw signer", signer.Hex())
+	}
+	if signed.UnsignedTx.TxHash() != packet.UnsignedTx.TxHash() || len(signed.Inputs) != len(packet.Inputs) {
+		return errors.New("signed psbt of another tx")
+	}
+	key, err := btcec.ParsePubKey(pubKey)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sigHashes, err := w.sigHashes(packet)
+	if err != nil {
+		return err
+	}
+	for i := range packet.Inputs {
+		var signature []byte
+		for _, partialSig := range signed.Inputs[i].PartialSigs {
+			if bytes.Equal(partialSig.PubKey, pubKey) {
+				signature = partialSig.Signature
+			}
+		}
+		if len(signature) == 0 || txscript.SigHashType(signature[len(signature)-1]) != txscript.SigHashAll {
+			return fmt.Errorf("missing signature of input %d", i)
+		}
+		hash, err := txscript.CalcWitnessSigHash(w.script, sigHashes, txscript.SigHashAll, packet.UnsignedTx, i,
+			packet.Inputs[i].WitnessUtxo.Value)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		sig, err := ecdsa.ParseDERSignature(signature[:len(signature)-1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if !sig.Verify(hash, key) {
+			return fmt.Errorf("invalid signature of input %d", i)
+		}
+		input := &packet.Inputs[i]
+		for _, partialSig := range input.PartialSigs {
+			if bytes.Equal(partialSig.PubKey, pubKey) {
+				signature = nil
+			}
+		}
+		if signature != nil {
+			input.PartialSigs = append(input.PartialSigs, psbt.PartialSig{PubKey: pubKey, Signature: signature})
+		}
+	}
+	return nil
+}
+
+// Finalize builds the witnesses from threshold signatures of every input and
+// returns the signed tx after running the scripts.
+func (w *Wallet) Finalize(packet *psbt.Packet) (*wire.MsgTx, error) {
+	tx := packet.UnsignedTx.Copy()
+	prevOuts, err := w.prevOuts(packet)
+	if err != nil {
+		return nil, err
+	}
+	sigHashes := txscript.NewTxSigHashes(tx, prevOuts)
+	for i := range packet.Inputs {
+		input := &packet.Inputs[i]
+		// OP_CHECKMULTISIG pops one item too many, the signatures follow the key order
+		witness := wire.TxWitness{nil}
+		for _, pubKey := range w.pubKeys {
+			if len(witness)-1 == w.threshold {
+				break
+			}
+			for _, partialSig := range input.PartialSigs {
+				if bytes.Equal(partialSig.PubKey, pubKey) {
+					witness = append(witness, partialSig.Signature)
+					break
+				}
+			}
+		}
+		if len(witness)-1 < w.threshold {
+			return nil, fmt.Errorf("input %d has %d of %d signatures", i, len(witness)-1, w.threshold)
+		}
+		witness = append(witness, w.script)
+		input.FinalScriptWitness = witness
+		tx.TxIn[i].Witness = witness
+	}
+	for i := range tx.TxIn {
+		engine, err := txscript.NewEngine(packet.Inputs[i].WitnessUtxo.PkScript, tx, i, txscript.StandardVerifyFlags,
+			nil, sigHashes, packet.Inputs[i].WitnessUtxo.Value, prevOuts)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err = engine.Execute(); err != nil {
+			return nil, fmt.Errorf("input %d script failed: %w", i, err)
+		}
+	}
+	return tx, nil
+}
+
+// Broadcast sends the signed tx, a tx already in the chain is not an error.
+func (w *Wallet) Broadcast(tx *wire.MsgTx) error {
+	_, err := w.rpc.SendRawTransaction(tx)
+	return err
+}
+
+func (w *Wallet) sigHashes(packet *psbt.Packet) (*txscript.TxSigHashes, error) {
+	prevOuts, err := w.prevOuts(packet)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.NewTxSigHashes(packet.UnsignedTx, prevOuts), nil
+}
+
+func (w *Wallet) prevOuts(packet *psbt.Packet) (*txscript.MultiPrevOutFetcher, error) {
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, in := range packet.UnsignedTx.TxIn {
+		input := packet.Inputs[i]
+		if input.WitnessUtxo == nil || !bytes.Equal(input.WitnessUtxo.PkScript, w.pkScript) {
+			return nil, fmt.Errorf("input %d does not spend %s", i, w.address)
+		}
+		prevOuts.AddPrevOut(in.PreviousOutPoint, input.WitnessUtxo)
+	}
+	return prevOuts, nil
+}
+
+func (w *Wallet) payToScript(to string) ([]byte, error) {
+	address, err := btcutil.DecodeAddress(to, w.params)
+	if err != nil || !address.IsForNet(w.params) {
+		return nil, fmt.Errorf("invalid withdraw address: %s", to)
+	}
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return pkScript, nil
+}
+
+func (w *Wallet) contains(pubKey []byte) bool {
+	for _, key := range w.pubKeys {
+		if bytes.Equal(key, pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// fee returns the fee of a tx spending inputs of the multisig to outputs of
+// the given script lengths, with signatures at their largest.
+func (w *Wallet) fee(inputs int, outputs []int) int64 {
+	// version, input and output counts, locktime
+	size := 4 + 1 + 1 + 4
+	for _, pkScript := range outputs {
+		size += 8 + 1 + pkScript
+	}
+	// outpoint, empty script sig, sequence
+	size += inputs * (32 + 4 + 1 + 4)
+	// item count, dummy, signatures, script
+	witness := 1 + 1 + w.threshold*(1+73) + wire.VarIntSerializeSize(uint64(len(w.script))) + len(w.script)
+	// marker and flag
+	weight := size*4 + 2 + inputs*witness
+	return w.feeRate * int64((weight+3)/4)
+}
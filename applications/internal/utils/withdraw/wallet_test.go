@@ -0,0 +1,253 @@
+package withdraw
+
+import (
+	"bsquared.network/message-sharing-applications/internal/config"
+	"bsquared.network/message-sharing-applications/internal/enums"
+	"bsquared.network/message-sharing-applications/internal/rpcpool"
+	"bsquared.network/message-sharing-applications/internal/utils/psbt"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/ethereum/go-ethereum/common"
+	crypto_ "github.com/ethereum/go-ethereum/crypto"
+	"sort"
+	"testing"
+)
+
+// testRecipient is a testnet p2wpkh address
+const testRecipient = "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"
+
+// unspentSource is a bitcoin source listing the utxos given.
+type unspentSource struct {
+	rpcpool.BtcSource
+	utxos []rpcpool.Utxo
+}
+
+func (s *unspentSource) ListUnspent(address string) ([]rpcpool.Utxo, error) {
+	return s.utxos, nil
+}
+
+// newTestWallet returns the 2-of-3 wallet of new keys holding the utxos.
+func newTestWallet(t *testing.T, utxos []rpcpool.Utxo) (*Wallet, []*btcec.PrivateKey) {
+	keys := make([]*btcec.PrivateKey, 0, 3)
+	pubKeys := make([]string, 0, 3)
+	sorted := make([]*btcutil.AddressPubKey, 0, 3)
+	for i := 0; i < 3; i++ {
+		key, err := btcec.NewPrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		pubKeys = append(pubKeys, hex.EncodeToString(key.PubKey().SerializeCompressed()))
+		pubKey, err := btcutil.NewAddressPubKey(key.PubKey().SerializeCompressed(), &chaincfg.TestNet3Params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sorted = append(sorted, pubKey)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].ScriptAddress(), sorted[j].ScriptAddress()) < 0
+	})
+	script, err := txscript.MultiSigScript(sorted, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(script)
+	address, err := btcutil.NewAddressWitnessScriptHash(hash[:], &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet, err := NewWallet(config.Blockchain{
+		ChainType:         enums.ChainTypeUTXO,
+		ListenAddress:     address.EncodeAddress(),
+		WithdrawPubKeys:   pubKeys,
+		WithdrawThreshold: 2,
+		FeeRate:           10,
+	}, &unspentSource{utxos: utxos})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wallet, keys
+}
+
+func signer(key *btcec.PrivateKey) common.Address {
+	return crypto_.PubkeyToAddress(*key.PubKey().ToECDSA())
+}
+
+// relay sends the packet through its base64 encoding, as between the nodes.
+func relay(t *testing.T, packet *psbt.Packet) *psbt.Packet {
+	value, err := packet.B64Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := psbt.ParseBase64(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestWalletSignCombineFinalize(t *testing.T) {
+	utxos := []rpcpool.Utxo{
+		{OutPoint: wire.OutPoint{Hash: chainhash.Hash{1}, Index: 0}, Value: 30000},
+		{OutPoint: wire.OutPoint{Hash: chainhash.Hash{2}, Index: 1}, Value: 50000},
+	}
+	wallet, keys := newTestWallet(t, utxos)
+	packet, fee, err := wallet.Build(utxos, testRecipient, 70000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet.UnsignedTx.TxIn) != 2 || len(packet.UnsignedTx.TxOut) != 2 ||
+		packet.UnsignedTx.TxOut[0].Value != 70000-fee || packet.UnsignedTx.TxOut[1].Value != 10000 {
+		t.Fatalf("withdraw tx: %+v, fee %d", packet.UnsignedTx, fee)
+	}
+
+	// the proposer combines the signatures of two validators
+	proposal := relay(t, packet)
+	for _, key := range keys[1:] {
+		signed := relay(t, proposal)
+		if err = wallet.Verify(signed, testRecipient, 70000); err != nil {
+			t.Fatal(err)
+		}
+		if err = wallet.Sign(signed, key); err != nil {
+			t.Fatal(err)
+		}
+		if err = wallet.Combine(packet, relay(t, signed), signer(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tx, err := wallet.Finalize(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, in := range tx.TxIn {
+		prevOuts.AddPrevOut(in.PreviousOutPoint, packet.Inputs[i].WitnessUtxo)
+	}
+	sigHashes := txscript.NewTxSigHashes(tx, prevOuts)
+	for i := range tx.TxIn {
+		if len(tx.TxIn[i].Witness) != 4 {
+			t.Fatalf("witness of input %d: %d items", i, len(tx.TxIn[i].Witness))
+		}
+		engine, err := txscript.NewEngine(packet.Inputs[i].WitnessUtxo.PkScript, tx, i, txscript.StandardVerifyFlags,
+			nil, sigHashes, packet.Inputs[i].WitnessUtxo.Value, prevOuts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = engine.Execute(); err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+	}
+
+	// one signature is not enough
+	single := relay(t, proposal)
+	if err = wallet.Sign(single, keys[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wallet.Finalize(single); err == nil {
+		t.Fatal("finalized with one signature")
+	}
+
+	// a signature of another key or of another tx is refused
+	signed := relay(t, proposal)
+	if err = wallet.Sign(signed, keys[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = wallet.Combine(relay(t, proposal), signed, signer(keys[1])); err == nil {
+		t.Fatal("combined the signature of another signer")
+	}
+	other, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wallet.Sign(relay(t, proposal), other); err == nil {
+		t.Fatal("signed with a key out of the multisig")
+	}
+	changed := relay(t, proposal)
+	changed.UnsignedTx.TxOut[0].Value--
+	if err = wallet.Combine(changed, signed, signer(keys[0])); err == nil {
+		t.Fatal("combined the signature of another tx")
+	}
+	forged := relay(t, signed)
+	forged.Inputs[0].PartialSigs[0].Signature = forged.Inputs[1].PartialSigs[0].Signature
+	if err = wallet.Combine(relay(t, proposal), forged, signer(keys[0])); err == nil {
+		t.Fatal("combined the signature of another input")
+	}
+}
+
+func TestWalletVerify(t *testing.T) {
+	utxos := []rpcpool.Utxo{
+		{OutPoint: wire.OutPoint{Hash: chainhash.Hash{1}, Index: 0}, Value: 30000},
+		{OutPoint: wire.OutPoint{Hash: chainhash.Hash{2}, Index: 1}, Value: 50000},
+	}
+	wallet, _ := newTestWallet(t, utxos)
+	packet, fee, err := wallet.Build(utxos, testRecipient, 70000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wallet.Verify(relay(t, packet), testRecipient, 70000); err != nil {
+		t.Fatal(err)
+	}
+	other := "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"
+
+	for name, change := range map[string]func(packet *psbt.Packet){
+		"recipient": func(packet *psbt.Packet) {
+			address, _ := btcutil.DecodeAddress(other, &chaincfg.TestNet3Params)
+			packet.UnsignedTx.TxOut[0].PkScript, _ = txscript.PayToAddrScript(address)
+		},
+		"amount over the message": func(packet *psbt.Packet) {
+			packet.UnsignedTx.TxOut[0].Value = 70001
+		},
+		"fee over the fee rate": func(packet *psbt.Packet) {
+			packet.UnsignedTx.TxOut[0].Value -= fee
+		},
+		"change value": func(packet *psbt.Packet) {
+			packet.UnsignedTx.TxOut[1].Value--
+		},
+		"change address": func(packet *psbt.Packet) {
+			address, _ := btcutil.DecodeAddress(other, &chaincfg.TestNet3Params)
+			packet.UnsignedTx.TxOut[1].PkScript, _ = txscript.PayToAddrScript(address)
+		},
+		"missing change": func(packet *psbt.Packet) {
+			packet.UnsignedTx.TxOut = packet.UnsignedTx.TxOut[:1]
+			packet.Outputs = packet.Outputs[:1]
+		},
+		"extra output": func(packet *psbt.Packet) {
+			packet.UnsignedTx.AddTxOut(wire.NewTxOut(DustLimit, packet.UnsignedTx.TxOut[0].PkScript))
+			packet.Outputs = append(packet.Outputs, psbt.Output{})
+		},
+		"unknown input": func(packet *psbt.Packet) {
+			packet.UnsignedTx.TxIn[0].PreviousOutPoint.Hash = chainhash.Hash{3}
+		},
+		"duplicate input": func(packet *psbt.Packet) {
+			packet.UnsignedTx.TxIn[1].PreviousOutPoint = packet.UnsignedTx.TxIn[0].PreviousOutPoint
+			packet.Inputs[1] = packet.Inputs[0]
+		},
+		"witness utxo value": func(packet *psbt.Packet) {
+			packet.Inputs[0].WitnessUtxo = wire.NewTxOut(packet.Inputs[0].WitnessUtxo.Value+1, packet.Inputs[0].WitnessUtxo.PkScript)
+		},
+		"sighash type": func(packet *psbt.Packet) {
+			packet.Inputs[0].SighashType = txscript.SigHashNone
+		},
+	} {
+		changed := relay(t, packet)
+		change(changed)
+		if err = wallet.Verify(changed, testRecipient, 70000); err == nil {
+			t.Errorf("%s: verified", name)
+		}
+	}
+
+	// the same tx pays out another amount
+	if err = wallet.Verify(relay(t, packet), testRecipient, 69000); err == nil {
+		t.Error("verified for a smaller amount")
+	}
+	if err = wallet.Verify(relay(t, packet), other, 70000); err == nil {
+		t.Error("verified for another recipient")
+	}
+}
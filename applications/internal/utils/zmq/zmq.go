@@ -0,0 +1,223 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// topic published by bitcoin core with -zmqpubhashblock
+const TopicHashBlock = "hashblock"
+
+const (
+	// frame flags of ZMTP 3.0
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+
+	greetingLen = 64
+	dialTimeout = 10 * time.Second
+	// largest frame read, a raw block fits
+	maxFrameLen = 32 << 20
+)
+
+var ErrProtocol = errors.New("zmq protocol error")
+
+// Message is a multipart message published by a zmq PUB socket, bitcoin core
+// sends the topic, the body and a little endian sequence number.
+type Message struct {
+	Topic    string
+	Body     []byte
+	Sequence uint32
+}
+
+// Subscriber is a SUB socket speaking ZMTP 3.0 with the NULL mechanism over
+// tcp, enough for the notifications of bitcoin core, so no libzmq is linked.
+type Subscriber struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Dial connects to a publisher at an address like tcp://127.0.0.1:28332 and
+// subscribes to the topics, all the messages without topic.
+func Dial(address string, topics ...string) (*Subscriber, error) {
+	host, ok := strings.CutPrefix(address, "tcp://")
+	if !ok {
+		return nil, fmt.Errorf("unsupported zmq address: %s", address)
+	}
+	conn, err := net.DialTimeout("tcp", host, dialTimeout)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s := &Subscriber{conn: conn, r: bufio.NewReader(conn)}
+	err = s.handshake()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if len(topics) == 0 {
+		topics = []string{""}
+	}
+	for _, topic := range topics {
+		// a subscription is a message of 0x01 and the topic prefix
+		err = s.writeFrame(0, append([]byte{0x01}, topic...))
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Receive blocks until the next message, an error closes the subscription.
+func (s *Subscriber) Receive() (*Message, error) {
+	parts := make([][]byte, 0, 3)
+	for {
+		flags, body, err := s.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if flags&flagCommand != 0 {
+			continue
+		}
+		parts = append(parts, body)
+		if flags&flagMore == 0 {
+			break
+		}
+	}
+	msg := &Message{Topic: string(parts[0])}
+	if len(parts) > 1 {
+		msg.Body = parts[1]
+	}
+	if len(parts) > 2 && len(parts[2]) == 4 {
+		msg.Sequence = binary.LittleEndian.Uint32(parts[2])
+	}
+	return msg, nil
+}
+
+func (s *Subscriber) Close() error {
+	return s.conn.Close()
+}
+
+// handshake exchanges the greetings and the READY commands.
+func (s *Subscriber) handshake() error {
+	_ = s.conn.SetDeadline(time.Now().Add(dialTimeout))
+	defer s.conn.SetDeadline(time.Time{})
+
+	greeting := make([]byte, greetingLen)
+	greeting[0] = 0xff
+	greeting[9] = 0x7f
+	greeting[10] = 3 // version 3.0
+	copy(greeting[12:32], "NULL")
+	_, err := s.conn.Write(greeting)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	peer := make([]byte, greetingLen)
+	_, err = io.ReadFull(s.r, peer)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if peer[0] != 0xff || peer[9] != 0x7f || peer[10] < 3 {
+		return errors.Wrap(ErrProtocol, "greeting")
+	}
+	if string(bytes.TrimRight(peer[12:32], "\x00")) != "NULL" {
+		return errors.Wrapf(ErrProtocol, "mechanism: %s", bytes.TrimRight(peer[12:32], "\x00"))
+	}
+
+	err = s.writeFrame(flagCommand, command("READY", "Socket-Type", "SUB"))
+	if err != nil {
+		return err
+	}
+	flags, body, err := s.readFrame()
+	if err != nil {
+		return err
+	}
+	if flags&flagCommand == 0 || len(body) < 6 || string(body[1:6]) != "READY" {
+		return errors.Wrap(ErrProtocol, "ready")
+	}
+	socketType, err := property(body[6:], "Socket-Type")
+	if err != nil {
+		return err
+	}
+	if socketType != "PUB" && socketType != "XPUB" {
+		return errors.Wrapf(ErrProtocol, "socket type: %s", socketType)
+	}
+	return nil
+}
+
+// command encodes a command with a property.
+func command(name string, key string, value string) []byte {
+	body := append([]byte{byte(len(name))}, name...)
+	body = append(body, byte(len(key)))
+	body = append(body, key...)
+	body = binary.BigEndian.AppendUint32(body, uint32(len(value)))
+	return append(body, value...)
+}
+
+// property returns a property of the metadata of a command.
+func property(metadata []byte, key string) (string, error) {
+	for len(metadata) > 0 {
+		n := int(metadata[0])
+		if len(metadata) < 1+n+4 {
+			return "", errors.Wrap(ErrProtocol, "metadata")
+		}
+		name := string(metadata[1 : 1+n])
+		size := binary.BigEndian.Uint32(metadata[1+n:])
+		metadata = metadata[1+n+4:]
+		if uint32(len(metadata)) < size {
+			return "", errors.Wrap(ErrProtocol, "metadata")
+		}
+		if strings.EqualFold(name, key) {
+			return string(metadata[:size]), nil
+		}
+		metadata = metadata[size:]
+	}
+	return "", errors.Wrapf(ErrProtocol, "no property %s", key)
+}
+
+func (s *Subscriber) writeFrame(flags byte, body []byte) error {
+	var header []byte
+	if len(body) > 255 {
+		header = binary.BigEndian.AppendUint64([]byte{flags | flagLong}, uint64(len(body)))
+	} else {
+		header = []byte{flags, byte(len(body))}
+	}
+	_, err := s.conn.Write(append(header, body...))
+	return errors.WithStack(err)
+}
+
+func (s *Subscriber) readFrame() (byte, []byte, error) {
+	flags, err := s.r.ReadByte()
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	var size uint64
+	if flags&flagLong != 0 {
+		var buf [8]byte
+		_, err = io.ReadFull(s.r, buf[:])
+		size = binary.BigEndian.Uint64(buf[:])
+	} else {
+		var n byte
+		n, err = s.r.ReadByte()
+		size = uint64(n)
+	}
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	if size > maxFrameLen {
+		return 0, nil, errors.Wrapf(ErrProtocol, "frame size: %d", size)
+	}
+	body := make([]byte, size)
+	_, err = io.ReadFull(s.r, body)
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	return flags, body, nil
+}
@@ -0,0 +1,196 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// publisher is a PUB socket stand-in accepting one subscriber.
+type publisher struct {
+	listener net.Listener
+	// topics subscribed by the subscriber
+	topics chan string
+}
+
+func newPublisher(t *testing.T, greeting []byte, socketType string, messages [][][]byte) *publisher {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &publisher{listener: listener, topics: make(chan string, 4)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		peer := make([]byte, greetingLen)
+		if _, err := io.ReadFull(r, peer); err != nil {
+			return
+		}
+		if _, err := conn.Write(greeting); err != nil {
+			return
+		}
+		flags, body, err := readTestFrame(r)
+		if err != nil || flags&flagCommand == 0 || string(body[1:6]) != "READY" {
+			return
+		}
+		ready := command("READY", "Socket-Type", socketType)
+		if _, err := conn.Write(testFrame(flagCommand, ready)); err != nil {
+			return
+		}
+		for {
+			_, body, err := readTestFrame(r)
+			if err != nil {
+				return
+			}
+			if len(body) > 0 && body[0] == 0x01 {
+				p.topics <- string(body[1:])
+				break
+			}
+		}
+		for _, parts := range messages {
+			for i, part := range parts {
+				flags := byte(0)
+				if i < len(parts)-1 {
+					flags = flagMore
+				}
+				if _, err := conn.Write(testFrame(flags, part)); err != nil {
+					return
+				}
+			}
+		}
+		// wait for the subscriber to close
+		_, _ = io.Copy(io.Discard, r)
+	}()
+	return p
+}
+
+func (p *publisher) address() string {
+	return "tcp://" + p.listener.Addr().String()
+}
+
+func greeting(mechanism string) []byte {
+	greeting := make([]byte, greetingLen)
+	greeting[0] = 0xff
+	greeting[9] = 0x7f
+	greeting[10] = 3
+	copy(greeting[12:32], mechanism)
+	return greeting
+}
+
+func testFrame(flags byte, body []byte) []byte {
+	if len(body) > 255 {
+		return append(binary.BigEndian.AppendUint64([]byte{flags | flagLong}, uint64(len(body))), body...)
+	}
+	return append([]byte{flags, byte(len(body))}, body...)
+}
+
+func readTestFrame(r *bufio.Reader) (byte, []byte, error) {
+	flags, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var size uint64
+	if flags&flagLong != 0 {
+		var buf [8]byte
+		if _, err = io.ReadFull(r, buf[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(buf[:])
+	} else {
+		n, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size = uint64(n)
+	}
+	body := make([]byte, size)
+	_, err = io.ReadFull(r, body)
+	return flags, body, err
+}
+
+func TestSubscriberReceive(t *testing.T) {
+	hash := bytes.Repeat([]byte{0xab}, 32)
+	// a body over 255 bytes takes a long frame
+	long := bytes.Repeat([]byte{0x01}, 300)
+	p := newPublisher(t, greeting("NULL"), "PUB", [][][]byte{
+		{[]byte(TopicHashBlock), hash, {0x07, 0x00, 0x00, 0x00}},
+		{[]byte(TopicHashBlock), long, {0x08, 0x00, 0x00, 0x00}},
+		{[]byte(TopicHashBlock)},
+	})
+	defer p.listener.Close()
+
+	sub, err := Dial(p.address(), TopicHashBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if topic := <-p.topics; topic != TopicHashBlock {
+		t.Fatalf("subscribed topic: %q", topic)
+	}
+
+	msg, err := sub.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Topic != TopicHashBlock || !bytes.Equal(msg.Body, hash) || msg.Sequence != 7 {
+		t.Fatalf("message: %+v", msg)
+	}
+	msg, err = sub.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg.Body, long) || msg.Sequence != 8 {
+		t.Fatalf("long message: %s %d", msg.Topic, len(msg.Body))
+	}
+	msg, err = sub.Receive()
+	if err != nil || msg.Topic != TopicHashBlock || msg.Body != nil || msg.Sequence != 0 {
+		t.Fatalf("topic only message: %+v, err: %v", msg, err)
+	}
+}
+
+func TestSubscriberAllTopics(t *testing.T) {
+	p := newPublisher(t, greeting("NULL"), "XPUB", nil)
+	defer p.listener.Close()
+
+	sub, err := Dial(p.address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if topic := <-p.topics; topic != "" {
+		t.Fatalf("subscribed topic: %q", topic)
+	}
+}
+
+func TestSubscriberHandshake(t *testing.T) {
+	bad := greeting("NULL")
+	bad[0] = 0x00
+	for name, test := range map[string]struct {
+		greeting   []byte
+		socketType string
+	}{
+		"bad greeting": {greeting: bad, socketType: "PUB"},
+		"mechanism":    {greeting: greeting("CURVE"), socketType: "PUB"},
+		"socket type":  {greeting: greeting("NULL"), socketType: "REP"},
+	} {
+		p := newPublisher(t, test.greeting, test.socketType, nil)
+		_, err := Dial(p.address(), TopicHashBlock)
+		if !errors.Is(err, ErrProtocol) {
+			t.Errorf("%s: expected a protocol error, got: %v", name, err)
+		}
+		p.listener.Close()
+	}
+
+	_, err := Dial("ipc:///tmp/bitcoind", TopicHashBlock)
+	if err == nil {
+		t.Fatal("expected an unsupported address error")
+	}
+}
@@ -2,8 +2,6 @@ package vo
 
 import (
 	"bsquared.network/message-sharing-applications/internal/enums"
-	"github.com/btcsuite/btcd/rpcclient"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 type MessageWrap struct {
@@ -37,6 +35,7 @@ type Message struct {
 	Data                string
 	TxHash              string
 	LogIndex            int64
+	BlockNumber         int64
 }
 
 type MessageSignature struct {
@@ -53,7 +52,14 @@ type MessageSignature struct {
 	Signature           string
 }
 
-type RpcClient struct {
-	EthRpc *ethclient.Client
-	BtcRpc *rpcclient.Client
+// WithdrawProposal asks a validator to sign the psbt paying out a message.
+type WithdrawProposal struct {
+	WithdrawId int64
+	Message    Message
+	Psbt       string
+}
+
+type WithdrawSignature struct {
+	WithdrawId int64
+	Psbt       string
 }